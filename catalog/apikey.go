@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// APIKey lets a machine client that cannot obtain bearer tokens, such as a
+// warehouse scanner, call the API with the scopes of the key. Only a hash
+// of the key is stored.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// TenantID is the tenant the key acts for, set when it is issued.
+	TenantID string `json:"tenantId"`
+	// Prefix is the start of the key, to tell keys apart.
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RotatedAt  *time.Time `json:"rotatedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedBy  string     `json:"createdBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Key is the secret key. It is only returned when the key is issued or
+	// rotated and cannot be read back.
+	Key string `json:"key,omitempty"`
+}
+
+// maxScopesLength is the length of api_key.scopes.
+const maxScopesLength = 1024
+
+// Validate checks the API key fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (k *APIKey) Validate() error {
+	var fields []FieldError
+	if n := utf8.RuneCountInString(k.Name); n == 0 || n > maxFieldLength {
+		fields = append(fields, FieldError{Field: "name", Message: "must be between 1 and 255 characters"})
+	}
+	if len(k.Scopes) == 0 {
+		fields = append(fields, FieldError{Field: "scopes", Message: "must list at least one scope"})
+	}
+	for _, s := range k.Scopes {
+		if s == "" || strings.ContainsAny(s, " \t\r\n") {
+			fields = append(fields, FieldError{Field: "scopes", Message: "must not be empty or contain spaces"})
+			break
+		}
+	}
+	if len(strings.Join(k.Scopes, " ")) > maxScopesLength {
+		fields = append(fields, FieldError{Field: "scopes", Message: "must be at most 1024 characters together"})
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		fields = append(fields, FieldError{Field: "expiresAt", Message: "must be in the future"})
+	}
+	return ValidationError("API key is invalid", fields)
+}
+
+// Expired reports whether the key has expired at t.
+func (k *APIKey) Expired(t time.Time) bool {
+	return k.ExpiresAt != nil && !t.Before(*k.ExpiresAt)
+}
+
+// APIKeyService manages the API keys. Except for APIKeyByHash and
+// TouchAPIKey, which authenticate the callers, it only sees the keys of
+// the context's tenant.
+type APIKeyService interface {
+	APIKey(ctx context.Context, id string) (*APIKey, error)
+	APIKeys(ctx context.Context) ([]*APIKey, error)
+	// CreateAPIKey stores a new key with the hash of its secret and
+	// assigns its ID. A key without a tenant is issued for the context's
+	// tenant.
+	CreateAPIKey(ctx context.Context, k *APIKey, hash string) error
+	// APIKeyByHash returns the key whose secret has the hash, revoked and
+	// expired keys included. The previous secret of a rotated key matches
+	// until its grace period ends.
+	APIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	// RotateAPIKey replaces the secret of a key by one with the prefix and
+	// hash. The previous secret stays valid for grace.
+	RotateAPIKey(ctx context.Context, id, prefix, hash string, grace time.Duration) (*APIKey, error)
+	// RevokeAPIKey revokes a key for good. Revoking it again does nothing.
+	RevokeAPIKey(ctx context.Context, id string) error
+	// TouchAPIKey records that the key was used at t.
+	TouchAPIKey(ctx context.Context, id string, t time.Time) error
+}
@@ -0,0 +1,199 @@
+package catalog
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// Attribute data types.
+const (
+	AttributeString  = "string"
+	AttributeInteger = "integer"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+// AttributeDefinition describes a custom product attribute such as
+// material, brand or weight. Products carry values for the defined
+// attributes in Product.Attributes.
+type AttributeDefinition struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// AllowedValues, when set, restricts a string, integer or number
+	// attribute to the listed values.
+	AllowedValues []string `json:"allowedValues,omitempty"`
+	// Unit is the unit the values are expressed in, e.g. kg or V.
+	Unit     string `json:"unit,omitempty"`
+	Required bool   `json:"required"`
+}
+
+// attributeNamePattern keeps attribute names usable as query parameters.
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]{0,63}$`)
+
+// Validate checks the definition fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (d *AttributeDefinition) Validate() error {
+	var fields []FieldError
+	if !attributeNamePattern.MatchString(d.Name) {
+		fields = append(fields, FieldError{Field: "name", Message: "must start with a lowercase letter and contain at most 64 letters, digits or underscores"})
+	}
+	switch d.Type {
+	case AttributeString, AttributeInteger, AttributeNumber:
+		for _, v := range d.AllowedValues {
+			if _, err := d.Canonical(v); err != nil {
+				fields = append(fields, FieldError{Field: "allowedValues", Message: err.Error()})
+				break
+			}
+		}
+	case AttributeBoolean:
+		if len(d.AllowedValues) > 0 {
+			fields = append(fields, FieldError{Field: "allowedValues", Message: "not supported for boolean attributes"})
+		}
+	default:
+		fields = append(fields, FieldError{Field: "type", Message: "must be one of string, integer, number or boolean"})
+	}
+	if utf8.RuneCountInString(d.Unit) > 32 {
+		fields = append(fields, FieldError{Field: "unit", Message: "must be at most 32 characters"})
+	}
+	return ValidationError("attribute definition is invalid", fields)
+}
+
+// Canonical converts a value to the text form it is stored and compared in.
+// Values decoded from JSON (string, float64, bool) and their text forms are
+// accepted. It does not check AllowedValues.
+func (d *AttributeDefinition) Canonical(v interface{}) (string, error) {
+	switch d.Type {
+	case AttributeString:
+		if s, ok := v.(string); ok && utf8.RuneCountInString(s) <= maxFieldLength {
+			return s, nil
+		}
+		return "", errors.New("must be a string of at most 255 characters")
+	case AttributeInteger:
+		switch n := v.(type) {
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+				return strconv.FormatInt(int64(n), 10), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+				return strconv.FormatInt(i, 10), nil
+			}
+		}
+		return "", errors.New("must be an integer")
+	case AttributeNumber:
+		switch n := v.(type) {
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		case string:
+			if f, err := strconv.ParseFloat(n, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return strconv.FormatFloat(f, 'f', -1, 64), nil
+			}
+		}
+		return "", errors.New("must be a number")
+	case AttributeBoolean:
+		switch b := v.(type) {
+		case bool:
+			return strconv.FormatBool(b), nil
+		case string:
+			if b == "true" || b == "false" {
+				return b, nil
+			}
+		}
+		return "", errors.New("must be true or false")
+	}
+	return "", errors.New("has an unknown type")
+}
+
+// Value converts a stored text value back to its typed form: string,
+// int64, float64 or bool.
+func (d *AttributeDefinition) Value(s string) interface{} {
+	switch d.Type {
+	case AttributeInteger:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case AttributeNumber:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case AttributeBoolean:
+		return s == "true"
+	}
+	return s
+}
+
+// allows reports whether the canonical value is permitted by AllowedValues.
+func (d *AttributeDefinition) allows(canonical string) bool {
+	if len(d.AllowedValues) == 0 {
+		return true
+	}
+	for _, v := range d.AllowedValues {
+		if c, err := d.Canonical(v); err == nil && c == canonical {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalAttributes validates the attribute values of a product against
+// the definitions and returns them in canonical text form. Unknown
+// attributes, values of the wrong type or outside the allowed values, and
+// missing required attributes are reported as field errors.
+func CanonicalAttributes(defs []*AttributeDefinition, values map[string]interface{}) (map[string]string, error) {
+	byName := make(map[string]*AttributeDefinition, len(defs))
+	var fields []FieldError
+	for _, d := range defs {
+		byName[d.Name] = d
+		if _, ok := values[d.Name]; d.Required && !ok {
+			fields = append(fields, FieldError{Field: "attributes." + d.Name, Message: "is required"})
+		}
+	}
+	canonical := make(map[string]string, len(values))
+	for name, v := range values {
+		d, ok := byName[name]
+		if !ok {
+			fields = append(fields, FieldError{Field: "attributes." + name, Message: "is not a defined attribute"})
+			continue
+		}
+		c, err := d.Canonical(v)
+		if err == nil && !d.allows(c) {
+			err = errors.New("is not one of the allowed values")
+		}
+		if err != nil {
+			fields = append(fields, FieldError{Field: "attributes." + name, Message: err.Error()})
+			continue
+		}
+		canonical[name] = c
+	}
+	return canonical, ValidationError("product attributes are invalid", fields)
+}
+
+// AttributeFilter matches products whose attribute Name equals Value.
+// Numeric values match numerically, so 1.50 matches 1.5.
+type AttributeFilter struct {
+	Name  string
+	Value string
+}
+
+// ValidAttributeName reports whether name is a well formed attribute name.
+func ValidAttributeName(name string) bool {
+	return attributeNamePattern.MatchString(name)
+}
+
+// AttributeService represents a service for managing attribute definitions.
+// Attribute values are read and written with the product by ProductService.
+type AttributeService interface {
+	AttributeDefinition(ctx context.Context, name string) (*AttributeDefinition, error)
+	AttributeDefinitions(ctx context.Context) ([]*AttributeDefinition, error)
+	CreateAttributeDefinition(ctx context.Context, d *AttributeDefinition) error
+	// UpdateAttributeDefinition changes the allowed values, unit and
+	// required flag. The type of a definition cannot be changed.
+	UpdateAttributeDefinition(ctx context.Context, d *AttributeDefinition) error
+	// DeleteAttributeDefinition deletes a definition no product has a value for.
+	DeleteAttributeDefinition(ctx context.Context, name string) error
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	a, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := NewAPIKey()
+	if a == b || !strings.HasPrefix(a, "ck_") || len(a) != 46 {
+		t.Errorf("unexpected keys: %s, %s", a, b)
+	}
+	if p := APIKeyPrefix(a); len(p) != 11 || !strings.HasPrefix(a, p) {
+		t.Errorf("unexpected prefix %s of %s", p, a)
+	}
+	if h := HashAPIKey(a); len(h) != 64 || h == HashAPIKey(b) || h != HashAPIKey(a) {
+		t.Errorf("unexpected hash %s", h)
+	}
+}
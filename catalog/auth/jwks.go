@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// ErrUnknownKey is returned for tokens signed with a key that is not in
+// the key set.
+var ErrUnknownKey = errors.New("auth: unknown signing key")
+
+// KeySet looks up the public keys that sign the tokens.
+type KeySet interface {
+	// Key returns the *rsa.PublicKey or *ecdsa.PublicKey with the key ID.
+	// An empty kid matches the only key of a set holding a single key.
+	Key(ctx context.Context, kid string) (interface{}, error)
+}
+
+// StaticKeySet is a fixed set of public keys by key ID.
+type StaticKeySet map[string]interface{}
+
+// Key returns the key with the ID.
+func (s StaticKeySet) Key(ctx context.Context, kid string) (interface{}, error) {
+	return lookup(s, kid)
+}
+
+// lookup returns the key with the ID from keys.
+func lookup(keys map[string]interface{}, kid string) (interface{}, error) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, nil
+		}
+	}
+	k, ok := keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+// jsonWebKey is a key of a JSON Web Key Set (RFC 7517). Only the members
+// of RSA and EC signing keys are read.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseKeySet reads the RSA and P-256 signing keys of a JSON Web Key Set.
+// Other keys are skipped.
+func ParseKeySet(data []byte) (StaticKeySet, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: invalid key set: %v", err)
+	}
+	keys := StaticKeySet{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key interface{}
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaKey()
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// LoadKeySetFile reads a JSON Web Key Set from a file.
+func LoadKeySetFile(path string) (StaticKeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(data)
+}
+
+func (k *jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k *jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	x, err := decodeInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// RemoteKeySet defaults.
+const (
+	DefaultKeySetTTL          = time.Hour
+	DefaultKeySetRefreshDelay = time.Minute
+)
+
+// RemoteKeySet fetches the keys from a JWKS URL and caches them. The keys
+// are fetched again once the cache expires, and early when a token names
+// a key that is not cached, so that rotated keys are picked up. When the
+// URL cannot be reached the cached keys are kept.
+type RemoteKeySet struct {
+	URL    string
+	Client *http.Client
+	// TTL is how long the keys are cached when the response does not set
+	// a max-age.
+	TTL time.Duration
+	// RefreshDelay is the least time between two fetches, so that tokens
+	// with made up key IDs cannot make the service hammer the URL.
+	RefreshDelay time.Duration
+
+	mu        sync.Mutex
+	keys      StaticKeySet
+	expires   time.Time
+	lastFetch time.Time
+	now       func() time.Time
+}
+
+// NewRemoteKeySet returns a key set fetched from url with the default
+// settings.
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		URL:          url,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		TTL:          DefaultKeySetTTL,
+		RefreshDelay: DefaultKeySetRefreshDelay,
+		now:          time.Now,
+	}
+}
+
+// Key returns the key with the ID, fetching the key set when it is not
+// cached or has expired.
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.keys != nil && now.Before(s.expires) {
+		if k, err := lookup(s.keys, kid); err == nil {
+			return k, nil
+		}
+	}
+	if s.keys == nil || now.Sub(s.lastFetch) >= s.RefreshDelay {
+		s.lastFetch = now
+		if err := s.fetch(ctx, now); err != nil {
+			if s.keys == nil {
+				return nil, err
+			}
+			log.Warnf("Keeping the cached signing keys: %v", err)
+		}
+	}
+	return lookup(s.keys, kid)
+}
+
+// fetch replaces the cached keys with the keys at the URL.
+func (s *RemoteKeySet) fetch(ctx context.Context, now time.Time) error {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("auth: fetching the key set: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: fetching the key set: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("auth: fetching the key set: %v", err)
+	}
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	s.expires = now.Add(maxAge(resp.Header.Get("Cache-Control"), s.TTL))
+	return nil
+}
+
+// maxAge returns the max-age of a Cache-Control header, or fallback.
+func maxAge(cacheControl string, fallback time.Duration) time.Duration {
+	for _, d := range strings.Split(cacheControl, ",") {
+		d = strings.TrimSpace(d)
+		if strings.HasPrefix(d, "max-age=") {
+			if n, err := strconv.Atoi(strings.TrimPrefix(d, "max-age=")); err == nil && n > 0 {
+				return time.Duration(n) * time.Second
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// jwks returns a JSON Web Key Set of the public keys by key ID.
+func jwks(t *testing.T, keys map[string]interface{}) []byte {
+	enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, k := range keys {
+		switch k := k.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{"kty": "RSA", "kid": kid, "use": "sig",
+				"n": enc(k.N), "e": enc(big.NewInt(int64(k.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": enc(k.X), "y": enc(k.Y)})
+		}
+	}
+	// Keys that are skipped.
+	set.Keys = append(set.Keys, map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"})
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLoadKeySetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	data := jwks(t, map[string]interface{}{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadKeySetFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected the 2 signing keys, got %v", keys)
+	}
+	if k, ok := keys["rsa"].(*rsa.PublicKey); !ok || k.N.Cmp(rsaKey.N) != 0 || k.E != rsaKey.E {
+		t.Errorf("unexpected RSA key: %v", keys["rsa"])
+	}
+	if k, ok := keys["ec"].(*ecdsa.PublicKey); !ok || k.X.Cmp(ecKey.X) != 0 || k.Y.Cmp(ecKey.Y) != 0 {
+		t.Errorf("unexpected EC key: %v", keys["ec"])
+	}
+}
+
+func TestRemoteKeySet_Key(t *testing.T) {
+	var mu sync.Mutex
+	fetches := 0
+	current := map[string]interface{}{"one": &rsaKey.PublicKey}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write(jwks(t, current))
+	}))
+	defer srv.Close()
+
+	now := testNow
+	s := NewRemoteKeySet(srv.URL)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := s.Key(ctx, "one"); err != nil || fetches != 1 {
+		t.Fatalf("expected the key fetched, got %v after %d fetches", err, fetches)
+	}
+	// Cached keys are not fetched again.
+	if _, err := s.Key(ctx, "one"); err != nil || fetches != 1 {
+		t.Fatalf("expected the cached key, got %v after %d fetches", err, fetches)
+	}
+
+	// The keys rotate. An unknown key is fetched, but not more often than
+	// the refresh delay allows.
+	mu.Lock()
+	current = map[string]interface{}{"one": &rsaKey.PublicKey, "two": &ecKey.PublicKey}
+	mu.Unlock()
+	if _, err := s.Key(ctx, "two"); err != ErrUnknownKey || fetches != 1 {
+		t.Fatalf("expected the refresh to wait, got %v after %d fetches", err, fetches)
+	}
+	now = now.Add(DefaultKeySetRefreshDelay)
+	if _, err := s.Key(ctx, "two"); err != nil || fetches != 2 {
+		t.Fatalf("expected the rotated key, got %v after %d fetches", err, fetches)
+	}
+
+	// The max-age expires the cache; the cached keys outlive a failed fetch.
+	srv.Close()
+	now = now.Add(11 * time.Minute)
+	if _, err := s.Key(ctx, "one"); err != nil {
+		t.Errorf("expected the cached key when the URL is down, got %v", err)
+	}
+}
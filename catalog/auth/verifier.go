@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/context"
+)
+
+// Token validation errors.
+var (
+	ErrNoExpiry    = errors.New("token has no expiry")
+	ErrExpired     = errors.New("token is expired")
+	ErrNotYetValid = errors.New("token is not valid yet")
+	ErrIssuer      = errors.New("token issuer is not accepted")
+	ErrAudience    = errors.New("token audience is not accepted")
+)
+
+// DefaultLeeway is the clock skew tolerated on the exp and nbf claims.
+const DefaultLeeway = time.Minute
+
+// signingMethods are the accepted token algorithms. Restricting them keeps
+// "none" and HMAC tokens out.
+var signingMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+
+// Verifier verifies RS256 and ES256 signed access tokens and their issuer,
+// audience, expiry and not-before claims.
+type Verifier struct {
+	Keys     KeySet
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated on the time claims.
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+// NewVerifier returns a verifier of the tokens issued by issuer for the
+// audience and signed with keys.
+func NewVerifier(keys KeySet, issuer, audience string) *Verifier {
+	return &Verifier{Keys: keys, Issuer: issuer, Audience: audience, Leeway: DefaultLeeway, now: time.Now}
+}
+
+// Verify returns the claims of a valid token. Tokens without an exp claim
+// are rejected.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parser := &jwt.Parser{ValidMethods: signingMethods, SkipClaimsValidation: true}
+	claims := &Claims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		// Report why the key lookup failed rather than the parser's wrapper.
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Inner != nil {
+			return nil, ve.Inner
+		}
+		return nil, err
+	}
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	t := now().Unix()
+	leeway := int64(v.Leeway / time.Second)
+	switch {
+	case claims.ExpiresAt == 0:
+		return nil, ErrNoExpiry
+	case t > claims.ExpiresAt+leeway:
+		return nil, ErrExpired
+	case claims.NotBefore != 0 && t < claims.NotBefore-leeway:
+		return nil, ErrNotYetValid
+	case claims.Issuer != v.Issuer:
+		return nil, ErrIssuer
+	case !claims.Audience.Contains(v.Audience):
+		return nil, ErrAudience
+	}
+	return claims, nil
+}
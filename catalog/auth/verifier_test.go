@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/context"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "https://catalog.example.com"
+)
+
+var (
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testNow   = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// sign returns a token with the claims signed by the method and key.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// validClaims returns the claims of a token valid at testNow.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"sub":   "partner",
+		"aud":   []string{testAudience, "https://issuer.example.com/userinfo"},
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"nbf":   testNow.Add(-time.Hour).Unix(),
+		"scope": "read:product write:product",
+	}
+}
+
+func newTestVerifier() *Verifier {
+	v := NewVerifier(StaticKeySet{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}, testIssuer, testAudience)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	v := newTestVerifier()
+	for _, token := range []string{
+		sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()),
+		sign(t, jwt.SigningMethodES256, "ec", ecKey, validClaims()),
+	} {
+		c, err := v.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.Subject != "partner" || !c.HasScope("write:product") || c.HasScope("admin:product") {
+			t.Errorf("unexpected claims: %+v", c)
+		}
+	}
+}
+
+func TestVerifier_VerifyInvalid(t *testing.T) {
+	v := newTestVerifier()
+	with := func(name string, value interface{}) jwt.MapClaims {
+		c := validClaims()
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+		return c
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"unsigned", unsigned, nil},
+		{"hmac", hmac, nil},
+		{"wrong key", sign(t, jwt.SigningMethodRS256, "rsa", otherKey, validClaims()), nil},
+		{"unknown key", sign(t, jwt.SigningMethodRS256, "old", rsaKey, validClaims()), ErrUnknownKey},
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("exp", testNow.Add(-2*time.Minute).Unix())), ErrExpired},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("exp", nil)), ErrNoExpiry},
+		{"not yet valid", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("nbf", testNow.Add(2*time.Minute).Unix())), ErrNotYetValid},
+		{"issuer", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("iss", "https://evil.example.com/")), ErrIssuer},
+		{"audience", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("aud", "https://other.example.com")), ErrAudience},
+		{"malformed", "not.a.token", nil},
+	}
+	for _, tt := range tests {
+		_, err := v.Verify(context.Background(), tt.token)
+		if err == nil || (tt.err != nil && err != tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	// The leeway tolerates a little clock skew.
+	token := sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with("exp", testNow.Add(-30*time.Second).Unix()))
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Errorf("expected a token expired within the leeway to pass, got %v", err)
+	}
+}
+
+func TestClaims_Strings(t *testing.T) {
+	claims := validClaims()
+	claims["https://example.com/roles"] = []string{"editor", "apparel"}
+	claims["team"] = "apparel"
+	c, err := newTestVerifier().Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.Strings("https://example.com/roles"); len(got) != 2 || got[1] != "apparel" {
+		t.Errorf("unexpected roles: %v", got)
+	}
+	if got := c.Strings("team"); len(got) != 1 || got[0] != "apparel" {
+		t.Errorf("unexpected team: %v", got)
+	}
+	if got := c.Strings("exp"); got != nil {
+		t.Errorf("expected no strings for a number, got %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"fmt"
+)
+
+// Batch operation kinds.
+const (
+	BatchUpsert = "upsert"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOperations is the largest number of operations a batch may hold.
+const MaxBatchOperations = 10000
+
+// ErrBatchAborted is the result of the operations of an all-or-nothing
+// batch that were not applied because another operation failed.
+var ErrBatchAborted error = &Error{Kind: ErrConflict, Message: "not applied because another operation of the batch failed"}
+
+// BatchOperation is a single operation of a product batch. An upsert
+// creates Product when it has no ID and updates it otherwise, checking a
+// non-zero Product.Version. A delete removes the product ID, checking a
+// non-zero Version.
+type BatchOperation struct {
+	Op      string   `json:"op"`
+	Product *Product `json:"product,omitempty"`
+	ID      string   `json:"productId,omitempty"`
+	Version int64    `json:"version,omitempty"`
+}
+
+// Validate checks the operation, including the product of an upsert.
+func (o *BatchOperation) Validate() error {
+	switch o.Op {
+	case BatchUpsert:
+		if o.Product == nil {
+			return ValidationError("operation is invalid", []FieldError{{Field: "product", Message: "is required"}})
+		}
+		return o.Product.Validate()
+	case BatchDelete:
+		if o.ID == "" {
+			return ValidationError("operation is invalid", []FieldError{{Field: "productId", Message: "is required"}})
+		}
+		return nil
+	}
+	return ValidationError("operation is invalid",
+		[]FieldError{{Field: "op", Message: fmt.Sprintf("must be %s or %s", BatchUpsert, BatchDelete)}})
+}
+
+// BatchResult is the outcome of a single batch operation.
+type BatchResult struct {
+	// Product is the stored product after a successful upsert.
+	Product *Product
+	// Created is set when an upsert created a new product.
+	Created bool
+	// Err is nil when the operation was applied.
+	Err error
+}
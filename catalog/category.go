@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"regexp"
+
+	"golang.org/x/net/context"
+)
+
+// Category is a node in the category tree. Root categories have no ParentID.
+// Siblings are ordered by Position.
+type Category struct {
+	ID       string `json:"categoryId"`
+	ParentID string `json:"parentId,omitempty"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Position int    `json:"position"`
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Validate checks the category fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (c *Category) Validate() error {
+	var fields []FieldError
+	if c.Name == "" || len(c.Name) > maxFieldLength {
+		fields = append(fields, FieldError{Field: "name", Message: "must be 1 to 255 characters"})
+	}
+	if !slugPattern.MatchString(c.Slug) || len(c.Slug) > maxFieldLength {
+		fields = append(fields, FieldError{Field: "slug", Message: "must be lowercase letters, digits and single hyphens"})
+	}
+	if c.ParentID != "" && c.ParentID == c.ID {
+		fields = append(fields, FieldError{Field: "parentId", Message: "must not be the category itself"})
+	}
+	return ValidationError("category is invalid", fields)
+}
+
+// CategoryService represents a service for managing the category tree and
+// the assignment of products to categories. Products in a category are
+// listed with ProductService.Products and ProductQuery.CategoryID.
+type CategoryService interface {
+	Category(ctx context.Context, id string) (*Category, error)
+	// Children returns the ordered children of a category, or the root
+	// categories when parentID is empty.
+	Children(ctx context.Context, parentID string) ([]*Category, error)
+	// Breadcrumbs returns the path from the root category down to and
+	// including the category.
+	Breadcrumbs(ctx context.Context, id string) ([]*Category, error)
+	CreateCategory(ctx context.Context, c *Category) error
+	// UpdateCategory renames, reorders or moves a category with its subtree.
+	UpdateCategory(ctx context.Context, c *Category) error
+	// DeleteCategory deletes a category without children.
+	DeleteCategory(ctx context.Context, id string) error
+
+	AssignProduct(ctx context.Context, categoryID, productID string) error
+	UnassignProduct(ctx context.Context, categoryID, productID string) error
+	ProductCategories(ctx context.Context, productID string) ([]*Category, error)
+}
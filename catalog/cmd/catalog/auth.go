@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/http"
+	"github.com/mvonbodun/go-package-test/catalog/ratelimit"
+	log "github.com/sirupsen/logrus"
+)
+
+// Environment variables configuring the verification of bearer tokens.
+const (
+	jwksURL     = "JWKS_URL"
+	jwksFile    = "JWKS_FILE"
+	jwtIssuer   = "JWT_ISSUER"
+	jwtAudience = "JWT_AUDIENCE"
+	policyFile  = "POLICY_FILE"
+	// tenantClaim names the token claim carrying the caller's tenant.
+	tenantClaim = "TENANT_CLAIM"
+)
+
+// Environment variables limiting the rate of every client, such as
+// "600/m", or "off". The address limit applies to every request from an
+// address before it is authenticated.
+const (
+	rateLimitAddress = "RATE_LIMIT_ADDRESS"
+	rateLimitRead    = "RATE_LIMIT_READ"
+	rateLimitWrite   = "RATE_LIMIT_WRITE"
+)
+
+// Default rate limits of every client.
+const (
+	defaultRateLimitAddress = "3000/m"
+	defaultRateLimitRead    = "1200/m"
+	defaultRateLimitWrite   = "300/m"
+)
+
+// Defaults of the Auth0 tenant fronting the service, see openapi.yaml.
+const (
+	defaultJWKSURL     = "https://geauxcommerce.auth0.com/.well-known/jwks.json"
+	defaultJWTIssuer   = "https://geauxcommerce.auth0.com/"
+	defaultJWTAudience = "http://google_api"
+)
+
+// authConfig reads which keys sign the bearer tokens and which issuer and
+// audience they must name. A key set file takes precedence over the URL.
+func authConfig() (*auth.Verifier, error) {
+	issuer := envString(jwtIssuer, defaultJWTIssuer)
+	audience := envString(jwtAudience, defaultJWTAudience)
+	if path := envString(jwksFile, ""); path != "" {
+		keys, err := auth.LoadKeySetFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", jwksFile, err)
+		}
+		log.Infof("Verifying tokens with the %d keys of %s", len(keys), path)
+		return auth.NewVerifier(keys, issuer, audience), nil
+	}
+	url := envString(jwksURL, defaultJWKSURL)
+	log.Infof("Verifying tokens with the keys at %s", url)
+	return auth.NewVerifier(auth.NewRemoteKeySet(url), issuer, audience), nil
+}
+
+// policyConfig reads the authorization policy file, if any. Without one
+// the handler enforces http.DefaultPolicy.
+func policyConfig() (*http.Policy, error) {
+	path := envString(policyFile, "")
+	if path == "" {
+		return nil, nil
+	}
+	p, err := http.LoadPolicy(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", policyFile, err)
+	}
+	log.Infof("Authorizing requests with the %d rules of %s", len(p.Rules), path)
+	return p, nil
+}
+
+// rateLimitConfig reads how often every client address may call and every
+// client may read and write. The buckets are kept in memory, so every
+// instance limits its clients on its own. Without limits the handler does
+// not limit.
+func rateLimitConfig() (*http.RateLimiter, error) {
+	address, err := ratelimit.ParseLimit(envString(rateLimitAddress, defaultRateLimitAddress))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", rateLimitAddress, err)
+	}
+	read, err := ratelimit.ParseLimit(envString(rateLimitRead, defaultRateLimitRead))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", rateLimitRead, err)
+	}
+	write, err := ratelimit.ParseLimit(envString(rateLimitWrite, defaultRateLimitWrite))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", rateLimitWrite, err)
+	}
+	if address.Unlimited() && read.Unlimited() && write.Unlimited() {
+		return nil, nil
+	}
+	log.Infof("Limiting every address to %s requests and every client to %s reads and %s writes", address, read, write)
+	return &http.RateLimiter{Store: ratelimit.NewMemoryStore(), Address: address, Read: read, Write: write}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/ratelimit"
+)
+
+func TestAuthConfig(t *testing.T) {
+	defer os.Unsetenv(jwksFile)
+	defer os.Unsetenv(jwtIssuer)
+	v, err := authConfig()
+	if err != nil || v.Issuer != defaultJWTIssuer || v.Audience != defaultJWTAudience {
+		t.Fatalf("unexpected config: %+v %v", v, err)
+	}
+	if ks, ok := v.Keys.(*auth.RemoteKeySet); !ok || ks.URL != defaultJWKSURL {
+		t.Errorf("expected the keys at %s, got %+v", defaultJWKSURL, v.Keys)
+	}
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, []byte(`{"keys":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(jwksFile, path)
+	os.Setenv(jwtIssuer, "https://issuer.example.com/")
+	v, err = authConfig()
+	if err != nil || v.Issuer != "https://issuer.example.com/" {
+		t.Fatalf("unexpected config: %+v %v", v, err)
+	}
+	if _, ok := v.Keys.(auth.StaticKeySet); !ok {
+		t.Errorf("expected the keys of the file, got %T", v.Keys)
+	}
+
+	os.Setenv(jwksFile, filepath.Join(dir, "missing.json"))
+	if _, err := authConfig(); err == nil {
+		t.Error("expected a missing key set file to fail")
+	}
+}
+
+func TestPolicyConfig(t *testing.T) {
+	defer os.Unsetenv(policyFile)
+	if p, err := policyConfig(); p != nil || err != nil {
+		t.Fatalf("expected no policy, got %+v %v", p, err)
+	}
+
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.json")
+	policy := `{"rules": [{"name": "readers", "effect": "allow", "scopes": ["read:product"], "actions": ["*:read"]}]}`
+	if err := ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(policyFile, path)
+	p, err := policyConfig()
+	if err != nil || len(p.Rules) != 1 || p.Rules[0].Name != "readers" {
+		t.Fatalf("unexpected policy: %+v %v", p, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"rules": [{"name": "readers"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policyConfig(); err == nil {
+		t.Error("expected an invalid policy to fail")
+	}
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	defer os.Unsetenv(rateLimitAddress)
+	defer os.Unsetenv(rateLimitRead)
+	defer os.Unsetenv(rateLimitWrite)
+	l, err := rateLimitConfig()
+	if err != nil || l.Address != (ratelimit.Limit{Burst: 3000, Period: time.Minute}) ||
+		l.Read != (ratelimit.Limit{Burst: 1200, Period: time.Minute}) ||
+		l.Write != (ratelimit.Limit{Burst: 300, Period: time.Minute}) {
+		t.Fatalf("unexpected limiter: %+v %v", l, err)
+	}
+
+	os.Setenv(rateLimitAddress, "off")
+	os.Setenv(rateLimitRead, "off")
+	os.Setenv(rateLimitWrite, "off")
+	if l, err := rateLimitConfig(); l != nil || err != nil {
+		t.Fatalf("expected no limiter, got %+v %v", l, err)
+	}
+
+	os.Setenv(rateLimitWrite, "often")
+	if _, err := rateLimitConfig(); err == nil {
+		t.Error("expected an invalid limit to fail")
+	}
+}
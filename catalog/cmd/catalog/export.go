@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// runExport implements the export subcommand:
+//
+//	catalog export [-format csv|ndjson] [-map field=column,...] [-prefix code] file
+//
+// Every product, or those whose productCode starts with the prefix, is
+// written in productId order. CSV files have a column per attribute
+// definition; NDJSON files hold one product object per line.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "file format, csv or ndjson (default: from the file extension)")
+	mapping := fs.String("map", "", "comma separated field=column pairs, e.g. productCode=sku,attr.color=Colour")
+	prefix := fs.String("prefix", "", "only export products whose productCode starts with this prefix")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: catalog export [-format csv|ndjson] [-map field=column,...] [-prefix code] file|-")
+		return 2
+	}
+	path := fs.Arg(0)
+	f, err := fileFormat(*format, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 2
+	}
+	m, err := parseMapping(*mapping)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 2
+	}
+
+	client, err := openClient()
+	if err != nil {
+		log.Errorf("Failed to open MySql client: %v", err)
+		return 1
+	}
+	defer client.Close()
+	ctx := context.Background()
+	defs, err := client.AttributeService().AttributeDefinitions(ctx)
+	if err != nil {
+		log.Errorf("Failed to read the attribute definitions: %v", err)
+		return 1
+	}
+	out, err := openOutput(path)
+	if err != nil {
+		log.Errorf("Failed to create the export file: %v", err)
+		return 1
+	}
+	n, err := exportProducts(ctx, client.ProductService(), defs, m, f, out, *prefix)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Errorf("Export failed: %v", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d products\n", n)
+	return 0
+}
+
+// exportProducts writes the products whose code starts with prefix to w
+// page by page, returning the number written.
+func exportProducts(ctx context.Context, products catalog.ProductService, defs []*catalog.AttributeDefinition,
+	mapping fieldMapping, format string, w io.Writer, prefix string) (int, error) {
+	fields := []string{fieldProductID, fieldProductCode, fieldShortDesc, fieldLongDesc, fieldVersion}
+	var names []string
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, attributePrefix+name)
+	}
+
+	var write func(p *catalog.Product) error
+	var cw *csv.Writer
+	if format == formatCSV {
+		cw = csv.NewWriter(w)
+		header := make([]string, len(fields))
+		for i, f := range fields {
+			header[i] = mapping.column(f)
+		}
+		if err := cw.Write(header); err != nil {
+			return 0, err
+		}
+		write = func(p *catalog.Product) error {
+			return cw.Write(csvRow(fields, p))
+		}
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(p *catalog.Product) error {
+			return enc.Encode(ndjsonObject(mapping, p))
+		}
+	}
+
+	n := 0
+	q := catalog.ProductQuery{ProductCodePrefix: prefix, Page: catalog.Page{Limit: catalog.MaxPageSize}}
+	for {
+		page, err := products.Products(ctx, q)
+		if err != nil {
+			return n, err
+		}
+		for _, p := range page.Products {
+			if err := write(p); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if page.Next == "" {
+			break
+		}
+		q.Cursor = page.Next
+	}
+	if cw != nil {
+		cw.Flush()
+		return n, cw.Error()
+	}
+	return n, nil
+}
+
+// csvRow returns the CSV cells of a product in the order of fields.
+// Attributes the product has no value for are left empty.
+func csvRow(fields []string, p *catalog.Product) []string {
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		switch f {
+		case fieldProductID:
+			row[i] = p.ID
+		case fieldProductCode:
+			row[i] = p.ProductCode
+		case fieldShortDesc:
+			row[i] = p.ShortDesc
+		case fieldLongDesc:
+			row[i] = p.LongDesc
+		case fieldVersion:
+			row[i] = strconv.FormatInt(p.Version, 10)
+		default:
+			if v, ok := p.Attributes[strings.TrimPrefix(f, attributePrefix)]; ok {
+				row[i] = formatValue(v)
+			}
+		}
+	}
+	return row
+}
+
+// ndjsonObject returns the JSON object of a product with the mapped keys
+// renamed. Attributes that are not mapped stay in the attributes object.
+func ndjsonObject(mapping fieldMapping, p *catalog.Product) map[string]interface{} {
+	obj := map[string]interface{}{
+		mapping.column(fieldProductID):   p.ID,
+		mapping.column(fieldProductCode): p.ProductCode,
+		mapping.column(fieldShortDesc):   p.ShortDesc,
+		mapping.column(fieldLongDesc):    p.LongDesc,
+		mapping.column(fieldVersion):     p.Version,
+	}
+	attrs := map[string]interface{}{}
+	for name, v := range p.Attributes {
+		if c, ok := mapping[attributePrefix+name]; ok {
+			obj[c] = v
+		} else {
+			attrs[name] = v
+		}
+	}
+	if len(attrs) > 0 {
+		obj["attributes"] = attrs
+	}
+	return obj
+}
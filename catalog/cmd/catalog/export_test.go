@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestExportProducts_CSV(t *testing.T) {
+	var ps mock.ProductService
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if q.ProductCodePrefix != "t" {
+			t.Errorf("expected the prefix filter, got %q", q.ProductCodePrefix)
+		}
+		if q.Cursor == "" {
+			return &catalog.ProductPage{Products: []*catalog.Product{
+				{ID: "7", ProductCode: "tee", ShortDesc: "Tee, red", Version: 2, Attributes: map[string]interface{}{"weight": 0.25}},
+			}, Next: "c1"}, nil
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{
+			{ID: "8", ProductCode: "top", Version: 1, Attributes: map[string]interface{}{"color": "blue"}},
+		}}, nil
+	}
+
+	var out bytes.Buffer
+	n, err := exportProducts(context.Background(), &ps, importDefs, fieldMapping{"productCode": "sku"}, formatCSV, &out, "t")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 products, got %d", n)
+	}
+	want := "productId,sku,shortDesc,longDesc,version,attr.color,attr.weight\n" +
+		"7,tee,\"Tee, red\",,2,,0.25\n" +
+		"8,top,,,1,blue,\n"
+	if out.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestExportProducts_NDJSONRoundTrip(t *testing.T) {
+	var ps mock.ProductService
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		return &catalog.ProductPage{Products: []*catalog.Product{
+			{ID: "7", ProductCode: "tee", ShortDesc: "Tee", Version: 2,
+				Attributes: map[string]interface{}{"color": "red", "weight": 0.25}},
+		}}, nil
+	}
+	ps.ProductByCodeFn = existingProducts()
+	ps.BatchProductsFn = func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+		p := ops[0].Product
+		if p.ProductCode != "tee" || p.ShortDesc != "Tee" || p.Attributes["color"] != "red" || p.Attributes["weight"] != 0.25 {
+			t.Errorf("unexpected product: %+v", p)
+		}
+		return []*catalog.BatchResult{{Product: p, Created: true}}, nil
+	}
+
+	m := fieldMapping{"attr.color": "Colour"}
+	var out bytes.Buffer
+	if _, err := exportProducts(context.Background(), &ps, importDefs, m, formatNDJSON, &out, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), `"Colour":"red"`) || !strings.Contains(out.String(), `"attributes":{"weight":0.25}`) {
+		t.Errorf("unexpected export: %s", out.String())
+	}
+
+	// Import the export into an empty catalog.
+	var report bytes.Buffer
+	im := newImporter(&ps, importDefs, m, false, &report)
+	if err := im.run(context.Background(), newRecordReader(formatNDJSON, &out)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !ps.BatchProductsInvoked || !strings.Contains(report.String(), "1 records: 1 created, 0 updated, 0 failed") {
+		t.Errorf("unexpected report: %s", report.String())
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// importChunkSize is the number of products written per batch.
+const importChunkSize = 500
+
+// runImport implements the import subcommand:
+//
+//	catalog import [-format csv|ndjson] [-map field=column,...] [-dry-run] file
+//
+// Products are matched by productCode: existing products are updated with
+// the fields present in the file, others are created. A dry run validates
+// every record and reports what would change without writing anything.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "file format, csv or ndjson (default: from the file extension)")
+	mapping := fs.String("map", "", "comma separated field=column pairs, e.g. productCode=sku,attr.color=Colour")
+	dryRun := fs.Bool("dry-run", false, "validate the file and report the changes without writing them")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: catalog import [-format csv|ndjson] [-map field=column,...] [-dry-run] file|-")
+		return 2
+	}
+	path := fs.Arg(0)
+	f, err := fileFormat(*format, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 2
+	}
+	m, err := parseMapping(*mapping)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 2
+	}
+	in, err := openInput(path)
+	if err != nil {
+		log.Errorf("Failed to open the import file: %v", err)
+		return 1
+	}
+	defer in.Close()
+
+	client, err := openClient()
+	if err != nil {
+		log.Errorf("Failed to open MySql client: %v", err)
+		return 1
+	}
+	defer client.Close()
+	// Imported changes are recorded in the product history as made by the importer.
+	ctx := catalog.WithActor(context.Background(), "catalog import")
+	defs, err := client.AttributeService().AttributeDefinitions(ctx)
+	if err != nil {
+		log.Errorf("Failed to read the attribute definitions: %v", err)
+		return 1
+	}
+	im := newImporter(client.ProductService(), defs, m, *dryRun, os.Stdout)
+	if err := im.run(ctx, newRecordReader(f, in)); err != nil {
+		log.Errorf("Import failed: %v", err)
+		return 1
+	}
+	if im.failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// recordReader reads the records of an import file keyed by column.
+type recordReader interface {
+	// Read returns the next record, or io.EOF after the last one. A
+	// recordError only affects the current record.
+	Read() (map[string]interface{}, error)
+}
+
+// recordError reports a record that cannot be read.
+type recordError string
+
+func (e recordError) Error() string {
+	return string(e)
+}
+
+// newRecordReader returns a reader for the format.
+func newRecordReader(format string, r io.Reader) recordReader {
+	if format == formatCSV {
+		return &csvRecordReader{r: csv.NewReader(r)}
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &ndjsonRecordReader{s: s}
+}
+
+// csvRecordReader reads CSV files whose first row names the columns.
+type csvRecordReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func (c *csvRecordReader) Read() (map[string]interface{}, error) {
+	if c.header == nil {
+		header, err := c.r.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the header: %v", err)
+		}
+		c.header = header
+	}
+	row, err := c.r.Read()
+	var pe *csv.ParseError
+	if errors.As(err, &pe) && pe.Err == csv.ErrFieldCount {
+		return nil, recordError(fmt.Sprintf("has %d columns, the header has %d", len(row), len(c.header)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		rec[c.header[i]] = v
+	}
+	return rec, nil
+}
+
+// ndjsonRecordReader reads one JSON product object per line. The values
+// of the attributes object are read as attr.<name> columns.
+type ndjsonRecordReader struct {
+	s *bufio.Scanner
+}
+
+func (n *ndjsonRecordReader) Read() (map[string]interface{}, error) {
+	for n.s.Scan() {
+		line := strings.TrimSpace(n.s.Text())
+		if line == "" {
+			continue
+		}
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, recordError(fmt.Sprintf("is not a JSON object: %v", err))
+		}
+		if v, ok := rec["attributes"]; ok {
+			attrs, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, recordError("attributes must be an object")
+			}
+			delete(rec, "attributes")
+			for name, value := range attrs {
+				rec[attributePrefix+name] = value
+			}
+		}
+		return rec, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// importer upserts the records of an import file by productCode, writing
+// them in batches of importChunkSize.
+type importer struct {
+	products catalog.ProductService
+	defs     []*catalog.AttributeDefinition
+	mapping  fieldMapping
+	dryRun   bool
+	report   io.Writer
+
+	records, created, updated, failed int
+
+	// pending holds the products of the unwritten batch by productCode;
+	// in a dry run it holds every product that would be created.
+	pending map[string]*catalog.Product
+	ops     []*catalog.BatchOperation
+	numbers []int
+	unknown map[string]bool
+}
+
+// newImporter returns an importer writing its report to report.
+func newImporter(products catalog.ProductService, defs []*catalog.AttributeDefinition, mapping fieldMapping, dryRun bool, report io.Writer) *importer {
+	return &importer{
+		products: products,
+		defs:     defs,
+		mapping:  mapping,
+		dryRun:   dryRun,
+		report:   report,
+		pending:  map[string]*catalog.Product{},
+		unknown:  map[string]bool{},
+	}
+}
+
+// run imports every record and writes the report. Invalid records are
+// reported and skipped; the error is set when the import had to stop.
+func (im *importer) run(ctx context.Context, rr recordReader) error {
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		im.records++
+		if re, ok := err.(recordError); ok {
+			im.fail(im.records, re)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := im.add(ctx, im.records, rec); err != nil {
+			return err
+		}
+	}
+	if err := im.flush(ctx); err != nil {
+		return err
+	}
+	if im.dryRun {
+		fmt.Fprintf(im.report, "%d records: %d to create, %d to update, %d invalid (dry run, nothing was written)\n",
+			im.records, im.created, im.updated, im.failed)
+	} else {
+		fmt.Fprintf(im.report, "%d records: %d created, %d updated, %d failed\n",
+			im.records, im.created, im.updated, im.failed)
+	}
+	return nil
+}
+
+// add validates a record and queues it for writing.
+func (im *importer) add(ctx context.Context, number int, rec map[string]interface{}) error {
+	fields := im.fields(rec)
+	code, _ := fields[fieldProductCode].(string)
+	if code == "" {
+		im.fail(number, catalog.ValidationError("product is invalid",
+			[]catalog.FieldError{{Field: fieldProductCode, Message: "is required"}}))
+		return nil
+	}
+	product, err := im.existing(ctx, code)
+	if err != nil {
+		return err
+	}
+	exists := product != nil
+	if !exists {
+		product = &catalog.Product{}
+	}
+	if err := applyFields(fields, product); err != nil {
+		im.fail(number, err)
+		return nil
+	}
+	if err := product.Validate(); err != nil {
+		im.fail(number, err)
+		return nil
+	}
+	if _, err := catalog.CanonicalAttributes(im.defs, product.Attributes); err != nil {
+		im.fail(number, err)
+		return nil
+	}
+	if im.dryRun {
+		if exists {
+			im.updated++
+		} else {
+			im.created++
+		}
+		im.pending[product.ProductCode] = product
+		return nil
+	}
+	im.pending[product.ProductCode] = product
+	im.ops = append(im.ops, &catalog.BatchOperation{Op: catalog.BatchUpsert, Product: product})
+	im.numbers = append(im.numbers, number)
+	if len(im.ops) >= importChunkSize {
+		return im.flush(ctx)
+	}
+	return nil
+}
+
+// existing returns the product with the code, or nil if there is none. A
+// product still waiting in the batch is written first so it is updated
+// rather than created twice.
+func (im *importer) existing(ctx context.Context, code string) (*catalog.Product, error) {
+	if p, ok := im.pending[code]; ok {
+		if im.dryRun {
+			return copyProduct(p), nil
+		}
+		if err := im.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+	p, err := im.products.ProductByCode(ctx, code)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// flush writes the queued products as a best-effort batch.
+func (im *importer) flush(ctx context.Context) error {
+	if im.dryRun || len(im.ops) == 0 {
+		return nil
+	}
+	results, err := im.products.BatchProducts(ctx, im.ops, false)
+	if err != nil {
+		return err
+	}
+	for i, r := range results {
+		switch {
+		case r.Err != nil:
+			im.fail(im.numbers[i], r.Err)
+		case r.Created:
+			im.created++
+		default:
+			im.updated++
+		}
+	}
+	im.ops, im.numbers = nil, nil
+	im.pending = map[string]*catalog.Product{}
+	return nil
+}
+
+// fields maps the columns of a record to product fields. Product ids and
+// versions are ignored as products are matched by productCode, so files
+// written by export can be imported as they are.
+func (im *importer) fields(rec map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(rec))
+	for column, v := range rec {
+		f := im.mapping.field(column)
+		switch {
+		case f == fieldProductCode, f == fieldShortDesc, f == fieldLongDesc,
+			strings.HasPrefix(f, attributePrefix) && len(f) > len(attributePrefix):
+			fields[f] = v
+		case f == fieldProductID, f == fieldVersion, f == "":
+		default:
+			if !im.unknown[column] {
+				im.unknown[column] = true
+				fmt.Fprintf(im.report, "ignoring unknown column %q\n", column)
+			}
+		}
+	}
+	return fields
+}
+
+// fail reports a record that was not imported.
+func (im *importer) fail(number int, err error) {
+	im.failed++
+	msg := err.Error()
+	var cErr *catalog.Error
+	if errors.As(err, &cErr) && len(cErr.Fields) > 0 {
+		var parts []string
+		for _, f := range cErr.Fields {
+			parts = append(parts, f.Field+" "+f.Message)
+		}
+		msg = strings.Join(parts, "; ")
+	}
+	fmt.Fprintf(im.report, "record %d: %s\n", number, msg)
+}
+
+// applyFields sets the fields present in a record on the product. An empty
+// or null attribute value removes the attribute.
+func applyFields(fields map[string]interface{}, product *catalog.Product) error {
+	var errs []catalog.FieldError
+	for _, f := range []struct {
+		name string
+		dst  *string
+	}{{fieldProductCode, &product.ProductCode}, {fieldShortDesc, &product.ShortDesc}, {fieldLongDesc, &product.LongDesc}} {
+		v, ok := fields[f.name]
+		if !ok {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			errs = append(errs, catalog.FieldError{Field: f.name, Message: "must be a string"})
+			continue
+		}
+		*f.dst = s
+	}
+	for f, v := range fields {
+		if !strings.HasPrefix(f, attributePrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(f, attributePrefix)
+		if s, ok := v.(string); v == nil || ok && s == "" {
+			delete(product.Attributes, name)
+			continue
+		}
+		if product.Attributes == nil {
+			product.Attributes = map[string]interface{}{}
+		}
+		product.Attributes[name] = v
+	}
+	return catalog.ValidationError("record is invalid", errs)
+}
+
+// copyProduct returns a copy of p that can be changed independently.
+func copyProduct(p *catalog.Product) *catalog.Product {
+	c := *p
+	c.Attributes = make(map[string]interface{}, len(p.Attributes))
+	for k, v := range p.Attributes {
+		c.Attributes[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+// importDefs are the attribute definitions used by the import tests.
+var importDefs = []*catalog.AttributeDefinition{
+	{Name: "color", Type: catalog.AttributeString, AllowedValues: []string{"red", "blue"}},
+	{Name: "weight", Type: catalog.AttributeNumber},
+}
+
+// existingProducts returns a ProductByCodeFn finding the products by code.
+func existingProducts(products ...*catalog.Product) func(ctx context.Context, code string) (*catalog.Product, error) {
+	return func(ctx context.Context, code string) (*catalog.Product, error) {
+		for _, p := range products {
+			if p.ProductCode == code {
+				return p, nil
+			}
+		}
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product with code %q not found", code)
+	}
+}
+
+func TestImporter_CSV(t *testing.T) {
+	var ps mock.ProductService
+	ps.ProductByCodeFn = existingProducts(&catalog.Product{ID: "7", ProductCode: "tee", ShortDesc: "Tee", LongDesc: "A tee",
+		Version: 2, Attributes: map[string]interface{}{"weight": 0.2}})
+	ps.BatchProductsFn = func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+		if atomic || len(ops) != 2 {
+			t.Fatalf("expected a best-effort batch of 2 operations, got %d", len(ops))
+		}
+		tee := ops[0].Product
+		if tee.ID != "7" || tee.Version != 2 || tee.ShortDesc != "Red tee" || tee.LongDesc != "A tee" {
+			t.Errorf("expected the existing tee to be updated, got %+v", tee)
+		}
+		if tee.Attributes["color"] != "red" || tee.Attributes["weight"] != 0.2 {
+			t.Errorf("unexpected tee attributes: %v", tee.Attributes)
+		}
+		if hat := ops[1].Product; hat.ID != "" || hat.ProductCode != "cap" || len(hat.Attributes) != 0 {
+			t.Errorf("expected a new cap, got %+v", hat)
+		}
+		return []*catalog.BatchResult{{Product: tee}, {Product: ops[1].Product, Created: true}}, nil
+	}
+
+	input := "sku,title,attr.color,notes\n" +
+		"tee,Red tee,red,x\n" +
+		"cap,Cap,,\n" +
+		"sock,Sock,green,\n" +
+		",Nothing,,\n"
+	m, err := parseMapping("productCode=sku,shortDesc=title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report bytes.Buffer
+	im := newImporter(&ps, importDefs, m, false, &report)
+	if err := im.run(context.Background(), newRecordReader(formatCSV, strings.NewReader(input))); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{
+		`ignoring unknown column "notes"`,
+		"record 3: attributes.color is not one of the allowed values",
+		"record 4: productCode is required",
+		"4 records: 1 created, 1 updated, 2 failed",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestImporter_DryRunNDJSON(t *testing.T) {
+	var ps mock.ProductService
+	ps.ProductByCodeFn = existingProducts()
+
+	input := `{"productCode": "tee", "attributes": {"weight": 0.2}}
+
+{"productCode": "tee", "shortDesc": "Tee"}
+{"productCode": "cap", "attributes": {"weight": "heavy"}}
+not json
+`
+	var report bytes.Buffer
+	im := newImporter(&ps, importDefs, fieldMapping{}, true, &report)
+	if err := im.run(context.Background(), newRecordReader(formatNDJSON, strings.NewReader(input))); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ps.BatchProductsInvoked {
+		t.Fatal("expected a dry run not to write")
+	}
+	for _, want := range []string{
+		"record 3: attributes.weight must be a number",
+		"record 4: is not a JSON object",
+		"4 records: 1 to create, 1 to update, 2 invalid (dry run, nothing was written)",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	if _, err := parseMapping("productCode=sku,shortDesc=sku"); err == nil {
+		t.Error("expected a column mapped twice to fail")
+	}
+	if _, err := parseMapping("price=amount"); err == nil {
+		t.Error("expected an unknown field to fail")
+	}
+	m, err := parseMapping("productCode=sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.field("sku") != fieldProductCode || m.field(fieldProductCode) != "" || m.field(fieldShortDesc) != fieldShortDesc {
+		t.Errorf("unexpected mapping: %v", m)
+	}
+}
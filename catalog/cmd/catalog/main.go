@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/mvonbodun/go-package-test/catalog/http"
 	"github.com/mvonbodun/go-package-test/catalog/mysql"
+	"github.com/mvonbodun/go-package-test/catalog/events"
+	"github.com/mvonbodun/go-package-test/catalog/webhooks"
 	log "github.com/sirupsen/logrus"
 	"go.opencensus.io/plugin/ochttp"
 	http2 "net/http"
@@ -53,6 +55,20 @@ func main() {
 	log.AddHook(logrus_stack.StandardHook())
 	log.Info("Finished initializing logrus.")
 
+	// Run a subcommand instead of the server if one was given.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		default:
+			log.Fatalf("Unknown command %q. Usage: catalog [migrate up|down [n]|status | import file | export file]", os.Args[1])
+		}
+	}
+
 	// If useStackdriver is set to "TRUE", enable the various stackdriver components
 	if useStackdriver == "TRUE" {
 		ctx := context.Background()
@@ -99,29 +115,73 @@ func main() {
 		log.AddHook(sdHook)
 	}
 
-	// Get the config to connect to the database
-	host := os.Getenv(mysqlDBHost)
-	user := os.Getenv(mysqlDBUser)
-	password := os.Getenv(mysqlDBPassword)
-	log.Infof("host: %v", host)
-
 	// Connect to the database
 	client := mysql.NewClient()
 	log.Info("Created new MySql client")
-	err := client.Open(mysql.MySQLConfig{
-		Host: host,
-		Username: user,
-		Password: password,
-	})
+	err := client.Open(mysqlConfig())
 	if err != nil {
 		log.Fatalf("Failed to open MySql client: %v", err)
 	}
 	// Close the Database connection when the program exits
 	defer client.Close()
 
+	// Purge the products that were deleted longer ago than the retention.
+	retention, purgeInterval, err := retentionConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if retention > 0 {
+		go runRetention(context.Background(), client.ProductService(), retention, purgeInterval)
+	}
+
+	// Relay the product events written by the product changes. The webhook
+	// deliveries are queued first, since queueing an event again is harmless.
+	publisher, relayInterval, err := relayConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	publishers := events.MultiPublisher{&webhooks.Publisher{Service: client.WebhookService()}}
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	go runRelay(context.Background(), client.EventService(), publishers, relayInterval)
+
+	// Send the queued webhook deliveries.
+	dispatcher, dispatchInterval, err := webhookConfig(client.WebhookService())
+	if err != nil {
+		log.Fatal(err)
+	}
+	go runDispatcher(context.Background(), dispatcher, dispatchInterval)
+
+	// Verify the bearer tokens with the keys of the token issuer.
+	verifier, err := authConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	policy, err := policyConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter, err := rateLimitConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Create the http Handler
 	h := http.NewHandler()
 	h.ProductService = client.ProductService()
+	h.PriceService = client.PriceService()
+	h.VariantService = client.VariantService()
+	h.CategoryService = client.CategoryService()
+	h.AttributeService = client.AttributeService()
+	h.WebhookService = client.WebhookService()
+	h.EventService = client.EventService()
+	h.APIKeyService = client.APIKeyService()
+	h.TenantService = client.TenantService()
+	h.Verifier = verifier
+	h.Policy = policy
+	h.TenantClaim = envString(tenantClaim, http.DefaultTenantClaim)
+	h.RateLimiter = limiter
 	h.Handler = h
 	//h.ErrorClient = errorClient
 
@@ -130,7 +190,7 @@ func main() {
 
 	errs := make(chan error)
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 		errs <- fmt.Errorf("%s", <-c)
 	}()
@@ -153,6 +213,17 @@ func defaultAuthScopes() []string {
 	}
 }
 
+// mysqlConfig returns the config to connect to the database from the environment.
+func mysqlConfig() mysql.MySQLConfig {
+	host := os.Getenv(mysqlDBHost)
+	log.Infof("host: %v", host)
+	return mysql.MySQLConfig{
+		Host: host,
+		Username: os.Getenv(mysqlDBUser),
+		Password: os.Getenv(mysqlDBPassword),
+	}
+}
+
 // envString retrieves an environment variable from the os, or uses the fallack if not set.
 func envString(env, fallback string) string {
 	e := os.Getenv(env)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mvonbodun/go-package-test/catalog/mysql"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// runMigrate implements the migrate subcommand:
+//
+//	catalog migrate up          apply all pending migrations
+//	catalog migrate down [n]    revert the last n migrations (default 1)
+//	catalog migrate status      list migrations and whether they are applied
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: catalog migrate up|down [n]|status")
+		return 2
+	}
+	m, err := mysql.NewMigrator(mysqlConfig())
+	if err != nil {
+		log.Errorf("Failed to connect for migrations: %v", err)
+		return 1
+	}
+	defer m.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Errorf("Migrate up failed: %v", err)
+			return 1
+		}
+		fmt.Printf("Applied %d migrations\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "migrate down: n must be a positive number")
+				return 2
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Errorf("Migrate down failed: %v", err)
+			return 1
+		}
+		fmt.Printf("Reverted %d migrations\n", n)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.Errorf("Migrate status failed: %v", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "migrate: unknown action %q\n", args[0])
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/events"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Environment variables configuring the relay of product events.
+const (
+	eventPublisher     = "EVENT_PUBLISHER"
+	eventRelayInterval = "EVENT_RELAY_INTERVAL"
+)
+
+// relayBatchSize is the number of events published per transaction.
+const relayBatchSize = 100
+
+// relayConfig reads which publisher relays the product events and how
+// often the outbox is checked. With a nil publisher the events only reach
+// the webhooks.
+func relayConfig() (catalog.EventPublisher, time.Duration, error) {
+	interval, err := time.ParseDuration(envString(eventRelayInterval, "1s"))
+	if err != nil || interval <= 0 {
+		return nil, 0, fmt.Errorf("%s must be a positive duration such as 1s", eventRelayInterval)
+	}
+	switch kind := envString(eventPublisher, "log"); kind {
+	case "log":
+		return &events.LogPublisher{}, interval, nil
+	case "channel":
+		return events.NewChannelPublisher(), interval, nil
+	case "none":
+		return nil, interval, nil
+	default:
+		return nil, 0, fmt.Errorf("%s must be log, channel or none, not %q", eventPublisher, kind)
+	}
+}
+
+// runRelay publishes the queued product events every interval until the
+// context is done.
+func runRelay(ctx context.Context, es catalog.EventService, p catalog.EventPublisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		relayPending(ctx, es, p)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayPending publishes batches of queued events until the outbox is
+// empty. Failures are logged and retried on the next run.
+func relayPending(ctx context.Context, es catalog.EventService, p catalog.EventPublisher) {
+	for ctx.Err() == nil {
+		n, err := es.PublishPending(ctx, p, relayBatchSize)
+		if err != nil {
+			log.Errorf("Failed to publish product events: %v", err)
+			return
+		}
+		if n < relayBatchSize {
+			return
+		}
+	}
+}
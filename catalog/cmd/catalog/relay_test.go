@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/events"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestRelayPending(t *testing.T) {
+	var es mock.EventService
+	var p events.LogPublisher
+	var calls int
+	es.PublishPendingFn = func(ctx context.Context, publisher catalog.EventPublisher, limit int) (int, error) {
+		if publisher != &p || limit != relayBatchSize {
+			t.Fatalf("unexpected arguments: %v, %d", publisher, limit)
+		}
+		calls++
+		// Two full batches, then the rest.
+		if calls <= 2 {
+			return relayBatchSize, nil
+		}
+		return 3, nil
+	}
+	relayPending(context.Background(), &es, &p)
+	if calls != 3 {
+		t.Errorf("expected the outbox to be drained in 3 batches, got %d", calls)
+	}
+
+	// A failure ends the run.
+	calls = 0
+	es.PublishPendingFn = func(ctx context.Context, publisher catalog.EventPublisher, limit int) (int, error) {
+		calls++
+		return relayBatchSize, errors.New("broker unavailable")
+	}
+	relayPending(context.Background(), &es, &p)
+	if calls != 1 {
+		t.Errorf("expected the run to stop at the failure, got %d batches", calls)
+	}
+}
+
+func TestRunRelay(t *testing.T) {
+	var es mock.EventService
+	runs := make(chan struct{}, 1)
+	es.PublishPendingFn = func(ctx context.Context, p catalog.EventPublisher, limit int) (int, error) {
+		runs <- struct{}{}
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runRelay(ctx, &es, &events.LogPublisher{}, time.Hour)
+		close(done)
+	}()
+	<-runs
+	cancel()
+	<-done
+}
+
+func TestRelayConfig(t *testing.T) {
+	defer os.Unsetenv(eventPublisher)
+	p, interval, err := relayConfig()
+	if _, ok := p.(*events.LogPublisher); !ok || interval != time.Second || err != nil {
+		t.Errorf("unexpected config: %T %v %v", p, interval, err)
+	}
+	os.Setenv(eventPublisher, "none")
+	if p, _, err := relayConfig(); p != nil || err != nil {
+		t.Errorf("expected the relay to be disabled, got %T %v", p, err)
+	}
+	os.Setenv(eventPublisher, "kafka")
+	if _, _, err := relayConfig(); err == nil {
+		t.Error("expected an unknown publisher to fail")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Environment variables configuring the purge of deleted products.
+const (
+	productRetention     = "PRODUCT_RETENTION"
+	productPurgeInterval = "PRODUCT_PURGE_INTERVAL"
+)
+
+// retentionConfig reads how long deleted products are kept and how often
+// expired ones are purged. A retention of 0 keeps deleted products forever.
+func retentionConfig() (retention, interval time.Duration, err error) {
+	if retention, err = time.ParseDuration(envString(productRetention, "720h")); err != nil || retention < 0 {
+		return 0, 0, fmt.Errorf("%s must be a positive duration such as 720h", productRetention)
+	}
+	if interval, err = time.ParseDuration(envString(productPurgeInterval, "1h")); err != nil || interval <= 0 {
+		return 0, 0, fmt.Errorf("%s must be a positive duration such as 1h", productPurgeInterval)
+	}
+	return retention, interval, nil
+}
+
+// runRetention purges the products deleted more than retention ago every
+// interval until the context is done.
+func runRetention(ctx context.Context, products catalog.ProductService, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purgeExpired(ctx, products, time.Now().Add(-retention))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired purges the products deleted before the cutoff. Failures are
+// logged and retried on the next run.
+func purgeExpired(ctx context.Context, products catalog.ProductService, cutoff time.Time) {
+	n, err := products.PurgeDeletedProducts(ctx, cutoff)
+	if err != nil {
+		log.Errorf("Failed to purge deleted products: %v", err)
+	}
+	if n > 0 {
+		log.Infof("Purged %d products deleted before %v", n, cutoff.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestRunRetention(t *testing.T) {
+	var ps mock.ProductService
+	cutoffs := make(chan time.Time, 1)
+	ps.PurgeDeletedProductsFn = func(ctx context.Context, before time.Time) (int64, error) {
+		cutoffs <- before
+		return 3, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runRetention(ctx, &ps, 48*time.Hour, time.Hour)
+		close(done)
+	}()
+	cutoff := <-cutoffs
+	cancel()
+	<-done
+	if age := time.Since(cutoff); age < 48*time.Hour || age > 49*time.Hour {
+		t.Errorf("expected products deleted 48h ago to be purged, got a cutoff %v ago", age)
+	}
+}
+
+func TestRetentionConfig(t *testing.T) {
+	defer os.Unsetenv(productRetention)
+	os.Setenv(productRetention, "24h")
+	retention, interval, err := retentionConfig()
+	if err != nil || retention != 24*time.Hour || interval != time.Hour {
+		t.Errorf("unexpected config: %v %v %v", retention, interval, err)
+	}
+	os.Setenv(productRetention, "a month")
+	if _, _, err := retentionConfig(); err == nil {
+		t.Error("expected an invalid retention to fail")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog/mysql"
+)
+
+// Supported import and export file formats.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// Product fields as they are named in import and export files. Attribute
+// values are named attributePrefix followed by the attribute name.
+const (
+	fieldProductID   = "productId"
+	fieldProductCode = "productCode"
+	fieldShortDesc   = "shortDesc"
+	fieldLongDesc    = "longDesc"
+	fieldVersion     = "version"
+	attributePrefix  = "attr."
+)
+
+// fileFormat returns the format named by the -format flag, or derives it
+// from the file extension.
+func fileFormat(flagValue, path string) (string, error) {
+	format := flagValue
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = formatCSV
+		case ".ndjson", ".jsonl":
+			format = formatNDJSON
+		}
+	}
+	switch format {
+	case formatCSV, formatNDJSON:
+		return format, nil
+	case "":
+		return "", fmt.Errorf("cannot tell the format of %q, use -format csv|ndjson", path)
+	}
+	return "", fmt.Errorf("unknown format %q, use csv or ndjson", format)
+}
+
+// fieldMapping maps product fields to the columns, or NDJSON keys, holding
+// them in a file. Unmapped fields use their own name.
+type fieldMapping map[string]string
+
+// parseMapping parses a mapping such as "productCode=sku,attr.color=Colour".
+func parseMapping(spec string) (fieldMapping, error) {
+	m := fieldMapping{}
+	if spec == "" {
+		return m, nil
+	}
+	columns := map[string]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		field, column := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch {
+		case field == fieldProductID, field == fieldProductCode, field == fieldShortDesc,
+			field == fieldLongDesc, field == fieldVersion, strings.HasPrefix(field, attributePrefix):
+		default:
+			return nil, fmt.Errorf("cannot map unknown field %q", field)
+		}
+		if columns[column] {
+			return nil, fmt.Errorf("column %q is mapped more than once", column)
+		}
+		columns[column] = true
+		m[field] = column
+	}
+	return m, nil
+}
+
+// column returns the column holding field.
+func (m fieldMapping) column(field string) string {
+	if c, ok := m[field]; ok {
+		return c
+	}
+	return field
+}
+
+// field returns the product field held by column, or "" when the column
+// is the default name of a field that was mapped to another column.
+func (m fieldMapping) field(column string) string {
+	for f, c := range m {
+		if c == column {
+			return f
+		}
+	}
+	if _, mapped := m[column]; mapped {
+		return ""
+	}
+	return column
+}
+
+// formatValue formats a typed attribute value for a CSV cell.
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// openClient connects to the catalog database.
+func openClient() (*mysql.Client, error) {
+	client := mysql.NewClient()
+	if err := client.Open(mysqlConfig()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// openInput opens path for reading, "-" being standard input.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// openOutput creates path for writing, "-" being standard output.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// nopWriteCloser keeps standard output open when the export is closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
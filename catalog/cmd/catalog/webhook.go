@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/webhooks"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Environment variables configuring the webhook deliveries.
+const (
+	webhookDispatchInterval = "WEBHOOK_DISPATCH_INTERVAL"
+	webhookMaxAttempts      = "WEBHOOK_MAX_ATTEMPTS"
+	webhookTimeout          = "WEBHOOK_TIMEOUT"
+)
+
+// webhookConfig reads how the webhook deliveries are sent and how often
+// the queue is checked.
+func webhookConfig(ws catalog.WebhookService) (*webhooks.Dispatcher, time.Duration, error) {
+	interval, err := time.ParseDuration(envString(webhookDispatchInterval, "1s"))
+	if err != nil || interval <= 0 {
+		return nil, 0, fmt.Errorf("%s must be a positive duration such as 1s", webhookDispatchInterval)
+	}
+	timeout, err := time.ParseDuration(envString(webhookTimeout, webhooks.DefaultTimeout.String()))
+	if err != nil || timeout <= 0 {
+		return nil, 0, fmt.Errorf("%s must be a positive duration such as 10s", webhookTimeout)
+	}
+	attempts, err := strconv.Atoi(envString(webhookMaxAttempts, strconv.Itoa(webhooks.DefaultMaxAttempts)))
+	if err != nil || attempts < 1 {
+		return nil, 0, fmt.Errorf("%s must be a positive number of attempts", webhookMaxAttempts)
+	}
+	d := webhooks.NewDispatcher(ws)
+	d.Client.Timeout = timeout
+	d.MaxAttempts = attempts
+	return d, interval, nil
+}
+
+// runDispatcher sends the due webhook deliveries every interval until the
+// context is done.
+func runDispatcher(ctx context.Context, d *webhooks.Dispatcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		dispatchDue(ctx, d)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue sends batches of due deliveries until none are left.
+// Failures are logged and retried on the next run.
+func dispatchDue(ctx context.Context, d *webhooks.Dispatcher) {
+	for ctx.Err() == nil {
+		n, err := d.DeliverDue(ctx)
+		if err != nil {
+			log.Errorf("Failed to dispatch webhook deliveries: %v", err)
+			return
+		}
+		if n < d.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"github.com/mvonbodun/go-package-test/catalog/webhooks"
+	"golang.org/x/net/context"
+)
+
+func TestDispatchDue(t *testing.T) {
+	var ws mock.WebhookService
+	d := webhooks.NewDispatcher(&ws)
+	d.BatchSize = 2
+	var calls int
+	ws.ClaimDeliveriesFn = func(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error) {
+		calls++
+		// One full batch of deliveries that cannot be sent, then none.
+		if calls == 1 {
+			hook := &catalog.Webhook{URL: "http://127.0.0.1:0/hooks"}
+			return []*catalog.WebhookDelivery{{ID: "1", Webhook: hook}, {ID: "2", Webhook: hook}}, nil
+		}
+		return nil, nil
+	}
+	ws.RecordAttemptFn = func(ctx context.Context, dl *catalog.WebhookDelivery) error {
+		if dl.Status != catalog.DeliveryFailed || dl.Attempts != 1 {
+			t.Errorf("unexpected attempt: %+v", dl)
+		}
+		return nil
+	}
+	dispatchDue(context.Background(), d)
+	if calls != 2 {
+		t.Errorf("expected the queue to be drained in 2 batches, got %d", calls)
+	}
+
+	// A failure ends the run.
+	calls = 0
+	ws.ClaimDeliveriesFn = func(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error) {
+		calls++
+		return nil, errors.New("database unavailable")
+	}
+	dispatchDue(context.Background(), d)
+	if calls != 1 {
+		t.Errorf("expected the run to stop at the failure, got %d batches", calls)
+	}
+}
+
+func TestWebhookConfig(t *testing.T) {
+	defer os.Unsetenv(webhookMaxAttempts)
+	var ws mock.WebhookService
+	d, interval, err := webhookConfig(&ws)
+	if err != nil || interval != time.Second || d.MaxAttempts != webhooks.DefaultMaxAttempts ||
+		d.Client.Timeout != webhooks.DefaultTimeout {
+		t.Errorf("unexpected config: %+v %v %v", d, interval, err)
+	}
+	os.Setenv(webhookMaxAttempts, "3")
+	if d, _, err := webhookConfig(&ws); err != nil || d.MaxAttempts != 3 {
+		t.Errorf("expected 3 attempts, got %+v %v", d, err)
+	}
+	os.Setenv(webhookMaxAttempts, "0")
+	if _, _, err := webhookConfig(&ws); err == nil {
+		t.Error("expected no attempts to fail")
+	}
+}
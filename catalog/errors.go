@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds returned by catalog services. Use errors.Is to test for them.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid")
+	// ErrVersionConflict reports that a record was changed since the
+	// version the caller based its change on.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrQuotaExceeded reports that a tenant reached one of its quotas.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Error is a domain error of a given kind with a message that is safe to
+// show to callers. Validation errors list the offending fields.
+type Error struct {
+	Kind    error
+	Message string
+	Fields  []FieldError
+}
+
+// FieldError describes why a single field failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind so that errors.Is(err, ErrNotFound) works.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Errorf returns an *Error of the given kind with a formatted message.
+func Errorf(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidationError returns an ErrInvalid *Error listing the failed fields,
+// or nil if there are none.
+func ValidationError(message string, fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &Error{Kind: ErrInvalid, Message: message, Fields: fields}
+}
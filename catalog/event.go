@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Product event types.
+const (
+	EventProductCreated  = "product.created"
+	EventProductUpdated  = "product.updated"
+	EventProductDeleted  = "product.deleted"
+	EventProductRestored = "product.restored"
+)
+
+// ProductEvent tells downstream services that a product changed. Events
+// are numbered in the order the changes were committed. Product is the
+// product after the change and is omitted for deletes.
+type ProductEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	TenantID   string    `json:"tenantId"`
+	ProductID  string    `json:"productId"`
+	Version    int64     `json:"version"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Product    *Product  `json:"product,omitempty"`
+}
+
+// EventPublisher delivers product events. Events are published in order,
+// but an event may be published again after a failure, so consumers
+// should ignore event IDs they have already seen.
+type EventPublisher interface {
+	Publish(ctx context.Context, e *ProductEvent) error
+}
+
+// EventService relays the product events that are written together with
+// the product changes.
+type EventService interface {
+	// PublishPending publishes up to limit unpublished events in order and
+	// returns how many were published. It stops at the first event that
+	// fails to publish; that event is retried by the next call.
+	PublishPending(ctx context.Context, p EventPublisher, limit int) (int, error)
+	// EventsAfter returns up to limit events of the context's tenant
+	// numbered above after, in order, whether published or not. Events
+	// are only returned once no event with a lower number can still
+	// commit, so that readers resuming after a number miss none.
+	EventsAfter(ctx context.Context, after int64, limit int) ([]*ProductEvent, error)
+	// LastEventID returns the number of the newest event of the context's
+	// tenant that EventsAfter returns, or 0 when there are none.
+	LastEventID(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"golang.org/x/net/context"
+)
+
+// Ensure ChannelPublisher implements catalog.EventPublisher
+var _ catalog.EventPublisher = &ChannelPublisher{}
+
+// ChannelPublisher hands product events to subscribers within the process.
+// Every subscriber receives every event published while it is subscribed.
+type ChannelPublisher struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+// subscription is the channel of a subscriber. done is closed when the
+// subscriber unsubscribes so that publishing no longer waits for it.
+type subscription struct {
+	events chan *catalog.ProductEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewChannelPublisher returns a publisher without subscribers.
+func NewChannelPublisher() *ChannelPublisher {
+	return &ChannelPublisher{subs: map[*subscription]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the published events, buffering up
+// to buffer events, and a function that ends the subscription. The channel
+// is not closed when the subscription ends.
+func (p *ChannelPublisher) Subscribe(buffer int) (<-chan *catalog.ProductEvent, func()) {
+	s := &subscription{events: make(chan *catalog.ProductEvent, buffer), done: make(chan struct{})}
+	p.mu.Lock()
+	p.subs[s] = struct{}{}
+	p.mu.Unlock()
+	return s.events, func() {
+		p.mu.Lock()
+		delete(p.subs, s)
+		p.mu.Unlock()
+		s.once.Do(func() { close(s.done) })
+	}
+}
+
+// Publish hands the event to every subscriber. It waits for subscribers
+// whose buffer is full, so a slow subscriber holds up the publishing.
+func (p *ChannelPublisher) Publish(ctx context.Context, e *catalog.ProductEvent) error {
+	p.mu.Lock()
+	subs := make([]*subscription, 0, len(p.subs))
+	for s := range p.subs {
+		subs = append(subs, s)
+	}
+	p.mu.Unlock()
+	for _, s := range subs {
+		select {
+		case s.events <- e:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"golang.org/x/net/context"
+)
+
+func TestChannelPublisher_Publish(t *testing.T) {
+	p := NewChannelPublisher()
+	first, _ := p.Subscribe(1)
+	second, cancel := p.Subscribe(0)
+	cancel()
+
+	// An ended subscription does not hold up the publishing.
+	e := &catalog.ProductEvent{ID: 7, Type: catalog.EventProductCreated, ProductID: "5"}
+	if err := p.Publish(context.Background(), e); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if got := <-first; got != e {
+		t.Errorf("expected event 7, got %+v", got)
+	}
+	select {
+	case got := <-second:
+		t.Errorf("expected no event after unsubscribing, got %+v", got)
+	default:
+	}
+}
+
+func TestChannelPublisher_PublishCanceled(t *testing.T) {
+	p := NewChannelPublisher()
+	p.Subscribe(0)
+
+	// Nobody reads the subscription, so publishing waits until canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Publish(ctx, &catalog.ProductEvent{ID: 7}); err != context.Canceled {
+		t.Errorf("expected context.Canceled but got: %v", err)
+	}
+}
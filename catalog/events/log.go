@@ -0,0 +1,33 @@
+// Package events provides publishers of product events.
+package events
+
+import (
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure LogPublisher implements catalog.EventPublisher
+var _ catalog.EventPublisher = &LogPublisher{}
+
+// LogPublisher writes product events to the log. It is meant for local
+// development, where no downstream service listens.
+type LogPublisher struct {
+	// Logger receives the events; the standard logger when nil.
+	Logger log.FieldLogger
+}
+
+// Publish logs the event.
+func (p *LogPublisher) Publish(ctx context.Context, e *catalog.ProductEvent) error {
+	logger := p.Logger
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	logger.WithFields(log.Fields{
+		"eventId":   e.ID,
+		"type":      e.Type,
+		"productId": e.ProductID,
+		"version":   e.Version,
+	}).Info("Product event")
+	return nil
+}
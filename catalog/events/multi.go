@@ -0,0 +1,24 @@
+package events
+
+import (
+	"github.com/mvonbodun/go-package-test/catalog"
+	"golang.org/x/net/context"
+)
+
+// Ensure MultiPublisher implements catalog.EventPublisher
+var _ catalog.EventPublisher = MultiPublisher{}
+
+// MultiPublisher publishes every event to each of its publishers in turn.
+type MultiPublisher []catalog.EventPublisher
+
+// Publish publishes the event to the publishers in order and stops at the
+// first failure. The event is published again to all of them when it is
+// retried, so the publishers before the failing one see it twice.
+func (m MultiPublisher) Publish(ctx context.Context, e *catalog.ProductEvent) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
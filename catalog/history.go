@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Product history actions.
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+)
+
+// ProductRevision is a recorded change of a product. Version is the
+// product version the change produced, so it identifies the revision.
+// Before is nil for creates and restores, After for deletes.
+type ProductRevision struct {
+	ProductID string    `json:"productId"`
+	Version   int64     `json:"version"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+	Before    *Product  `json:"before,omitempty"`
+	After     *Product  `json:"after,omitempty"`
+}
+
+// RevisionPage is a page of revisions, newest first. Next is empty on the
+// last page.
+type RevisionPage struct {
+	Revisions []*ProductRevision `json:"revisions"`
+	Next      string             `json:"next,omitempty"`
+}
+
+// FieldChange is a field that differs between two states of a product.
+// Attributes are named attributes.<name>. A nil value means the field is
+// unset in that state.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// DiffProducts lists the fields that differ between two states of a
+// product, either of which may be nil. The id, version and variants are
+// not compared.
+func DiffProducts(before, after *Product) []FieldChange {
+	if before == nil {
+		before = &Product{}
+	}
+	if after == nil {
+		after = &Product{}
+	}
+	changes := []FieldChange{}
+	add := func(field string, b, a interface{}) {
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	add("productCode", stringValue(before.ProductCode), stringValue(after.ProductCode))
+	add("shortDesc", stringValue(before.ShortDesc), stringValue(after.ShortDesc))
+	add("longDesc", stringValue(before.LongDesc), stringValue(after.LongDesc))
+	add("status", stringValue(string(before.Status)), stringValue(string(after.Status)))
+	add("publishAt", timeValue(before.PublishAt), timeValue(after.PublishAt))
+	add("unpublishAt", timeValue(before.UnpublishAt), timeValue(after.UnpublishAt))
+	add("deletedAt", timeValue(before.DeletedAt), timeValue(after.DeletedAt))
+	names := map[string]bool{}
+	for name := range before.Attributes {
+		names[name] = true
+	}
+	for name := range after.Attributes {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		add("attributes."+name, before.Attributes[name], after.Attributes[name])
+	}
+	return changes
+}
+
+// stringValue returns nil for an empty string so unset fields diff as null.
+func stringValue(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// timeValue returns a time as an RFC 3339 string, or nil.
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// actorKey is the context key of the actor.
+type actorKey struct{}
+
+// WithActor returns a context recording who makes the changes done with
+// it, e.g. the subject of the caller's token.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor recorded by WithActor, or "".
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// apiKeyHeader carries the API key of machine clients, as an alternative
+// to a bearer token.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyTouchInterval limits how often the last use of a key is written.
+const apiKeyTouchInterval = time.Minute
+
+// maxRotationGrace is the longest the previous secret of a rotated key
+// stays valid, in seconds.
+const maxRotationGrace = 7 * 24 * 60 * 60
+
+// authenticateAPIKey looks up the API key of the request. It returns the
+// request carrying the key's scopes and tenant as claims with the subject
+// "apikey:<id>", or responds 401 for an unknown, revoked or expired key
+// and returns nil.
+func (h *Handler) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string) *http.Request {
+	if h.APIKeyService == nil {
+		log.Error("http: no API key service is configured")
+		respondWithError(w, r, http.StatusInternalServerError, "API keys are not configured")
+		return nil
+	}
+	k, err := h.APIKeyService.APIKeyByHash(r.Context(), auth.HashAPIKey(key))
+	if errors.Is(err, catalog.ErrNotFound) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`APIKey realm=%q`, realm))
+		respondWithError(w, r, http.StatusUnauthorized, "Invalid API key")
+		return nil
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return nil
+	}
+	now := time.Now()
+	reason := ""
+	switch {
+	case k.RevokedAt != nil:
+		reason = "API key " + k.Prefix + " is revoked"
+	case k.Expired(now):
+		reason = "API key " + k.Prefix + " has expired"
+	}
+	if reason != "" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`APIKey realm=%q`, realm))
+		respondWithError(w, r, http.StatusUnauthorized, reason)
+		return nil
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval {
+		// Failing to record the use must not fail the request.
+		h.APIKeyService.TouchAPIKey(r.Context(), k.ID, now)
+	}
+	claims := &auth.Claims{
+		Subject: "apikey:" + k.ID,
+		Scope:   strings.Join(k.Scopes, " "),
+		// The key only acts for the tenant it was issued in.
+		Raw: map[string]interface{}{h.tenantClaim(): k.TenantID},
+	}
+	ctx := auth.WithClaims(r.Context(), claims)
+	ctx = catalog.WithActor(ctx, claims.Subject)
+	return r.WithContext(ctx)
+}
+
+// GetAPIKeys retrieves all API keys, revoked ones included. The keys
+// themselves are never returned.
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeyService.APIKeys(r.Context())
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, keys)
+}
+
+// GetAPIKey retrieves a single API key.
+func (h *Handler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	k, err := h.APIKeyService.APIKey(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, k)
+}
+
+// AddAPIKey issues an API key with a name, scopes and an optional expiry
+// for the tenant of the request. The response is the only time the key is
+// shown.
+func (h *Handler) AddAPIKey(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddAPIKey: %v", err))
+		return
+	}
+	if !h.checkKeyScopes(w, r, body.Scopes) {
+		return
+	}
+	key, err := auth.NewAPIKey()
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	k := &catalog.APIKey{
+		Name:      body.Name,
+		TenantID:  catalog.TenantFromContext(r.Context()),
+		Prefix:    auth.APIKeyPrefix(key),
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+		CreatedBy: catalog.ActorFromContext(r.Context()),
+	}
+	if err := h.APIKeyService.CreateAPIKey(r.Context(), k, auth.HashAPIKey(key)); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	k.Key = key
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, r, http.StatusCreated, k)
+}
+
+// checkKeyScopes checks the scopes of a key being issued. It responds 422
+// for scopes that mean nothing to the service and 403 for scopes the
+// caller does not hold itself, since a key must not grant more than its
+// issuer has, and returns false.
+func (h *Handler) checkKeyScopes(w http.ResponseWriter, r *http.Request, scopes []string) bool {
+	policy := h.policy()
+	var fields []catalog.FieldError
+	for _, s := range scopes {
+		if s != adminScope && s != tenantAdminScope && !policy.namesScope(s) {
+			fields = append(fields, catalog.FieldError{Field: "scopes", Message: s + " is not a known scope"})
+		}
+	}
+	if len(fields) > 0 {
+		respondWithServiceError(w, r, catalog.ValidationError("API key is invalid", fields))
+		return false
+	}
+	for _, s := range scopes {
+		if !requestHasScope(r, s) {
+			respondWithError(w, r, http.StatusForbidden,
+				fmt.Sprintf("Forbidden: the caller cannot grant the %s scope it does not hold", s))
+			return false
+		}
+	}
+	return true
+}
+
+// RotateAPIKey replaces the key of an API key, keeping its name, scopes
+// and expiry. The "grace" query parameter keeps the previous key valid for
+// that many seconds, so that clients can switch over.
+func (h *Handler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	grace, err := intParam(r.URL.Query().Get("grace"), 0, maxRotationGrace)
+	if err != nil {
+		respondWithBadRequest(w, r, catalog.ValidationError("invalid query parameters",
+			[]catalog.FieldError{{Field: "grace", Message: err.Error()}}))
+		return
+	}
+	// Rotating hands out the key, so it takes the scopes of the key too.
+	k, err := h.APIKeyService.APIKey(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	if !h.checkKeyScopes(w, r, k.Scopes) {
+		return
+	}
+	key, err := auth.NewAPIKey()
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	k, err = h.APIKeyService.RotateAPIKey(r.Context(), k.ID, auth.APIKeyPrefix(key),
+		auth.HashAPIKey(key), time.Duration(grace)*time.Second)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	k.Key = key
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, r, http.StatusOK, k)
+}
+
+// RevokeAPIKey revokes an API key for good. The key is kept, marked
+// revoked, for the record.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.APIKeyService.RevokeAPIKey(r.Context(), mux.Vars(r)["id"]); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
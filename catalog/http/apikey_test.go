@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_AddAPIKey(t *testing.T) {
+	// Inject our mock into our handler.
+	var ks mock.APIKeyService
+	h.APIKeyService = &ks
+
+	var hash string
+	ks.CreateAPIKeyFn = func(ctx context.Context, k *catalog.APIKey, h string) error {
+		if k.Name != "scanner" || len(k.Scopes) != 1 || k.CreatedBy != "admin" || !strings.HasPrefix(k.Prefix, "ck_") {
+			t.Fatalf("unexpected API key: %+v", k)
+		}
+		k.ID, hash = "4", h
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/apikeys", strings.NewReader(`{"name":"scanner","scopes":["read:product"]}`))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ks.CreateAPIKeyInvoked {
+		t.Fatal("expected CreateAPIKey() to be invoked.")
+	}
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	var k catalog.APIKey
+	if err := json.Unmarshal(w.Body.Bytes(), &k); err != nil {
+		t.Fatal(err)
+	}
+	// Only the hash of the key is stored.
+	if k.Key == "" || auth.HashAPIKey(k.Key) != hash || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("unexpected API key %+v for hash %s", k, hash)
+	}
+}
+
+func TestHandler_AddAPIKeyScopes(t *testing.T) {
+	// Inject our mock into our handler.
+	var ks mock.APIKeyService
+	h.APIKeyService = &ks
+
+	tests := []struct {
+		name   string
+		scopes string
+		code   int
+	}{
+		// adminToken does not hold admin:tenant.
+		{"escalation", `["read:product","admin:tenant"]`, http.StatusForbidden},
+		{"unknown scope", `["read:everything"]`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := newRequest("POST", "/apikeys", strings.NewReader(`{"name":"scanner","scopes":`+tt.scopes+`}`))
+		r.Header.Set("Authorization", "Bearer "+adminToken)
+		h.Router.ServeHTTP(w, r)
+		if w.Code != tt.code || ks.CreateAPIKeyInvoked {
+			t.Errorf("%s: unexpected response %d %s", tt.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandler_RotateAPIKey(t *testing.T) {
+	// Inject our mock into our handler.
+	var ks mock.APIKeyService
+	h.APIKeyService = &ks
+
+	ks.RotateAPIKeyFn = func(ctx context.Context, id, prefix, hash string, grace time.Duration) (*catalog.APIKey, error) {
+		if id != "4" || grace != time.Hour || hash == "" {
+			t.Fatalf("unexpected arguments: %v, %v, %v", id, hash, grace)
+		}
+		return &catalog.APIKey{ID: id, Prefix: prefix}, nil
+	}
+	ks.APIKeyFn = func(ctx context.Context, id string) (*catalog.APIKey, error) {
+		if id == "5" {
+			return &catalog.APIKey{ID: id, Scopes: []string{"admin:tenant"}}, nil
+		}
+		return &catalog.APIKey{ID: id, Scopes: []string{"read:product"}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/apikeys/4:rotate?grace=86400000", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || ks.RotateAPIKeyInvoked {
+		t.Fatalf("expected 400 for a long grace period, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("POST", "/apikeys/4:rotate?grace=3600", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"key":"ck_`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	// Rotating a key with scopes the caller does not hold would hand them out.
+	ks.RotateAPIKeyInvoked = false
+	w = httptest.NewRecorder()
+	r = newRequest("POST", "/apikeys/5:rotate", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden || ks.RotateAPIKeyInvoked {
+		t.Fatalf("expected 403, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_APIKeyAuthentication(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ks mock.APIKeyService
+	var ps mock.ProductService
+	h.APIKeyService = &ks
+	h.ProductService = &ps
+
+	expired := time.Now().Add(-time.Hour)
+	keys := map[string]*catalog.APIKey{
+		"ck_reader":  {ID: "1", Prefix: "ck_reader", Scopes: []string{"read:product"}},
+		"ck_writer":  {ID: "2", Prefix: "ck_writer", Scopes: []string{"write:product"}, LastUsedAt: &expired},
+		"ck_revoked": {ID: "3", Prefix: "ck_revoked", Scopes: []string{"read:product"}, RevokedAt: &expired},
+		"ck_expired": {ID: "4", Prefix: "ck_expired", Scopes: []string{"read:product"}, ExpiresAt: &expired},
+	}
+	ks.APIKeyByHashFn = func(ctx context.Context, hash string) (*catalog.APIKey, error) {
+		for key, k := range keys {
+			if auth.HashAPIKey(key) == hash {
+				return k, nil
+			}
+		}
+		return nil, catalog.Errorf(catalog.ErrNotFound, "API key not found")
+	}
+	var touched []string
+	ks.TouchAPIKeyFn = func(ctx context.Context, id string, t time.Time) error {
+		touched = append(touched, id)
+		return nil
+	}
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+
+	tests := []struct {
+		key  string
+		code int
+		body string
+	}{
+		{"ck_reader", http.StatusOK, ""},
+		{"ck_writer", http.StatusForbidden, "no rule allows product:read"},
+		{"ck_revoked", http.StatusUnauthorized, "API key ck_revoked is revoked"},
+		{"ck_expired", http.StatusUnauthorized, "API key ck_expired has expired"},
+		{"ck_unknown", http.StatusUnauthorized, "Invalid API key"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := newRequest("GET", "/product/100", nil)
+		r.Header.Del("Authorization")
+		r.Header.Set("X-API-Key", tt.key)
+		h.Router.ServeHTTP(w, r)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: unexpected response %d %s", tt.key, w.Code, w.Body.String())
+		}
+	}
+	if len(touched) != 2 || touched[0] != "1" || touched[1] != "2" {
+		t.Errorf("expected the valid keys to be touched, got %v", touched)
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// GetAttributeDefinitions retrieves all attribute definitions.
+func (h *Handler) GetAttributeDefinitions(w http.ResponseWriter, r *http.Request) {
+	defs, err := h.AttributeService.AttributeDefinitions(r.Context())
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, defs)
+}
+
+// GetAttributeDefinition retrieves a single attribute definition.
+func (h *Handler) GetAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	d, err := h.AttributeService.AttributeDefinition(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, d)
+}
+
+// AddAttributeDefinition adds an attribute definition.
+func (h *Handler) AddAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	d := &catalog.AttributeDefinition{}
+	if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddAttributeDefinition: %v", err))
+		return
+	}
+	if err := h.AttributeService.CreateAttributeDefinition(r.Context(), d); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, d)
+}
+
+// UpdateAttributeDefinition replaces the allowed values, unit and required
+// flag of an attribute definition.
+func (h *Handler) UpdateAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	d := &catalog.AttributeDefinition{}
+	if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdateAttributeDefinition: %v", err))
+		return
+	}
+	d.Name = mux.Vars(r)["name"]
+	if err := h.AttributeService.UpdateAttributeDefinition(r.Context(), d); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, d)
+}
+
+// DeleteAttributeDefinition deletes an attribute definition no product uses.
+func (h *Handler) DeleteAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	if err := h.AttributeService.DeleteAttributeDefinition(r.Context(), mux.Vars(r)["name"]); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetProductsFilteredByAttribute(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if len(q.Attributes) != 2 || q.Attributes[0] != (catalog.AttributeFilter{Name: "material", Value: "cotton"}) ||
+			q.Attributes[1] != (catalog.AttributeFilter{Name: "weight", Value: "1.5"}) {
+			t.Fatalf("unexpected attribute filters: %+v", q.Attributes)
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{
+			{ID: "1", ProductCode: "tee", Attributes: map[string]interface{}{"material": "cotton", "weight": 1.5}},
+		}}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?attr.weight=1.5&attr.material=cotton", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"attributes":{"material":"cotton","weight":1.5}`)) {
+		t.Fatalf("expected attributes in product: %s", w.Body.String())
+	}
+}
+
+func TestHandler_GetProductsInvalidAttributeName(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?attr.Bad-Name=x", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 status code, got %d", w.Code)
+	}
+	if ps.ProductsInvoked {
+		t.Fatal("expected Products() not to be invoked.")
+	}
+}
+
+func TestHandler_AddAttributeDefinition(t *testing.T) {
+	// Inject our mock into our handler.
+	var as mock.AttributeService
+	h.AttributeService = &as
+
+	as.CreateAttributeDefinitionFn = func(ctx context.Context, d *catalog.AttributeDefinition) error {
+		if d.Name != "voltage" || d.Type != catalog.AttributeInteger || len(d.AllowedValues) != 2 || d.Unit != "V" {
+			t.Fatalf("unexpected definition: %+v", d)
+		}
+		return nil
+	}
+
+	payload := []byte(`{ "name": "voltage", "type": "integer", "allowedValues": ["110", "230"], "unit": "V" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/attributes", bytes.NewBuffer(payload))
+	r.Header.Set("Authorization", "Bearer "+operatorToken)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 status code, got %d", w.Code)
+	}
+}
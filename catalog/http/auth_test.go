@@ -0,0 +1,68 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_Authentication(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": testIssuer, "aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(), "scope": "read:product"})
+	forged.Header["kid"] = "test"
+	forgedToken, _ := forged.SignedString(otherKey)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"iss": testIssuer, "aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(), "scope": "read:product"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := []struct {
+		name          string
+		authorization string
+		code          int
+		challenge     string
+	}{
+		{"missing header", "", http.StatusUnauthorized, `Bearer realm="catalog"`},
+		{"not a bearer token", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, `Bearer realm="catalog"`},
+		{"bearer without token", "Bearer", http.StatusUnauthorized, `Bearer realm="catalog"`},
+		{"unsigned", "Bearer " + unsigned, http.StatusUnauthorized, `error="invalid_token"`},
+		{"forged", "Bearer " + forgedToken, http.StatusUnauthorized, `error="invalid_token"`},
+		{"expired", "Bearer " + signToken(jwt.MapClaims{"scope": "read:product",
+			"exp": time.Now().Add(-time.Hour).Unix()}), http.StatusUnauthorized, `error="invalid_token"`},
+		{"other audience", "Bearer " + signToken(jwt.MapClaims{"scope": "read:product",
+			"aud": "https://other.example.com"}), http.StatusUnauthorized, `error="invalid_token"`},
+		{"missing scope", "Bearer " + signToken(jwt.MapClaims{"scope": "write:product"}),
+			http.StatusForbidden, `error="insufficient_scope"`},
+		{"valid", "Bearer " + testToken, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := newRequest("GET", "/product/100", nil)
+		r.Header.Del("Authorization")
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		h.Router.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d %s", tt.name, tt.code, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.challenge) || (tt.challenge == "") != (got == "") {
+			t.Errorf("%s: unexpected challenge %q", tt.name, got)
+		}
+	}
+}
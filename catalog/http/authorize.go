@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/codegangsta/negroni"
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// resource finds the categories of the resource a route acts on, for the
+// category conditions of the policy.
+type resource func(h *Handler, r *http.Request) func(ctx context.Context) ([]string, error)
+
+// authorize returns the middleware of a route performing the action on
+// the resource, nil for routes acting on a collection, and on any others
+// the action touches. It limits the rate
+// of the client address, authenticates the caller, limits its rate,
+// resolves its tenant and responds 403 with the reason when the policy
+// refuses the action.
+func (h *Handler) authorize(action string, res resource, others ...resource) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if !h.limitAddress(w, r) {
+			return
+		}
+		if r = h.authenticate(w, r); r == nil {
+			return
+		}
+		if !h.limit(w, r) {
+			return
+		}
+		if r = h.resolveTenant(w, r); r == nil {
+			return
+		}
+		policy := h.policy()
+		req := &PolicyRequest{Claims: auth.ClaimsFromContext(r.Context()), Action: action}
+		if res != nil {
+			req.Categories = res(h, r)
+		}
+		for _, o := range others {
+			req.Others = append(req.Others, o(h, r))
+		}
+		d, err := policy.Authorize(r.Context(), req)
+		if err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+		if !d.Allowed {
+			log.Debugf("Refused %s to %v: %s", action, req.Claims.Subject, d.Reason)
+			respondWithRefusal(w, r, d.Reason)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// respondWithRefusal responds 403 with the reason the policy refused the
+// request and a WWW-Authenticate challenge.
+func respondWithRefusal(w http.ResponseWriter, r *http.Request, reason string) {
+	w.Header().Set("WWW-Authenticate",
+		fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", error_description=%q`, realm, reason))
+	respondWithError(w, r, http.StatusForbidden, "Forbidden: "+reason)
+}
+
+// policy returns the policy authorizing the requests.
+func (h *Handler) policy() *Policy {
+	if h.Policy == nil {
+		return DefaultPolicy
+	}
+	return h.Policy
+}
+
+// productInPath is the product named by the {id} of the route.
+func productInPath(h *Handler, r *http.Request) func(ctx context.Context) ([]string, error) {
+	return h.productCategories(mux.Vars(r)["id"])
+}
+
+// productIDInPath is the product named by the {productId} of the route.
+func productIDInPath(h *Handler, r *http.Request) func(ctx context.Context) ([]string, error) {
+	return h.productCategories(mux.Vars(r)["productId"])
+}
+
+// productInBody is the product named by the productId of the JSON body.
+// The body is left for the handler to read.
+func productInBody(h *Handler, r *http.Request) func(ctx context.Context) ([]string, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	var p struct {
+		ID string `json:"productId"`
+	}
+	if err != nil || json.Unmarshal(body, &p) != nil || p.ID == "" {
+		// The handler rejects the body; a new product is in no category.
+		return nil
+	}
+	return h.productCategories(p.ID)
+}
+
+// categoryInPath is the category named by the {id} of the route.
+func categoryInPath(h *Handler, r *http.Request) func(ctx context.Context) ([]string, error) {
+	id := mux.Vars(r)["id"]
+	return once(func(ctx context.Context) ([]string, error) {
+		return h.categoryPath(ctx, id)
+	})
+}
+
+// productCategories returns the categories of the product with their
+// ancestors.
+func (h *Handler) productCategories(id string) func(ctx context.Context) ([]string, error) {
+	return once(func(ctx context.Context) ([]string, error) {
+		categories, err := h.CategoryService.ProductCategories(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, c := range categories {
+			path, err := h.categoryPath(ctx, c.ID)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, path...)
+		}
+		return ids, nil
+	})
+}
+
+// categoryPath returns the IDs of the category and its ancestors. An
+// unknown category has none; its route reports it.
+func (h *Handler) categoryPath(ctx context.Context, id string) ([]string, error) {
+	crumbs, err := h.CategoryService.Breadcrumbs(ctx, id)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(crumbs))
+	for i, c := range crumbs {
+		ids[i] = c.ID
+	}
+	return ids, nil
+}
+
+// once memoizes f, since several rules may ask for the categories.
+func once(f func(ctx context.Context) ([]string, error)) func(ctx context.Context) ([]string, error) {
+	var o sync.Once
+	var ids []string
+	var err error
+	return func(ctx context.Context) ([]string, error) {
+		o.Do(func() { ids, err = f(ctx) })
+		return ids, err
+	}
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// Batch modes.
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "bestEffort"
+)
+
+// batchRequest is the body of POST /products:batch.
+type batchRequest struct {
+	// Mode is atomic (the default) to apply all operations or none, or
+	// bestEffort to apply every operation that succeeds.
+	Mode       string                    `json:"mode"`
+	Operations []*catalog.BatchOperation `json:"operations"`
+}
+
+// batchItem is the outcome of a single operation, in the order of the request.
+type batchItem struct {
+	Index     int      `json:"index"`
+	Status    int      `json:"status"`
+	ProductID string   `json:"productId,omitempty"`
+	Version   int64    `json:"version,omitempty"`
+	Error     *Problem `json:"error,omitempty"`
+}
+
+// batchResponse is the body returned by POST /products:batch.
+type batchResponse struct {
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []*batchItem `json:"results"`
+}
+
+// BatchProducts applies a list of product upserts and deletes and responds
+// with a status per operation. The response is a 200 whenever the batch
+// was run, even if some or, in atomic mode, all operations failed. Every
+// operation must be allowed on its own; those the policy refuses fail with
+// a 403.
+func (h *Handler) BatchProducts(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during BatchProducts: %v", err))
+		return
+	}
+	var fields []catalog.FieldError
+	if req.Mode != "" && req.Mode != batchAtomic && req.Mode != batchBestEffort {
+		fields = append(fields, catalog.FieldError{Field: "mode", Message: "must be atomic or bestEffort"})
+	}
+	switch {
+	case len(req.Operations) == 0:
+		fields = append(fields, catalog.FieldError{Field: "operations", Message: "must not be empty"})
+	case len(req.Operations) > catalog.MaxBatchOperations:
+		fields = append(fields, catalog.FieldError{Field: "operations",
+			Message: fmt.Sprintf("must hold at most %d operations", catalog.MaxBatchOperations)})
+	}
+	for i, op := range req.Operations {
+		if op == nil {
+			fields = append(fields, catalog.FieldError{Field: fmt.Sprintf("operations[%d]", i), Message: "must be an object"})
+		}
+	}
+	if err := catalog.ValidationError("batch is invalid", fields); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	results, err := h.runBatch(r, req.Operations, req.Mode != batchBestEffort)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	resp := &batchResponse{Results: make([]*batchItem, len(results))}
+	for i, res := range results {
+		item := &batchItem{Index: i, Status: http.StatusOK}
+		switch {
+		case res.Err != nil:
+			item.Status, item.Error = batchProblem(r, res.Err)
+			resp.Failed++
+		case res.Product != nil:
+			item.ProductID, item.Version = res.Product.ID, res.Product.Version
+			if res.Created {
+				item.Status = http.StatusCreated
+			}
+			resp.Succeeded++
+		default:
+			item.ProductID = req.Operations[i].ID
+			resp.Succeeded++
+		}
+		resp.Results[i] = item
+	}
+	respondWithJson(w, r, http.StatusOK, resp)
+}
+
+// runBatch authorizes the operations and applies those allowed. Refused
+// operations fail; an atomic batch with a refused operation applies none.
+func (h *Handler) runBatch(r *http.Request, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+	results := make([]*catalog.BatchResult, len(ops))
+	var allowed []*catalog.BatchOperation
+	var indexes []int
+	for i, op := range ops {
+		d, err := h.authorizeOperation(r, op)
+		if err != nil {
+			return nil, err
+		}
+		if !d.Allowed {
+			log.Debugf("Refused batch operation %d to %v: %s", i, auth.ClaimsFromContext(r.Context()).Subject, d.Reason)
+			results[i] = &catalog.BatchResult{Err: &refusedError{reason: d.Reason}}
+			continue
+		}
+		allowed = append(allowed, op)
+		indexes = append(indexes, i)
+	}
+	if len(allowed) == 0 {
+		return results, nil
+	}
+	if atomic && len(allowed) < len(ops) {
+		for _, i := range indexes {
+			results[i] = &catalog.BatchResult{Err: catalog.ErrBatchAborted}
+		}
+		return results, nil
+	}
+	applied, err := h.ProductService.BatchProducts(r.Context(), allowed, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range indexes {
+		results[i] = applied[j]
+	}
+	return results, nil
+}
+
+// refusedError is the result of a batch operation the policy refuses.
+type refusedError struct {
+	reason string
+}
+
+func (e *refusedError) Error() string {
+	return "Forbidden: " + e.reason
+}
+
+// authorizeOperation decides a batch operation as the action it performs
+// on its own, product:create, product:update or product:delete on the
+// categories of its product, so that a batch cannot do what the caller may
+// not do one product at a time.
+func (h *Handler) authorizeOperation(r *http.Request, op *catalog.BatchOperation) (*Decision, error) {
+	req := &PolicyRequest{Claims: auth.ClaimsFromContext(r.Context()), Action: "product:create"}
+	switch {
+	case op.Op == catalog.BatchDelete:
+		req.Action, req.Categories = "product:delete", h.productCategories(op.ID)
+	case op.Product != nil && op.Product.ID != "":
+		req.Action, req.Categories = "product:update", h.productCategories(op.Product.ID)
+	}
+	return h.policy().Authorize(r.Context(), req)
+}
+
+// batchProblem returns the status and problem for a failed operation.
+// Operations skipped because an atomic batch failed report a 424 and
+// those the policy refuses a 403.
+func batchProblem(r *http.Request, err error) (int, *Problem) {
+	if err == catalog.ErrBatchAborted {
+		return http.StatusFailedDependency, newProblem(r, http.StatusFailedDependency, err.Error())
+	}
+	var refused *refusedError
+	if errors.As(err, &refused) {
+		return http.StatusForbidden, newProblem(r, http.StatusForbidden, err.Error())
+	}
+	code := statusForError(err)
+	if code == http.StatusInternalServerError {
+		log.WithField("httpRequest", r).
+			Errorf("Error handling batch operation: %v", err)
+		return code, newProblem(r, code, "An internal error occurred.")
+	}
+	p := newProblem(r, code, err.Error())
+	p.Errors = fieldErrors(err)
+	return code, p
+}
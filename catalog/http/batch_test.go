@@ -0,0 +1,188 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_BatchProducts(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.BatchProductsFn = func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+		if atomic {
+			t.Fatal("expected a best-effort batch")
+		}
+		if len(ops) != 3 || ops[0].Product.ProductCode != "tee" || ops[2].ID != "12" {
+			t.Fatalf("unexpected operations: %v", ops)
+		}
+		return []*catalog.BatchResult{
+			{Product: &catalog.Product{ID: "100", ProductCode: "tee", Version: 1}, Created: true},
+			{Err: catalog.Errorf(catalog.ErrVersionConflict, "product 11 is at version 4, not 3")},
+			{},
+		}, nil
+	}
+
+	payload := []byte(`{ "mode": "bestEffort", "operations": [
+		{ "op": "upsert", "product": { "productCode": "tee" } },
+		{ "op": "upsert", "product": { "productId": "11", "productCode": "cap", "version": 3 } },
+		{ "op": "delete", "productId": "12" }
+	] }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/products:batch", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 {
+		t.Fatalf("expected 2 succeeded and 1 failed, got %d and %d", resp.Succeeded, resp.Failed)
+	}
+	if resp.Results[0].Status != http.StatusCreated || resp.Results[0].ProductID != "100" {
+		t.Errorf("unexpected first result: %+v", resp.Results[0])
+	}
+	if resp.Results[1].Status != http.StatusConflict || resp.Results[1].Error == nil {
+		t.Errorf("unexpected second result: %+v", resp.Results[1])
+	}
+	if resp.Results[2].Status != http.StatusOK || resp.Results[2].ProductID != "12" {
+		t.Errorf("unexpected third result: %+v", resp.Results[2])
+	}
+}
+
+func TestHandler_BatchProductsAborted(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.BatchProductsFn = func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+		if !atomic {
+			t.Fatal("expected an atomic batch by default")
+		}
+		return []*catalog.BatchResult{
+			{Err: catalog.ErrBatchAborted},
+			{Err: catalog.Errorf(catalog.ErrNotFound, "product 12 not found")},
+		}, nil
+	}
+
+	payload := []byte(`{ "operations": [
+		{ "op": "upsert", "product": { "productCode": "tee" } },
+		{ "op": "delete", "productId": "12" }
+	] }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/products:batch", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	var resp batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusNotFound {
+		t.Errorf("unexpected results: %+v, %+v", resp.Results[0], resp.Results[1])
+	}
+}
+
+func TestHandler_BatchProductsEmpty(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/products:batch", bytes.NewBufferString(`{ "mode": "all", "operations": [] }`))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 status code, got %d", w.Code)
+	}
+	if ps.BatchProductsInvoked {
+		t.Fatal("expected BatchProducts() not to be invoked")
+	}
+}
+
+func TestHandler_BatchProductsRefused(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.CategoryService = &cs
+	h.Policy, _ = ParsePolicy([]byte(testPolicy))
+	defer func() { h.Policy = nil }()
+
+	ps.BatchProductsFn = func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+		if len(ops) != 1 || ops[0].Product.ID != "100" {
+			t.Fatalf("expected only the allowed operation, got %v", ops)
+		}
+		return []*catalog.BatchResult{{Product: &catalog.Product{ID: "100", Version: 2}}}, nil
+	}
+	cs.ProductCategoriesFn = func(ctx context.Context, productID string) ([]*catalog.Category, error) {
+		if productID == "100" {
+			return []*catalog.Category{{ID: "7"}}, nil
+		}
+		return nil, nil
+	}
+	cs.BreadcrumbsFn = func(ctx context.Context, id string) ([]*catalog.Category, error) {
+		return []*catalog.Category{{ID: id}}, nil
+	}
+	editor := signToken(jwt.MapClaims{"sub": "editor", "https://example.com/roles": []string{"apparel-editor", "contractor"}})
+
+	tests := []struct {
+		mode     string
+		statuses []int
+	}{
+		// An atomic batch applies nothing when an operation is refused.
+		{"atomic", []int{http.StatusFailedDependency, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+		{"bestEffort", []int{http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	}
+	for _, tt := range tests {
+		ps.BatchProductsInvoked = false
+		payload := []byte(`{ "mode": "` + tt.mode + `", "operations": [
+			{ "op": "upsert", "product": { "productId": "100", "productCode": "tee" } },
+			{ "op": "upsert", "product": { "productId": "200", "productCode": "mug" } },
+			{ "op": "delete", "productId": "100" },
+			{ "op": "upsert", "product": { "productCode": "hat" } }
+		] }`)
+
+		// Invoke the handler.
+		w := httptest.NewRecorder()
+		r := newRequest("POST", "/products:batch", bytes.NewBuffer(payload))
+		r.Header.Set("Authorization", "Bearer "+editor)
+		h.Router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200 status code, got %d: %s", tt.mode, w.Code, w.Body.String())
+		}
+		var resp batchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for i, status := range tt.statuses {
+			if resp.Results[i].Status != status {
+				t.Errorf("%s: expected status %d for operation %d, got %+v", tt.mode, status, i, resp.Results[i])
+			}
+		}
+		if p := resp.Results[2].Error; p == nil || !strings.Contains(p.Detail, "contractors never delete") {
+			t.Errorf("%s: expected the reason of the refusal, got %+v", tt.mode, p)
+		}
+		if ps.BatchProductsInvoked != (tt.mode == "bestEffort") {
+			t.Errorf("%s: unexpected BatchProducts() invocation: %v", tt.mode, ps.BatchProductsInvoked)
+		}
+	}
+}
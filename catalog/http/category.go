@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// GetCategories retrieves the root categories.
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryService.Children(r.Context(), "")
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, categories)
+}
+
+// GetCategory retrieves a single category.
+func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	c, err := h.CategoryService.Category(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, c)
+}
+
+// GetCategoryChildren retrieves the ordered children of a category.
+func (h *Handler) GetCategoryChildren(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	_, err := h.CategoryService.Category(r.Context(), id)
+	var children []*catalog.Category
+	if err == nil {
+		children, err = h.CategoryService.Children(r.Context(), id)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, children)
+}
+
+// GetBreadcrumbs retrieves the path from the root down to a category.
+func (h *Handler) GetBreadcrumbs(w http.ResponseWriter, r *http.Request) {
+	crumbs, err := h.CategoryService.Breadcrumbs(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, crumbs)
+}
+
+// AddCategory adds a category to the tree.
+func (h *Handler) AddCategory(w http.ResponseWriter, r *http.Request) {
+	c := &catalog.Category{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddCategory: %v", err))
+		return
+	}
+	if err := h.CategoryService.CreateCategory(r.Context(), c); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, c)
+}
+
+// UpdateCategory replaces a category. A new parentId moves the category
+// together with its subtree.
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	c := &catalog.Category{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdateCategory: %v", err))
+		return
+	}
+	c.ID = mux.Vars(r)["id"]
+	if err := h.CategoryService.UpdateCategory(r.Context(), c); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, c)
+}
+
+// DeleteCategory deletes a category without children.
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	if err := h.CategoryService.DeleteCategory(r.Context(), mux.Vars(r)["id"]); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// GetCategoryProducts retrieves a page of the products in a category.
+// With "descendants=true" products in subcategories are included. The
+// other /products filters, sort order and paging apply as well.
+func (h *Handler) GetCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	query, err := productQueryFromRequest(r)
+	if err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	if !restrictProductQuery(w, r, &query) {
+		return
+	}
+	query.CategoryID = mux.Vars(r)["id"]
+	_, err = h.CategoryService.Category(r.Context(), query.CategoryID)
+	var products *catalog.ProductPage
+	if err == nil {
+		products, err = h.ProductService.Products(r.Context(), query)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, products)
+}
+
+// AssignProduct adds a product of the tenant to a category.
+func (h *Handler) AssignProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := h.productVar(r, "productId")
+	if err == nil {
+		err = h.CategoryService.AssignProduct(r.Context(), mux.Vars(r)["id"], product.ID)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// UnassignProduct removes a product of the tenant from a category.
+func (h *Handler) UnassignProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := h.productVar(r, "productId")
+	if err == nil {
+		err = h.CategoryService.UnassignProduct(r.Context(), mux.Vars(r)["id"], product.ID)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// GetProductCategories retrieves the categories a product is assigned to.
+func (h *Handler) GetProductCategories(w http.ResponseWriter, r *http.Request) {
+	product, err := h.routeProduct(r)
+	var categories []*catalog.Category
+	if err == nil {
+		categories, err = h.CategoryService.ProductCategories(r.Context(), product.ID)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, categories)
+}
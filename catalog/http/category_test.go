@@ -0,0 +1,118 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetCategoryProductsWithDescendants(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.CategoryService = &cs
+
+	cs.CategoryFn = func(ctx context.Context, id string) (*catalog.Category, error) {
+		return &catalog.Category{ID: id, Name: "Apparel", Slug: "apparel"}, nil
+	}
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if q.CategoryID != "7" || !q.IncludeDescendants || q.Limit != 10 {
+			t.Fatalf("unexpected query: %+v", q)
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{{ID: "1", ProductCode: "tee"}}}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/category/7/products?descendants=true&limit=10", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+	if !ps.ProductsInvoked {
+		t.Fatal("expected Products() to be invoked.")
+	}
+}
+
+func TestHandler_GetCategoryProductsUnknownCategory(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.CategoryService = &cs
+
+	cs.CategoryFn = func(ctx context.Context, id string) (*catalog.Category, error) {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "category %v not found", id)
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/category/7/products", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+	if ps.ProductsInvoked {
+		t.Fatal("expected Products() not to be invoked.")
+	}
+}
+
+func TestHandler_UpdateCategoryCycle(t *testing.T) {
+	// Inject our mock into our handler.
+	var cs mock.CategoryService
+	h.CategoryService = &cs
+
+	cs.UpdateCategoryFn = func(ctx context.Context, c *catalog.Category) error {
+		if c.ID != "2" || c.ParentID != "9" {
+			t.Fatalf("unexpected category: %+v", c)
+		}
+		return catalog.ValidationError("category is invalid", []catalog.FieldError{
+			{Field: "parentId", Message: "must not be a descendant of the category"}})
+	}
+
+	payload := []byte(`{ "parentId": "9", "name": "Apparel", "slug": "apparel" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/category/2", bytes.NewBuffer(payload))
+	r.Header.Set("Authorization", "Bearer "+operatorToken)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 status code, got %d", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"parentId"`)) {
+		t.Fatalf("expected parentId field error: %s", w.Body.String())
+	}
+}
+
+func TestHandler_AssignProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var cs mock.CategoryService
+	h.CategoryService = &cs
+	h.ProductService = routeProductService("100")
+
+	cs.AssignProductFn = func(ctx context.Context, categoryID, productID string) error {
+		if categoryID != "7" || productID != "100" {
+			t.Fatalf("unexpected assignment: %v %v", categoryID, productID)
+		}
+		return nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/category/7/products/100", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Change feed settings. They are variables so that tests can shorten them.
+var (
+	// changesPollInterval is how often the feed checks for new events.
+	changesPollInterval = time.Second
+	// changesHeartbeat is how long the feed may stay silent before a
+	// comment is sent to keep proxies from closing the stream.
+	changesHeartbeat = 15 * time.Second
+)
+
+// changesBatchSize is the number of events read at once.
+const changesBatchSize = 100
+
+// GetProductChanges streams the product events as text/event-stream. Each
+// message carries the event number as its id, the event type as its event
+// name and the event as JSON data. A client resuming with Last-Event-ID
+// receives every event after that number first; without it the stream
+// starts with the next change. Events are only streamed once they have
+// settled, a few seconds after they happened, so that none committed out
+// of order is skipped. Like the product routes, the feed only shows the
+// changes of products that are not published to admins.
+func (h *Handler) GetProductChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	admin := requestHasScope(r, adminScope)
+	var after int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			respondWithBadRequest(w, r, catalog.ValidationError("invalid Last-Event-ID",
+				[]catalog.FieldError{{Field: "Last-Event-ID", Message: "must be an event id"}}))
+			return
+		}
+		after = id
+	} else {
+		id, err := h.EventService.LastEventID(ctx)
+		if err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+		after = id
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, r, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(changesPollInterval)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+	for {
+		events, err := h.EventService.EventsAfter(ctx, after, changesBatchSize)
+		if err != nil {
+			// The status is sent already; the client reconnects with the
+			// id of the last event it received.
+			if ctx.Err() == nil {
+				log.Errorf("Error streaming the product changes: %v", err)
+			}
+			return
+		}
+		for _, e := range events {
+			if !admin {
+				shown, err := h.publishedChange(ctx, e)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Errorf("Error streaming the product changes: %v", err)
+					}
+					return
+				}
+				if !shown {
+					after = e.ID
+					continue
+				}
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			after = e.ID
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+			lastWrite = time.Now()
+		} else if time.Since(lastWrite) >= changesHeartbeat {
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			lastWrite = time.Now()
+		}
+		// Drain a backlog without waiting.
+		if len(events) == changesBatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishedChange reports whether the event left its product published.
+// Deletions carry no product; they are told when the product was published
+// when it was deleted, as its history recorded it, so that the deletion of
+// a draft does not give it away.
+func (h *Handler) publishedChange(ctx context.Context, e *catalog.ProductEvent) (bool, error) {
+	if e.Product != nil {
+		return e.Product.Published(e.OccurredAt), nil
+	}
+	rev, err := h.ProductService.ProductRevision(ctx, e.ProductID, e.Version)
+	if errors.Is(err, catalog.ErrNotFound) {
+		// The product was purged along with its history.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return rev.Before != nil && rev.Before.Published(e.OccurredAt), nil
+}
+
+// writeEvent writes the event as a server-sent event message.
+func writeEvent(w http.ResponseWriter, e *catalog.ProductEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
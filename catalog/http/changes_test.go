@@ -0,0 +1,127 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+// newStreamRequest creates a change feed request that is canceled with
+// the returned function.
+func newStreamRequest() (*http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _ := http.NewRequest("GET", "/products/changes", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r.WithContext(ctx), cancel
+}
+
+func TestHandler_GetProductChanges(t *testing.T) {
+	// Inject our mocks into our handler.
+	var es mock.EventService
+	var ps mock.ProductService
+	h.EventService = &es
+	h.ProductService = &ps
+	defer func(d time.Duration) { changesPollInterval = d }(changesPollInterval)
+	changesPollInterval = time.Millisecond
+
+	r, cancel := newStreamRequest()
+	r.Header.Set("Last-Event-ID", "10")
+	var calls int
+	es.EventsAfterFn = func(ctx context.Context, after int64, limit int) ([]*catalog.ProductEvent, error) {
+		calls++
+		switch calls {
+		case 1:
+			if after != 10 {
+				t.Errorf("expected the events after 10, got %d", after)
+			}
+			return []*catalog.ProductEvent{
+				{ID: 11, Type: catalog.EventProductUpdated, ProductID: "5", Version: 2,
+					Product: &catalog.Product{ID: "5", Status: catalog.StatusActive}},
+				// Readers do not see the changes of drafts.
+				{ID: 12, Type: catalog.EventProductCreated, ProductID: "6", Version: 1,
+					Product: &catalog.Product{ID: "6", Status: catalog.StatusDraft}},
+				{ID: 13, Type: catalog.EventProductDeleted, ProductID: "5", Version: 3},
+				// Nor the deletions of drafts.
+				{ID: 14, Type: catalog.EventProductDeleted, ProductID: "6", Version: 2},
+			}, nil
+		case 2:
+			if after != 14 {
+				t.Errorf("expected the events after 14, got %d", after)
+			}
+			return nil, nil
+		}
+		// The client goes away.
+		cancel()
+		return nil, ctx.Err()
+	}
+	ps.ProductRevisionFn = func(ctx context.Context, id string, version int64) (*catalog.ProductRevision, error) {
+		status := catalog.StatusActive
+		if id == "6" {
+			status = catalog.StatusDraft
+		}
+		return &catalog.ProductRevision{ProductID: id, Version: version, Action: catalog.HistoryDelete,
+			Before: &catalog.Product{ID: id, Status: status}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, r)
+
+	if es.LastEventIDInvoked {
+		t.Error("expected LastEventID() not to be invoked when resuming")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "id: 11\nevent: product.updated\ndata: {\"id\":11,") ||
+		!strings.Contains(body, "\n\nid: 13\nevent: product.deleted\ndata: ") || strings.Contains(body, "id: 12\n") || strings.Contains(body, "id: 14\n") {
+		t.Errorf("unexpected stream: %q", body)
+	}
+}
+
+func TestHandler_GetProductChangesFromNow(t *testing.T) {
+	// Inject our mock into our handler.
+	var es mock.EventService
+	h.EventService = &es
+
+	r, cancel := newStreamRequest()
+	es.LastEventIDFn = func(ctx context.Context) (int64, error) {
+		return 42, nil
+	}
+	es.EventsAfterFn = func(ctx context.Context, after int64, limit int) ([]*catalog.ProductEvent, error) {
+		if after != 42 {
+			t.Errorf("expected the events after 42, got %d", after)
+		}
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !es.EventsAfterInvoked {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_GetProductChangesInvalidLastEventID(t *testing.T) {
+	// Inject our mock into our handler.
+	var es mock.EventService
+	h.EventService = &es
+
+	r, cancel := newStreamRequest()
+	defer cancel()
+	r.Header.Set("Last-Event-ID", "abc")
+
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || es.EventsAfterInvoked {
+		t.Fatalf("expected 400 for an invalid Last-Event-ID, got %d", w.Code)
+	}
+}
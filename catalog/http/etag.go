@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// productETag returns the entity tag of a product, derived from its version.
+func productETag(p *catalog.Product) string {
+	return `"` + strconv.FormatInt(p.Version, 10) + `"`
+}
+
+// etagListMatches reports whether a comma separated If-Match or
+// If-None-Match header lists tag or "*". Weak tags (W/"...") only match
+// when weak is set, as If-Match requires the strong comparison.
+func etagListMatches(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPreconditions reports whether the request carries If-Match or If-None-Match.
+func hasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != ""
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match against the
+// current product. It returns 0 when the request may proceed, 304 for a
+// GET whose If-None-Match matches, and 412 otherwise.
+func checkPreconditions(r *http.Request, current *catalog.Product) int {
+	tag := productETag(current)
+	if im := r.Header.Get("If-Match"); im != "" && !etagListMatches(im, tag, false) {
+		return http.StatusPreconditionFailed
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagListMatches(inm, tag, true) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			return http.StatusNotModified
+		}
+		return http.StatusPreconditionFailed
+	}
+	return 0
+}
+
+// respondWithPrecondition writes the response for a failed precondition.
+func respondWithPrecondition(w http.ResponseWriter, r *http.Request, code int, current *catalog.Product) {
+	w.Header().Set("ETag", productETag(current))
+	if code == http.StatusNotModified {
+		w.WriteHeader(code)
+		return
+	}
+	respondWithError(w, r, code, "The product does not match the If-Match or If-None-Match condition.")
+}
+
+// preconditionVersion loads the product named in a conditional write and
+// evaluates the preconditions. It returns the version the write must be
+// based on, 0 when the request carries no preconditions, or writes the
+// error response and returns ok false.
+func (h *Handler) preconditionVersion(w http.ResponseWriter, r *http.Request, id string) (version int64, ok bool) {
+	if !hasPreconditions(r) {
+		return 0, true
+	}
+	current, err := h.ProductService.Product(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return 0, false
+	}
+	if code := checkPreconditions(r, current); code != 0 {
+		respondWithPrecondition(w, r, code, current)
+		return 0, false
+	}
+	return current.Version, true
+}
+
+// respondWithWriteError reports a service error from a conditional write.
+// A version conflict under If-Match or If-None-Match means the product
+// changed after the preconditions were checked, so it is a 412.
+func respondWithWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if hasPreconditions(r) && errors.Is(err, catalog.ErrVersionConflict) {
+		respondWithError(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	respondWithServiceError(w, r, err)
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetProductETag(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3, Status: catalog.StatusActive}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected ETag \"3\", got %q", etag)
+	}
+}
+
+func TestHandler_GetProductNotModified(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3, Status: catalog.StatusActive}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100", nil)
+	r.Header.Set("If-None-Match", `"2", W/"3"`)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 status code, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("expected no body, got %s", w.Body.String())
+	}
+}
+
+func TestHandler_UpdateProductIfMatch(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3, Status: catalog.StatusActive}, nil
+	}
+	ps.UpdateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		if product.Version != 3 {
+			t.Fatalf("expected update based on version 3, got %d", product.Version)
+		}
+		product.Version++
+		return nil
+	}
+
+	payload := []byte(`{ "productId": "100", "productCode": "tee" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/product", bytes.NewBuffer(payload))
+	r.Header.Set("If-Match", `"3"`)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 status code, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("expected ETag \"4\", got %q", etag)
+	}
+}
+
+func TestHandler_UpdateProductIfMatchFailed(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 4}, nil
+	}
+
+	payload := []byte(`{ "productId": "100", "productCode": "tee" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/product", bytes.NewBuffer(payload))
+	r.Header.Set("If-Match", `"3"`)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 status code, got %d", w.Code)
+	}
+	if ps.UpdateProductInvoked {
+		t.Fatal("expected UpdateProduct() not to be invoked.")
+	}
+}
+
+func TestHandler_UpdateProductStaleBodyVersion(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.UpdateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return catalog.Errorf(catalog.ErrVersionConflict, "product %v is at version 4, not %d", product.ID, product.Version)
+	}
+
+	payload := []byte(`{ "productId": "100", "productCode": "tee", "version": 3 }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/product", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_DeleteProductIfMatchRace(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3, Status: catalog.StatusActive}, nil
+	}
+	ps.DeleteProductFn = func(ctx context.Context, id string, version int64) error {
+		// Another request updated the product after the If-Match check.
+		return catalog.Errorf(catalog.ErrVersionConflict, "product %v is at version 4, not %d", id, version)
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("DELETE", "/product/100", nil)
+	r.Header.Set("If-Match", `"3"`)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 status code, got %d", w.Code)
+	}
+}
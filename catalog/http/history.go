@@ -0,0 +1,115 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// historyDiff is the body returned by GET /product/{id}/history/diff.
+type historyDiff struct {
+	ProductID string                `json:"productId"`
+	From      int64                 `json:"from,omitempty"`
+	To        int64                 `json:"to"`
+	Changes   []catalog.FieldChange `json:"changes"`
+}
+
+// GetProductHistory retrieves a page of the recorded changes of a
+// product, newest first.
+func (h *Handler) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	limit, err := intParam(v.Get("limit"), 1, catalog.MaxPageSize)
+	if err != nil {
+		respondWithBadRequest(w, r, catalog.ValidationError("invalid query parameters",
+			[]catalog.FieldError{{Field: "limit", Message: err.Error()}}))
+		return
+	}
+	page, err := h.ProductService.ProductHistory(r.Context(), mux.Vars(r)["id"],
+		catalog.Page{Cursor: v.Get("cursor"), Limit: limit})
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, page)
+}
+
+// GetProductHistoryDiff lists the fields that differ between the product
+// versions named by the "from" and "to" query parameters. Without "from"
+// it lists the fields changed by the "to" revision.
+func (h *Handler) GetProductHistoryDiff(w http.ResponseWriter, r *http.Request) {
+	productId := mux.Vars(r)["id"]
+	v := r.URL.Query()
+	var fields []catalog.FieldError
+	from, err := versionParam(v.Get("from"))
+	if err != nil {
+		fields = append(fields, catalog.FieldError{Field: "from", Message: err.Error()})
+	}
+	to, err := versionParam(v.Get("to"))
+	if err != nil {
+		fields = append(fields, catalog.FieldError{Field: "to", Message: err.Error()})
+	} else if to == 0 {
+		fields = append(fields, catalog.FieldError{Field: "to", Message: "is required"})
+	}
+	if err := catalog.ValidationError("invalid query parameters", fields); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	target, err := h.ProductService.ProductRevision(r.Context(), productId, to)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	before := target.Before
+	if from != 0 {
+		source, err := h.ProductService.ProductRevision(r.Context(), productId, from)
+		if err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+		before = source.After
+	}
+	respondWithJson(w, r, http.StatusOK, &historyDiff{
+		ProductID: productId,
+		From:      from,
+		To:        to,
+		Changes:   catalog.DiffProducts(before, target.After),
+	})
+}
+
+// versionParam parses an optional product version query parameter.
+func versionParam(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("must be a product version")
+	}
+	return i, nil
+}
+
+// getProductAsOf responds with a product as it was at the time given by
+// the "asOf" query parameter. Products that were not published then are
+// only shown to admins.
+func (h *Handler) getProductAsOf(w http.ResponseWriter, r *http.Request, productId, asOf string) {
+	at, err := time.Parse(time.RFC3339, asOf)
+	if err != nil {
+		respondWithBadRequest(w, r, catalog.ValidationError("invalid query parameters",
+			[]catalog.FieldError{{Field: "asOf", Message: "must be an RFC 3339 timestamp"}}))
+		return
+	}
+	product, err := h.ProductService.ProductAsOf(r.Context(), productId, at)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	if !product.Published(at) && !requestHasScope(r, adminScope) {
+		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("product %v not found", productId))
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, product)
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetProductHistory(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductHistoryFn = func(ctx context.Context, id string, page catalog.Page) (*catalog.RevisionPage, error) {
+		if id != "100" || page.Cursor != "abc" || page.Limit != 10 {
+			t.Fatalf("unexpected arguments: %v, %+v", id, page)
+		}
+		return &catalog.RevisionPage{Revisions: []*catalog.ProductRevision{
+			{ProductID: "100", Version: 2, Action: catalog.HistoryUpdate, Actor: "admin"},
+		}}, nil
+	}
+
+	// Without the admin scope the history is not shown.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100/history?cursor=abc&limit=10", nil)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden || ps.ProductHistoryInvoked {
+		t.Fatalf("expected 403 without the admin scope, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ps.ProductHistoryInvoked {
+		t.Fatal("expected ProductHistory() to be invoked.")
+	}
+	var page catalog.RevisionPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(page.Revisions) != 1 || page.Revisions[0].Actor != "admin" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_GetProductHistoryDiff(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	revisions := map[int64]*catalog.ProductRevision{
+		1: {Version: 1, After: &catalog.Product{ProductCode: "tee", ShortDesc: "Tee"}},
+		3: {Version: 3, Before: &catalog.Product{ProductCode: "tee", ShortDesc: "Tee"},
+			After: &catalog.Product{ProductCode: "tee", ShortDesc: "T-shirt",
+				Attributes: map[string]interface{}{"material": "cotton"}}},
+	}
+	ps.ProductRevisionFn = func(ctx context.Context, id string, version int64) (*catalog.ProductRevision, error) {
+		r, ok := revisions[version]
+		if !ok {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "revision not found")
+		}
+		return r, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100/history/diff?from=1&to=3", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	var diff historyDiff
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(diff.Changes) != 2 {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if c := diff.Changes[0]; c.Field != "shortDesc" || c.Before != "Tee" || c.After != "T-shirt" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if c := diff.Changes[1]; c.Field != "attributes.material" || c.Before != nil || c.After != "cotton" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+
+	// The version to compare is required.
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/product/100/history/diff?from=1", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 status code, got %d", w.Code)
+	}
+
+	// Unknown revisions are not found.
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/product/100/history/diff?to=2", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetProductAsOf(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	at := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	ps.ProductAsOfFn = func(ctx context.Context, id string, asOf time.Time) (*catalog.Product, error) {
+		if id != "100" || !asOf.Equal(at) {
+			t.Fatalf("unexpected arguments: %v, %v", id, asOf)
+		}
+		return &catalog.Product{ID: "100", ProductCode: "tee", Version: 2, Status: catalog.StatusDraft}, nil
+	}
+
+	// A product that was not published then is only shown to admins.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100?asOf=2020-03-01T12:00:00Z", nil)
+	h.Router.ServeHTTP(w, r)
+	if !ps.ProductAsOfInvoked || ps.ProductInvoked {
+		t.Fatal("expected ProductAsOf() to be invoked instead of Product().")
+	}
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	var product catalog.Product
+	if err := json.Unmarshal(w.Body.Bytes(), &product); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || product.Version != 2 {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/product/100?asOf=yesterday", nil)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_RestoreProductRecordsActor(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	var actor string
+	ps.RestoreProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		actor = catalog.ActorFromContext(ctx)
+		return &catalog.Product{ID: id, Version: 4}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100:restore", nil)
+	h.Router.ServeHTTP(w, r)
+
+	// The subject of the token is the actor.
+	if w.Code != http.StatusOK || actor != "test" {
+		t.Fatalf("expected the change to be made by test, got %d %q", w.Code, actor)
+	}
+}
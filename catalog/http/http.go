@@ -4,20 +4,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/codegangsta/negroni"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Handler struct {
-	ProductService catalog.ProductService
-	Handler        *Handler
-	Router         *mux.Router
+	ProductService   catalog.ProductService
+	PriceService     catalog.PriceService
+	VariantService   catalog.VariantService
+	CategoryService  catalog.CategoryService
+	AttributeService catalog.AttributeService
+	WebhookService   catalog.WebhookService
+	EventService     catalog.EventService
+	APIKeyService    catalog.APIKeyService
+	TenantService    catalog.TenantService
+	Verifier         TokenVerifier
+	// Policy authorizes the requests; DefaultPolicy when nil.
+	Policy           *Policy
+	// TenantClaim is the token claim naming the tenant of the caller;
+	// DefaultTenantClaim when empty.
+	TenantClaim      string
+	// RateLimiter limits the requests of every client; none when nil.
+	RateLimiter      *RateLimiter
+	Handler          *Handler
+	Router           *mux.Router
+}
+
+// TokenVerifier verifies bearer tokens, returning their claims.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
 }
 
 // NewHandler creates a new Handler.
@@ -35,35 +60,244 @@ func (h *Handler) registerHandlers() *mux.Router {
 	//  All API calls leverage application/json
 	s := r.Headers("Accept", "application/json").Subrouter()
 
+	// The change feed streams text/event-stream rather than JSON.
+	r.Path("/products/changes").Methods("GET").Headers("Accept", "text/event-stream").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:read", nil)),
+		negroni.WrapFunc(h.GetProductChanges)))
+
 	//read := s.Methods("GET").
 	//	Handler(negroni.New(
-	//		negroni.HandlerFunc(readMiddleware),
+	//		negroni.HandlerFunc(h.readMiddleware),
 	//		negroni.Wrap(s)))
-	//write := s.Methods("POST", "PUT", "DELETE").Handler(negroni.New(negroni.HandlerFunc(writeMiddleware)))
+	//write := s.Methods("POST", "PUT", "DELETE").Handler(negroni.New(negroni.HandlerFunc(h.writeMiddleware)))
 
-	s.Path("/product/{id:[0-9]+}").Handler(negroni.New(
-		negroni.HandlerFunc(readMiddleware),
+	s.Path("/product/{id:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:read", productInPath)),
 		negroni.WrapFunc(h.GetProduct)))
 
-	s.Path("/products").Handler(negroni.New(
-		negroni.HandlerFunc(readMiddleware),
+	s.Path("/product/code/{code}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:read", nil)),
+		negroni.WrapFunc(h.GetProductByCode)))
+
+	s.Path("/products").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:read", nil)),
 		negroni.WrapFunc(h.GetProducts)))
 
-	s.Path("/product").Handler(negroni.New(
-		negroni.HandlerFunc(writeMiddleware),
+	s.Path("/products:batch").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:batch", nil)),
+		negroni.WrapFunc(h.BatchProducts)))
+
+	s.Path("/product").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:create", nil)),
 		negroni.WrapFunc(h.AddProduct)))
 
-	s.Path("/product").Handler(negroni.New(
-		negroni.HandlerFunc(writeMiddleware),
+	s.Path("/product").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:update", productInBody)),
 		negroni.WrapFunc(h.UpdateProduct)))
 
-	s.Path("/product/{id:[0-9]+}").Handler(negroni.New(
-		negroni.HandlerFunc(writeMiddleware),
+	s.Path("/product/{id:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:delete", productInPath)),
 		negroni.WrapFunc(h.DeleteProduct)))
 
+	s.Path("/product/{id:[0-9]+}:restore").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:restore", productInPath)),
+		negroni.WrapFunc(h.RestoreProduct)))
+
+	s.Path("/product/{id:[0-9]+}:purge").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:purge", productInPath)),
+		negroni.WrapFunc(h.PurgeProduct)))
+
+	s.Path("/product/{id:[0-9]+}").Methods("PATCH").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:update", productInPath)),
+		negroni.WrapFunc(h.PatchProduct)))
+
+	s.Path("/product/{id:[0-9]+}/history").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:history", productInPath)),
+		negroni.WrapFunc(h.GetProductHistory)))
+
+	s.Path("/product/{id:[0-9]+}/history/diff").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("product:history", productInPath)),
+		negroni.WrapFunc(h.GetProductHistoryDiff)))
+
+	s.Path("/product/{id:[0-9]+}/prices").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("price:read", productInPath)),
+		negroni.WrapFunc(h.GetPrices)))
+
+	s.Path("/product/{id:[0-9]+}/prices").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("price:create", productInPath)),
+		negroni.WrapFunc(h.AddPrice)))
+
+	s.Path("/product/{id:[0-9]+}/prices/{priceId:[0-9]+}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("price:update", productInPath)),
+		negroni.WrapFunc(h.UpdatePrice)))
+
+	s.Path("/product/{id:[0-9]+}/prices/{priceId:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("price:delete", productInPath)),
+		negroni.WrapFunc(h.DeletePrice)))
+
+	s.Path("/product/{id:[0-9]+}/variants").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("variant:read", productInPath)),
+		negroni.WrapFunc(h.GetVariants)))
+
+	s.Path("/product/{id:[0-9]+}/variants").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("variant:create", productInPath)),
+		negroni.WrapFunc(h.AddVariant)))
+
+	s.Path("/product/{id:[0-9]+}/variants/{variantId:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("variant:read", productInPath)),
+		negroni.WrapFunc(h.GetVariant)))
+
+	s.Path("/product/{id:[0-9]+}/variants/{variantId:[0-9]+}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("variant:update", productInPath)),
+		negroni.WrapFunc(h.UpdateVariant)))
+
+	s.Path("/product/{id:[0-9]+}/variants/{variantId:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("variant:delete", productInPath)),
+		negroni.WrapFunc(h.DeleteVariant)))
+
+	s.Path("/pricelists").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("pricelist:read", nil)),
+		negroni.WrapFunc(h.GetPriceLists)))
+
+	s.Path("/pricelists").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("pricelist:create", nil)),
+		negroni.WrapFunc(h.AddPriceList)))
+
+	s.Path("/pricelists/{id:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("pricelist:read", nil)),
+		negroni.WrapFunc(h.GetPriceList)))
+
+	s.Path("/categories").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", nil)),
+		negroni.WrapFunc(h.GetCategories)))
+
+	s.Path("/categories").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:create", nil)),
+		negroni.WrapFunc(h.AddCategory)))
+
+	s.Path("/category/{id:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", categoryInPath)),
+		negroni.WrapFunc(h.GetCategory)))
+
+	s.Path("/category/{id:[0-9]+}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:update", categoryInPath)),
+		negroni.WrapFunc(h.UpdateCategory)))
+
+	s.Path("/category/{id:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:delete", categoryInPath)),
+		negroni.WrapFunc(h.DeleteCategory)))
+
+	s.Path("/category/{id:[0-9]+}/children").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", categoryInPath)),
+		negroni.WrapFunc(h.GetCategoryChildren)))
+
+	s.Path("/category/{id:[0-9]+}/breadcrumbs").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", categoryInPath)),
+		negroni.WrapFunc(h.GetBreadcrumbs)))
+
+	s.Path("/category/{id:[0-9]+}/products").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", categoryInPath)),
+		negroni.WrapFunc(h.GetCategoryProducts)))
+
+	s.Path("/category/{id:[0-9]+}/products/{productId:[0-9]+}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:assign", categoryInPath, productIDInPath)),
+		negroni.WrapFunc(h.AssignProduct)))
+
+	s.Path("/category/{id:[0-9]+}/products/{productId:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:unassign", categoryInPath)),
+		negroni.WrapFunc(h.UnassignProduct)))
+
+	s.Path("/product/{id:[0-9]+}/categories").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("category:read", productInPath)),
+		negroni.WrapFunc(h.GetProductCategories)))
+
+	s.Path("/attributes").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("attribute:read", nil)),
+		negroni.WrapFunc(h.GetAttributeDefinitions)))
+
+	s.Path("/attributes").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("attribute:create", nil)),
+		negroni.WrapFunc(h.AddAttributeDefinition)))
+
+	s.Path("/attribute/{name}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("attribute:read", nil)),
+		negroni.WrapFunc(h.GetAttributeDefinition)))
+
+	s.Path("/attribute/{name}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("attribute:update", nil)),
+		negroni.WrapFunc(h.UpdateAttributeDefinition)))
+
+	s.Path("/attribute/{name}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("attribute:delete", nil)),
+		negroni.WrapFunc(h.DeleteAttributeDefinition)))
+
+	s.Path("/webhooks").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:read", nil)),
+		negroni.WrapFunc(h.GetWebhooks)))
+
+	s.Path("/webhooks").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:create", nil)),
+		negroni.WrapFunc(h.AddWebhook)))
+
+	s.Path("/webhooks/{id:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:read", nil)),
+		negroni.WrapFunc(h.GetWebhook)))
+
+	s.Path("/webhooks/{id:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:delete", nil)),
+		negroni.WrapFunc(h.DeleteWebhook)))
+
+	s.Path("/webhooks/{id:[0-9]+}/deliveries").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:read", nil)),
+		negroni.WrapFunc(h.GetWebhookDeliveries)))
+
+	s.Path("/webhooks/{id:[0-9]+}/deliveries/{deliveryId:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:read", nil)),
+		negroni.WrapFunc(h.GetWebhookDelivery)))
+
+	s.Path("/webhooks/{id:[0-9]+}/deliveries/{deliveryId:[0-9]+}:replay").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("webhook:replay", nil)),
+		negroni.WrapFunc(h.ReplayWebhookDelivery)))
+
+	s.Path("/apikeys").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("apikey:read", nil)),
+		negroni.WrapFunc(h.GetAPIKeys)))
+
+	s.Path("/apikeys").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("apikey:create", nil)),
+		negroni.WrapFunc(h.AddAPIKey)))
+
+	s.Path("/apikeys/{id:[0-9]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("apikey:read", nil)),
+		negroni.WrapFunc(h.GetAPIKey)))
+
+	s.Path("/apikeys/{id:[0-9]+}").Methods("DELETE").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("apikey:revoke", nil)),
+		negroni.WrapFunc(h.RevokeAPIKey)))
+
+	s.Path("/apikeys/{id:[0-9]+}:rotate").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("apikey:rotate", nil)),
+		negroni.WrapFunc(h.RotateAPIKey)))
+
+	s.Path("/tenants").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("tenant:read", nil)),
+		negroni.WrapFunc(h.GetTenants)))
+
+	s.Path("/tenants").Methods("POST").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("tenant:create", nil)),
+		negroni.WrapFunc(h.AddTenant)))
+
+	s.Path("/tenants/{id:[a-z0-9-]+}").Methods("GET").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("tenant:read", nil)),
+		negroni.WrapFunc(h.GetTenant)))
+
+	s.Path("/tenants/{id:[a-z0-9-]+}").Methods("PUT").Handler(negroni.New(
+		negroni.HandlerFunc(h.authorize("tenant:update", nil)),
+		negroni.WrapFunc(h.UpdateTenant)))
+
 	http.Handle("/", handlers.CompressHandler(handlers.CombinedLoggingHandler(os.Stdout, r)))
 
-	return s
+	return r
 }
 
 //func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -75,28 +309,212 @@ func (h *Handler) registerHandlers() *mux.Router {
 //}
 
 // GetProduct retrieves a single product from the database.
+// With "embed=variants" the product's variants are included. The ETag
+// header carries the product version; If-None-Match yields a 304 and
+// If-Match a 412 as usual. With "asOf" the product is reconstructed as it
+// was at that time from its history.
 func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	// Get the variables from the request
 	vars := mux.Vars(r)
 	productId := vars["id"]
+	if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+		h.getProductAsOf(w, r, productId, asOf)
+		return
+	}
 	product, err := h.ProductService.Product(r.Context(), productId)
 	if err != nil {
-		respondWithError(w, r, http.StatusNotFound, "productId: "+productId+" was not found.")
-	} else {
-		respondWithJson(w, r, http.StatusOK, product)
+		respondWithServiceError(w, r, err)
+		return
+	}
+	h.respondWithProduct(w, r, product)
+}
+
+// GetProductByCode retrieves a single product by its product code,
+// responding like GetProduct.
+func (h *Handler) GetProductByCode(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductService.ProductByCode(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	h.respondWithProduct(w, r, product)
+}
+
+// routeProduct loads the product named by the {id} of the route. See
+// productVar.
+func (h *Handler) routeProduct(r *http.Request) (*catalog.Product, error) {
+	return h.productVar(r, "id")
+}
+
+// productVar loads the product named by the route variable, so that the
+// resources of a product that does not exist, was deleted or belongs to
+// another tenant are not found. Like respondWithProduct, it only lets
+// admins read the nested resources of products that are not published.
+func (h *Handler) productVar(r *http.Request, name string) (*catalog.Product, error) {
+	product, err := h.ProductService.Product(r.Context(), mux.Vars(r)[name])
+	if err != nil {
+		return nil, err
+	}
+	if (r.Method == "GET" || r.Method == "HEAD") && !product.Published(time.Now()) && !requestHasScope(r, adminScope) {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", product.ID)
 	}
+	return product, nil
 }
 
-// GetProducts retrieves all of the products from the database.
+// respondWithProduct evaluates the preconditions, embeds the requested
+// relations and writes the product with its ETag. Products that are not
+// published are only shown to admins.
+func (h *Handler) respondWithProduct(w http.ResponseWriter, r *http.Request, product *catalog.Product) {
+	if !product.Published(time.Now()) && !requestHasScope(r, adminScope) {
+		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("product %v not found", product.ID))
+		return
+	}
+	if code := checkPreconditions(r, product); code != 0 {
+		respondWithPrecondition(w, r, code, product)
+		return
+	}
+	if embeds(r, "variants") {
+		var err error
+		if product.Variants, err = h.VariantService.Variants(r.Context(), product.ID); err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+	}
+	w.Header().Set("ETag", productETag(product))
+	respondWithJson(w, r, http.StatusOK, product)
+}
+
+// embeds reports whether the comma separated "embed" query parameter names the relation.
+func embeds(r *http.Request, relation string) bool {
+	for _, v := range r.URL.Query()["embed"] {
+		for _, e := range strings.Split(v, ",") {
+			if e == relation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetProducts retrieves a page of products from the database.
+// Filters, sort order and paging are taken from the query string.
 func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.ProductService.Products(r.Context())
+	query, err := productQueryFromRequest(r)
 	if err != nil {
-		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("An error occured retrieving products: %v", err))
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	if !restrictProductQuery(w, r, &query) {
+		return
+	}
+	products, err := h.ProductService.Products(r.Context(), query)
+	if err != nil {
+		respondWithServiceError(w, r, err)
 	} else {
 		respondWithJson(w, r, http.StatusOK, products)
 	}
 }
 
+// restrictProductQuery limits a listing to what the caller may see. Only
+// admins list deleted products and products that are not published; for
+// everyone else the listing is restricted to the products published now.
+// It responds and returns false when the query asks for deleted products
+// without the admin scope.
+func restrictProductQuery(w http.ResponseWriter, r *http.Request, q *catalog.ProductQuery) bool {
+	if requestHasScope(r, adminScope) {
+		return true
+	}
+	if q.Deleted != catalog.ExcludeDeleted {
+		respondWithError(w, r, http.StatusForbidden, "Insufficient scope. admin:product needed to list deleted products")
+		return false
+	}
+	q.PublishedAt = time.Now()
+	return true
+}
+
+// productQueryFromRequest reads the filter, sort and paging parameters from the query string.
+func productQueryFromRequest(r *http.Request) (catalog.ProductQuery, error) {
+	v := r.URL.Query()
+	q := catalog.ProductQuery{
+		Page:              catalog.Page{Cursor: v.Get("cursor")},
+		ProductCode:       v.Get("productCode"),
+		ProductCodePrefix: v.Get("productCodePrefix"),
+		ShortDescContains: v.Get("shortDesc"),
+		CategoryID:        v.Get("category"),
+	}
+	var fields []catalog.FieldError
+	var err error
+	if q.Limit, err = intParam(v.Get("limit"), 1, catalog.MaxPageSize); err != nil {
+		fields = append(fields, catalog.FieldError{Field: "limit", Message: err.Error()})
+	}
+	if q.MinID, err = idParam(v.Get("minId")); err != nil {
+		fields = append(fields, catalog.FieldError{Field: "minId", Message: err.Error()})
+	}
+	if q.MaxID, err = idParam(v.Get("maxId")); err != nil {
+		fields = append(fields, catalog.FieldError{Field: "maxId", Message: err.Error()})
+	}
+	if q.Sort, err = catalog.ParseSort(v.Get("sort")); err != nil {
+		fields = append(fields, catalog.FieldError{Field: "sort", Message: err.Error()})
+	}
+	if q.Deleted, err = catalog.ParseDeletedFilter(v.Get("deleted")); err != nil {
+		fields = append(fields, catalog.FieldError{Field: "deleted", Message: err.Error()})
+	}
+	if status := catalog.ProductStatus(v.Get("status")); status != "" {
+		if !status.Valid() {
+			fields = append(fields, catalog.FieldError{Field: "status", Message: "is not a product status"})
+		}
+		q.Status = status
+	}
+	if d := v.Get("descendants"); d != "" {
+		if q.IncludeDescendants, err = strconv.ParseBool(d); err != nil {
+			fields = append(fields, catalog.FieldError{Field: "descendants", Message: "must be true or false"})
+		}
+	}
+	// Attribute filters are named attr.<attribute name>.
+	var attrKeys []string
+	for key := range v {
+		if strings.HasPrefix(key, "attr.") {
+			attrKeys = append(attrKeys, key)
+		}
+	}
+	sort.Strings(attrKeys)
+	for _, key := range attrKeys {
+		name := strings.TrimPrefix(key, "attr.")
+		if !catalog.ValidAttributeName(name) {
+			fields = append(fields, catalog.FieldError{Field: key, Message: "is not a valid attribute name"})
+			continue
+		}
+		for _, value := range v[key] {
+			q.Attributes = append(q.Attributes, catalog.AttributeFilter{Name: name, Value: value})
+		}
+	}
+	return q, catalog.ValidationError("invalid query parameters", fields)
+}
+
+// intParam parses an optional integer query parameter within [min, max].
+func intParam(v string, min, max int) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < min || i > max {
+		return 0, fmt.Errorf("must be between %d and %d", min, max)
+	}
+	return i, nil
+}
+
+// idParam parses an optional product id query parameter.
+func idParam(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("must be a product id")
+	}
+	return i, nil
+}
+
 // AddProduct adds a single product to the database.
 func (h *Handler) AddProduct(w http.ResponseWriter, r *http.Request) {
 	product := &catalog.Product{}
@@ -108,38 +526,78 @@ func (h *Handler) AddProduct(w http.ResponseWriter, r *http.Request) {
 	// Add the product to the database
 	err := h.ProductService.CreateProduct(r.Context(), product)
 	if err != nil {
-		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error adding product: %v", err))
+		respondWithServiceError(w, r, err)
 	} else {
 		respondWithJson(w, r, http.StatusCreated, product)
 	}
 }
 
-// UpdateProduct updates a product from the database.
+// UpdateProduct updates a product from the database. The update is
+// rejected with a 409 when the version in the body is stale, or a 412 when
+// the If-Match or If-None-Match header does not hold.
 func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	product := &catalog.Product{}
 	if err := json.NewDecoder(r.Body).Decode(product); err != nil {
-		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddProduct: %v", err))
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdateProduct: %v", err))
 		return
 	}
 	log.Debugf("The body that was PUT for ProductCode: %v", product.ProductCode)
+	// If-Match and If-None-Match take precedence over the version in the body
+	version, ok := h.preconditionVersion(w, r, product.ID)
+	if !ok {
+		return
+	}
+	if version != 0 {
+		product.Version = version
+	}
 	// Update the product to the database
 	err := h.ProductService.UpdateProduct(r.Context(), product)
 	if err != nil {
-		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error updating product: %v", err))
+		respondWithWriteError(w, r, err)
 	} else {
+		w.Header().Set("ETag", productETag(product))
 		respondWithJson(w, r, http.StatusAccepted, product)
 	}
 }
 
-// DeleteProduct deletes a product from the database.
+// DeleteProduct deletes a product from the database, honoring If-Match
+// and If-None-Match.
 func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	// Get the variables from the request
 	vars := mux.Vars(r)
 	productId := vars["id"]
 	log.Debugf("DeleteProduct(): From the request productId=%v", productId)
-	err := h.ProductService.DeleteProduct(r.Context(), productId)
+	version, ok := h.preconditionVersion(w, r, productId)
+	if !ok {
+		return
+	}
+	err := h.ProductService.DeleteProduct(r.Context(), productId, version)
+	if err != nil {
+		respondWithWriteError(w, r, err)
+	} else {
+		respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+	}
+}
+
+// RestoreProduct undoes the deletion of a product and responds with the
+// restored product. Restoring fails with a 409 when another product took
+// its product code in the meantime.
+func (h *Handler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductService.RestoreProduct(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", productETag(product))
+	respondWithJson(w, r, http.StatusOK, product)
+}
+
+// PurgeProduct permanently removes a deleted product. Products have to be
+// deleted before they can be purged.
+func (h *Handler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	err := h.ProductService.PurgeProduct(r.Context(), mux.Vars(r)["id"])
 	if err != nil {
-		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("No product was found during delete: %v", err))
+		respondWithServiceError(w, r, err)
 	} else {
 		respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
 	}
@@ -156,54 +614,58 @@ func respondWithJson(w http.ResponseWriter, r *http.Request, code int, payload i
 	w.Write(response)
 }
 
-func respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
-	respondWithJson(w, r, code, map[string]string{"error": message})
-}
+// realm is the protection space named in the WWW-Authenticate challenges.
+const realm = "catalog"
 
-type CustomClaims struct {
-	Scope string `json:"scope"`
-	jwt.StandardClaims
-}
-
-func checkScope(scope string, tokenString string) bool {
-	token, _ := jwt.ParseWithClaims(tokenString, &CustomClaims{}, nil)
-	claims, _ := token.Claims.(*CustomClaims)
-	log.Debugf("claims: %v\n", claims)
-	hasScope := false
-	result := strings.Split(claims.Scope, " ")
-	for i := range result {
-		if result[i] == scope {
-			hasScope = true
-		}
+// authenticate verifies the API key or else the bearer token of the
+// request. It returns the request carrying the caller's claims, or
+// responds 401 for missing or invalid credentials and returns nil.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) *http.Request {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return h.authenticateAPIKey(w, r, key)
+	}
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q`, realm))
+		respondWithError(w, r, http.StatusUnauthorized, "A bearer token is required")
+		return nil
 	}
-	return hasScope
+	if h.Verifier == nil {
+		log.Error("http: no token verifier is configured")
+		respondWithError(w, r, http.StatusInternalServerError, "Token verification is not configured")
+		return nil
+	}
+	claims, err := h.Verifier.Verify(r.Context(), token)
+	if err != nil {
+		log.Debugf("Rejected bearer token: %v", err)
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm=%q, error="invalid_token", error_description=%q`, realm, err.Error()))
+		respondWithError(w, r, http.StatusUnauthorized, "Invalid bearer token: "+err.Error())
+		return nil
+	}
+	ctx := auth.WithClaims(r.Context(), claims)
+	// Record the subject as the actor of the changes made by the request.
+	if claims.Subject != "" {
+		ctx = catalog.WithActor(ctx, claims.Subject)
+	}
+	return r.WithContext(ctx)
 }
 
-func readMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	authHeaderParts := strings.Split(r.Header.Get("Authorization"), " ")
-	token := authHeaderParts[1]
-	log.Debugf("readMiddleware token: %v\n", token)
-
-	hasScope := checkScope("read:product", token)
-	if !hasScope {
-		message := "Insufficient scope. read:product needed"
-		respondWithError(w, r, 401, message)
-		return
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.Fields(r.Header.Get("Authorization"))
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
 	}
-	// Call the next handler
-	next(w, r)
+	return parts[1], true
 }
 
-func writeMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	authHeaderParts := strings.Split(r.Header.Get("Authorization"), " ")
-	token := authHeaderParts[1]
+// adminScope grants access to deleted and unpublished products.
+const adminScope = "admin:product"
 
-	hasScope := checkScope("write:product", token)
-	if !hasScope {
-		message := "Insufficient scope. write:product needed"
-		respondWithError(w, r, 401, message)
-		return
-	}
-	// Call the next handler
-	next(w, r)
-}
\ No newline at end of file
+// requestHasScope reports whether the verified token of the request
+// carries the scope. Unauthenticated requests have no scopes.
+func requestHasScope(r *http.Request, scope string) bool {
+	claims := auth.ClaimsFromContext(r.Context())
+	return claims != nil && claims.HasScope(scope)
+}
@@ -2,22 +2,79 @@ package http
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"errors"
+	"io"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
 	"github.com/mvonbodun/go-package-test/catalog/mock"
 	"golang.org/x/net/context"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Global variable for the Handler
 var h *Handler
 
+// Issuer and audience of the test tokens.
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "https://catalog.example.com"
+)
+
+// testKey signs the test tokens.
+var testKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+// testToken carries the read:product and write:product scopes.
+var testToken = signToken(jwt.MapClaims{"sub": "test", "scope": "read:product write:product"})
+
+// adminToken also carries the admin:product scope.
+var adminToken = signToken(jwt.MapClaims{"sub": "admin", "scope": "read:product write:product admin:product"})
+
+// operatorToken carries the admin:tenant scope of the operators of the
+// deployment.
+var operatorToken = signToken(jwt.MapClaims{"sub": "operator", "scope": "read:product admin:tenant"})
+
+// signToken returns a token with the claims, valid for an hour, signed
+// with testKey.
+func signToken(claims jwt.MapClaims) string {
+	for k, v := range map[string]interface{}{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	} {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	s, err := token.SignedString(testKey)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// newRequest creates a request with the headers the router requires.
+func newRequest(method, url string, body io.Reader) *http.Request {
+	r, _ := http.NewRequest(method, url, body)
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r
+}
+
 func TestMain(m *testing.M) {
 	// Global register the handlers, they can only be run once
 	h = NewHandler()
+	h.Verifier = auth.NewVerifier(auth.StaticKeySet{"test": &testKey.PublicKey}, testIssuer, testAudience)
 
 	exitCode := m.Run()
 
@@ -45,7 +102,7 @@ func TestHandler_GetProduct(t *testing.T) {
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("GET", "/product/100", nil)
+	r := newRequest("GET", "/product/100", nil)
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -54,6 +111,32 @@ func TestHandler_GetProduct(t *testing.T) {
 	}
 }
 
+func TestHandler_GetProductByCode(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Mock our ProductByCode() call
+	ps.ProductByCodeFn = func(ctx context.Context, code string) (*catalog.Product, error) {
+		if code != "tee-red" {
+			t.Fatalf("unexpected code: %v", code)
+		}
+		return &catalog.Product{ID: "100", ProductCode: code, Version: 2, Status: catalog.StatusActive}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/code/tee-red", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("expected ETag \"2\", got %q", etag)
+	}
+}
+
 func TestHandler_GetProductNotFound(t *testing.T) {
 	// Inject our mock into our handler.
 	var ps mock.ProductService
@@ -61,12 +144,12 @@ func TestHandler_GetProductNotFound(t *testing.T) {
 
 	// Mock our Product() call
 	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
-		return &catalog.Product{}, errors.New("not found")
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
 	}
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("GET", "/product/99", nil)
+	r := newRequest("GET", "/product/99", nil)
 	h.Router.ServeHTTP(w, r)
 
 	if w.Code != http.StatusNotFound {
@@ -84,7 +167,7 @@ func TestHandler_GetProducts(t *testing.T) {
 	var ps mock.ProductService
 	h.ProductService = &ps
 
-	ps.ProductsFn = func(ctx context.Context) ([]*catalog.Product, error) {
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
 
 		products := []*catalog.Product{
 			{
@@ -101,12 +184,12 @@ func TestHandler_GetProducts(t *testing.T) {
 			},
 		}
 
-		return products, nil
+		return &catalog.ProductPage{Products: products}, nil
 	}
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("GET", "/products", nil)
+	r := newRequest("GET", "/products", nil)
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -121,26 +204,29 @@ func TestHandler_GetProductsNotFound(t *testing.T) {
 	var ps mock.ProductService
 	h.ProductService = &ps
 
-	ps.ProductsFn = func(ctx context.Context) ([]*catalog.Product, error) {
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
 
 		products := []*catalog.Product{
 			{},
 		}
 
-		return products, errors.New("no products found")
+		return &catalog.ProductPage{Products: products}, errors.New("database is down")
 	}
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("GET", "/products", nil)
+	r := newRequest("GET", "/products", nil)
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
 	if !ps.ProductsInvoked {
 		t.Fatal("expect Products() to be invoked.")
 	}
-	if w.Code != http.StatusNotFound {
-		t.Fatal("expected 404 status code")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("expected 500 status code")
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("database is down")) {
+		t.Fatal("expected the underlying error not to be exposed")
 	}
 
 }
@@ -158,7 +244,7 @@ func TestHandler_AddProduct(t *testing.T) {
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("POST", "/product", bytes.NewBuffer(payload))
+	r := newRequest("POST", "/product", bytes.NewBuffer(payload))
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -184,7 +270,7 @@ func TestHandler_AddProductBadJson(t *testing.T) {
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("POST", "/product", bytes.NewBuffer(payload))
+	r := newRequest("POST", "/product", bytes.NewBuffer(payload))
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -207,12 +293,12 @@ func TestHandler_AddProductFailedInsert(t *testing.T) {
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("POST", "/product", bytes.NewBuffer(payload))
+	r := newRequest("POST", "/product", bytes.NewBuffer(payload))
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
-	if w.Code != http.StatusBadRequest {
-		t.Fatal("expected 400 status code")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("expected 500 status code")
 	}
 
 }
@@ -222,13 +308,13 @@ func TestHandler_DeleteProduct(t *testing.T) {
 	var ps mock.ProductService
 	h.ProductService = &ps
 
-	ps.DeleteProductFn = func(ctx context.Context, id string) error {
+	ps.DeleteProductFn = func(ctx context.Context, id string, version int64) error {
 		return nil
 	}
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("DELETE", "/product/100", nil)
+	r := newRequest("DELETE", "/product/100", nil)
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -246,13 +332,13 @@ func TestHandler_DeleteProductNotFound(t *testing.T) {
 	var ps mock.ProductService
 	h.ProductService = &ps
 
-	ps.DeleteProductFn = func(ctx context.Context, id string) error {
-		return errors.New("product not found")
+	ps.DeleteProductFn = func(ctx context.Context, id string, version int64) error {
+		return catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
 	}
 
 	// Invoke the handler.
 	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("DELETE", "/product/100", nil)
+	r := newRequest("DELETE", "/product/100", nil)
 	h.Router.ServeHTTP(w, r)
 
 	// Validate mock.
@@ -264,3 +350,351 @@ func TestHandler_DeleteProductNotFound(t *testing.T) {
 	}
 
 }
+
+func TestHandler_GetProductsPage(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if q.Cursor != "abc" || q.Limit != 10 {
+			t.Fatalf("unexpected query: %+v", q)
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{}, Next: "def"}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?cursor=abc&limit=10", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"next":"def"`)) {
+		t.Fatalf("expected next cursor in body: %s", w.Body.String())
+	}
+}
+
+func TestHandler_GetProductsBadLimit(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?limit=0", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if ps.ProductsInvoked {
+		t.Fatal("expected Products() not to be invoked.")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("expected 400 status code")
+	}
+}
+
+func TestHandler_GetProductsFiltered(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if q.ProductCodePrefix != "ab" || q.ShortDescContains != "shirt" || q.MinID != 10 || q.MaxID != 20 {
+			t.Fatalf("unexpected filters: %+v", q)
+		}
+		if len(q.Sort) != 2 || q.Sort[0] != (catalog.SortField{Field: "productCode"}) ||
+			q.Sort[1] != (catalog.SortField{Field: "productId", Desc: true}) {
+			t.Fatalf("unexpected sort: %+v", q.Sort)
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{}}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?productCodePrefix=ab&shortDesc=shirt&minId=10&maxId=20&sort=productCode,-productId", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetProductsBadSort(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?sort=longDesc", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if ps.ProductsInvoked {
+		t.Fatal("expected Products() not to be invoked.")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("expected 400 status code")
+	}
+}
+
+func TestHandler_AddProductConflict(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.CreateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return catalog.Errorf(catalog.ErrConflict, "duplicate value")
+	}
+
+	payload := []byte(`{  "productCode": "prod15", "shortDesc": "Short desc for prod 15", "longDesc": "Long desc for prod 15" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_UpdateProductInvalid(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.UpdateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return catalog.Errorf(catalog.ErrInvalid, "product with unassigned ID passed in to UpdateProduct")
+	}
+
+	payload := []byte(`{  "productCode": "prod15" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/product", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ps.UpdateProductInvoked {
+		t.Fatal("expect UpdateProduct() to be invoked.")
+	}
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_AddProductValidationProblem(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.CreateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return product.Validate()
+	}
+
+	payload := []byte(`{ "shortDesc": "Short desc for prod 15" }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product", bytes.NewBuffer(payload))
+	r.Header.Set("X-Request-Id", "req-1")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 status code, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Fatalf("unexpected content type: %v", ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != ProblemValidation || p.Status != 422 || p.Instance != "/product" || p.TraceID != "req-1" {
+		t.Fatalf("unexpected problem: %+v", p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Field != "productCode" {
+		t.Fatalf("expected a productCode field error: %+v", p.Errors)
+	}
+}
+
+func TestHandler_GetProductsBadParamsProblem(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?limit=x&minId=-1", nil)
+	h.Router.ServeHTTP(w, r)
+
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != http.StatusBadRequest || len(p.Errors) != 2 {
+		t.Fatalf("unexpected problem: %+v", p)
+	}
+}
+
+func TestHandler_RestoreProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.RestoreProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		if id != "100" {
+			t.Fatalf("unexpected id: %v", id)
+		}
+		return &catalog.Product{ID: "100", ProductCode: "abcdef", Version: 4}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100:restore", nil)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ps.RestoreProductInvoked {
+		t.Fatal("expected RestoreProduct() to be invoked.")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"4"` {
+		t.Fatalf("expected 200 with ETag \"4\", got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestHandler_RestoreProductCodeInUse(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.RestoreProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return nil, catalog.Errorf(catalog.ErrConflict, "product code is already in use")
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100:restore", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_PurgeProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.PurgeProductFn = func(ctx context.Context, id string) error {
+		if id != "100" {
+			t.Fatalf("unexpected id: %v", id)
+		}
+		return nil
+	}
+
+	// Without the admin scope the product is not purged.
+	w := httptest.NewRecorder()
+	r := newRequest("DELETE", "/product/100:purge", nil)
+	h.Router.ServeHTTP(w, r)
+	if ps.PurgeProductInvoked || w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without the admin scope, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("DELETE", "/product/100:purge", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if !ps.PurgeProductInvoked {
+		t.Fatal("expected PurgeProduct() to be invoked.")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetProductsDeleted(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		if q.Deleted != catalog.OnlyDeleted {
+			t.Fatalf("expected only deleted products, got %+v", q)
+		}
+		return &catalog.ProductPage{Products: []*catalog.Product{}}, nil
+	}
+
+	// Listing deleted products requires the admin scope.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products?deleted=only", nil)
+	h.Router.ServeHTTP(w, r)
+	if ps.ProductsInvoked || w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without the admin scope, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/products?deleted=only", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if !ps.ProductsInvoked || w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetProductUnpublished(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	publishAt := time.Now().Add(time.Hour)
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 1, Status: catalog.StatusActive, PublishAt: &publishAt}, nil
+	}
+
+	// The product is only published in an hour.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100", nil)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+
+	// Admins see it anyway.
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/product/100", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetProductsPublished(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	var query catalog.ProductQuery
+	ps.ProductsFn = func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+		query = q
+		return &catalog.ProductPage{Products: []*catalog.Product{}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/products", nil)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || query.PublishedAt.IsZero() {
+		t.Fatalf("expected the public listing to be restricted to published products, got %d %+v", w.Code, query)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("GET", "/products?status=review", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !query.PublishedAt.IsZero() || query.Status != catalog.StatusReview {
+		t.Fatalf("expected admins to list products in review, got %d %+v", w.Code, query)
+	}
+}
@@ -0,0 +1,457 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// Media types accepted by PATCH /product/{id}.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// errPatchTestFailed is returned when a JSON Patch test operation fails.
+var errPatchTestFailed = errors.New("test operation failed")
+
+// PatchProduct applies an RFC 7396 JSON Merge Patch or an RFC 6902 JSON
+// Patch to a product. The patch is applied to the JSON form of the current
+// product and only the fields it changed are passed on to the service, so
+// concurrent edits of other fields are kept. A version in the patched
+// document, If-Match or a JSON Patch test operation make the update
+// conditional on the product version.
+func (h *Handler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		respondWithError(w, r, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Content-Type must be %s or %s", mergePatchType, jsonPatchType))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error reading body during PatchProduct: %v", err))
+		return
+	}
+	id := mux.Vars(r)["id"]
+	version, ok := h.preconditionVersion(w, r, id)
+	if !ok {
+		return
+	}
+	current, err := h.routeProduct(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	doc, err := productDocument(current)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	var patched interface{}
+	if mediaType == mergePatchType {
+		var patch interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during PatchProduct: %v", err))
+			return
+		}
+		patched = mergePatch(doc, patch)
+	} else {
+		var ops []patchOperation
+		if err := json.Unmarshal(body, &ops); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during PatchProduct: %v", err))
+			return
+		}
+		// Operations modify the document in place, so patch a copy.
+		target, err := productDocument(current)
+		if err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+		patched, err = applyJSONPatch(target, ops)
+		if err == errPatchTestFailed {
+			respondWithError(w, r, http.StatusConflict, "A test operation of the patch failed.")
+			return
+		}
+		if err != nil {
+			respondWithError(w, r, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		// A test operation was checked against this version of the product.
+		if version == 0 && hasTestOperation(ops) {
+			version = current.Version
+		}
+	}
+	patch, err := diffProduct(doc, patched)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	if version != 0 {
+		patch.Version = version
+	}
+	product, err := h.ProductService.PatchProduct(r.Context(), id, patch)
+	if err != nil {
+		respondWithWriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", productETag(product))
+	respondWithJson(w, r, http.StatusOK, product)
+}
+
+// productDocument returns the JSON form of a product as generic values.
+// The attributes object is always present so JSON Patch operations can
+// add to it.
+func productDocument(p *catalog.Product) (map[string]interface{}, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if _, ok := doc["attributes"]; !ok {
+		doc["attributes"] = map[string]interface{}{}
+	}
+	return doc, nil
+}
+
+// mergePatch applies an RFC 7396 merge patch to target.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	result := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		result[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = mergePatch(result[k], v)
+	}
+	return result
+}
+
+// diffProduct compares the JSON form of a product before and after a patch
+// and returns the changes as a ProductPatch. A changed version becomes the
+// expected version.
+func diffProduct(before map[string]interface{}, after interface{}) (*catalog.ProductPatch, error) {
+	doc, ok := after.(map[string]interface{})
+	if !ok {
+		return nil, catalog.Errorf(catalog.ErrInvalid, "the patched product must be an object")
+	}
+	patch := &catalog.ProductPatch{}
+	var fields []catalog.FieldError
+	for k, v := range doc {
+		switch k {
+		case "productCode", "shortDesc", "longDesc", "attributes", "version", "status", "publishAt", "unpublishAt":
+		case "productId":
+			if !reflect.DeepEqual(before[k], v) {
+				fields = append(fields, catalog.FieldError{Field: k, Message: "cannot be changed"})
+			}
+		default:
+			fields = append(fields, catalog.FieldError{Field: k, Message: "is not a patchable field"})
+		}
+	}
+	for _, f := range []struct {
+		name string
+		dst  **string
+	}{{"productCode", &patch.ProductCode}, {"shortDesc", &patch.ShortDesc}, {"longDesc", &patch.LongDesc}} {
+		v, present := doc[f.name]
+		if reflect.DeepEqual(before[f.name], v) {
+			continue
+		}
+		s, isString := v.(string)
+		if present && !isString {
+			fields = append(fields, catalog.FieldError{Field: f.name, Message: "must be a string"})
+			continue
+		}
+		// A removed field becomes empty; a required one then fails validation.
+		*f.dst = &s
+	}
+	if v := doc["status"]; !reflect.DeepEqual(before["status"], v) {
+		s, _ := v.(string)
+		if status := catalog.ProductStatus(s); status.Valid() {
+			patch.Status = &status
+		} else {
+			fields = append(fields, catalog.FieldError{Field: "status", Message: "is not a product status"})
+		}
+	}
+	for _, name := range []string{"publishAt", "unpublishAt"} {
+		v := doc[name]
+		if reflect.DeepEqual(before[name], v) {
+			continue
+		}
+		// A removed or null time clears the schedule.
+		var t *time.Time
+		if v != nil {
+			s, _ := v.(string)
+			parsed, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				fields = append(fields, catalog.FieldError{Field: name, Message: "must be an RFC 3339 date-time"})
+				continue
+			}
+			t = &parsed
+		}
+		if name == "publishAt" {
+			patch.PublishAt = &t
+		} else {
+			patch.UnpublishAt = &t
+		}
+	}
+	if v, present := doc["version"]; present && !reflect.DeepEqual(before["version"], v) {
+		n, isNumber := v.(float64)
+		if !isNumber || n < 1 || n != float64(int64(n)) {
+			fields = append(fields, catalog.FieldError{Field: "version", Message: "must be a positive integer"})
+		} else {
+			patch.Version = int64(n)
+		}
+	}
+	oldAttrs, _ := before["attributes"].(map[string]interface{})
+	newAttrs, isObject := doc["attributes"].(map[string]interface{})
+	if _, present := doc["attributes"]; present && !isObject {
+		fields = append(fields, catalog.FieldError{Field: "attributes", Message: "must be an object"})
+	}
+	for name, v := range newAttrs {
+		if !reflect.DeepEqual(oldAttrs[name], v) {
+			if patch.Attributes == nil {
+				patch.Attributes = map[string]interface{}{}
+			}
+			if v == nil {
+				fields = append(fields, catalog.FieldError{Field: "attributes." + name, Message: "must not be null"})
+			}
+			patch.Attributes[name] = v
+		}
+	}
+	for name := range oldAttrs {
+		if _, ok := newAttrs[name]; !ok {
+			if patch.Attributes == nil {
+				patch.Attributes = map[string]interface{}{}
+			}
+			patch.Attributes[name] = nil
+		}
+	}
+	if err := catalog.ValidationError("patch is invalid", fields); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// patchOperation is a single RFC 6902 JSON Patch operation.
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// hasTestOperation reports whether the patch contains a test operation.
+func hasTestOperation(ops []patchOperation) bool {
+	for _, op := range ops {
+		if op.Op == "test" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyJSONPatch applies RFC 6902 operations to doc in order. It returns
+// errPatchTestFailed when a test operation fails.
+func applyJSONPatch(doc interface{}, ops []patchOperation) (interface{}, error) {
+	for i, op := range ops {
+		var value interface{}
+		if op.Value != nil {
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		switch op.Op {
+		case "add":
+			if op.Value == nil {
+				return nil, fmt.Errorf("operation %d: add requires a value", i)
+			}
+			doc, err = pointerAdd(doc, op.Path, value)
+		case "remove":
+			doc, _, err = pointerRemove(doc, op.Path)
+		case "replace":
+			if op.Value == nil {
+				return nil, fmt.Errorf("operation %d: replace requires a value", i)
+			}
+			if doc, _, err = pointerRemove(doc, op.Path); err == nil {
+				doc, err = pointerAdd(doc, op.Path, value)
+			}
+		case "move":
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("operation %d: cannot move a value into itself", i)
+			}
+			var moved interface{}
+			if doc, moved, err = pointerRemove(doc, op.From); err == nil {
+				doc, err = pointerAdd(doc, op.Path, moved)
+			}
+		case "copy":
+			var copied interface{}
+			if copied, err = pointerGet(doc, op.From); err == nil {
+				doc, err = pointerAdd(doc, op.Path, copied)
+			}
+		case "test":
+			var actual interface{}
+			if actual, err = pointerGet(doc, op.Path); err == nil && !reflect.DeepEqual(actual, value) {
+				return nil, errPatchTestFailed
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+// pointerGet returns the value at pointer.
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd adds value at pointer, replacing an object member or
+// inserting into an array, and returns the new document.
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		i := len(node)
+		if last != "-" {
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(node) {
+				return nil, fmt.Errorf("path %q is out of range", pointer)
+			}
+		}
+		node = append(node[:i], append([]interface{}{value}, node[i:]...)...)
+		return pointerSet(doc, parentPointer, node)
+	}
+	return nil, fmt.Errorf("path %q does not exist", pointer)
+}
+
+// pointerRemove removes the value at pointer and returns the new document
+// and the removed value.
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	removed, err := pointerGet(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, _ := pointerGet(doc, parentPointer)
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		delete(node, last)
+		return doc, removed, nil
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		node = append(node[:i:i], node[i+1:]...)
+		doc, err = pointerSet(doc, parentPointer, node)
+		return doc, removed, err
+	}
+	return nil, nil, fmt.Errorf("path %q does not exist", pointer)
+}
+
+// pointerSet replaces the value at an existing pointer, used to store
+// arrays that changed length.
+func pointerSet(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	tokens, _ := parsePointer(pointer)
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		node[i] = value
+	}
+	return doc, nil
+}
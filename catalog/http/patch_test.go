@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_PatchProductMergePatch(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", ShortDesc: "Tee", LongDesc: "A tee",
+			Version: 3, Attributes: map[string]interface{}{"color": "red", "size": "M"}}, nil
+	}
+	ps.PatchProductFn = func(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error) {
+		if patch.ShortDesc == nil || *patch.ShortDesc != "Blue tee" {
+			t.Fatalf("unexpected shortDesc: %v", patch.ShortDesc)
+		}
+		if patch.ProductCode != nil || patch.LongDesc != nil {
+			t.Fatalf("expected only shortDesc to change, got %+v", patch)
+		}
+		if len(patch.Attributes) != 2 || patch.Attributes["color"] != "blue" || patch.Attributes["size"] != nil {
+			t.Fatalf("unexpected attributes: %v", patch.Attributes)
+		}
+		if patch.Version != 0 {
+			t.Fatalf("expected an unconditional patch, got version %d", patch.Version)
+		}
+		return &catalog.Product{ID: id, ProductCode: "tee", ShortDesc: "Blue tee", Version: 4}, nil
+	}
+
+	payload := []byte(`{ "shortDesc": "Blue tee", "attributes": { "color": "blue", "size": null } }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBuffer(payload))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d: %s", w.Code, w.Body.String())
+	}
+	if !ps.PatchProductInvoked {
+		t.Fatal("expected PatchProduct() to be invoked")
+	}
+	if etag := w.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("expected ETag \"4\", got %q", etag)
+	}
+}
+
+func TestHandler_PatchProductJSONPatch(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", LongDesc: "A tee", Version: 3}, nil
+	}
+	ps.PatchProductFn = func(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error) {
+		if patch.LongDesc == nil || *patch.LongDesc != "" {
+			t.Fatalf("expected longDesc to be cleared, got %v", patch.LongDesc)
+		}
+		if patch.Attributes["color~/"] != "red" {
+			t.Fatalf("unexpected attributes: %v", patch.Attributes)
+		}
+		if patch.Version != 3 {
+			t.Fatalf("expected a patch based on the tested version 3, got %d", patch.Version)
+		}
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 4}, nil
+	}
+
+	payload := []byte(`[
+		{ "op": "test", "path": "/productCode", "value": "tee" },
+		{ "op": "remove", "path": "/longDesc" },
+		{ "op": "add", "path": "/attributes/color~0~1", "value": "red" }
+	]`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBuffer(payload))
+	r.Header.Set("Content-Type", "application/json-patch+json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_PatchProductTestFailed(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3}, nil
+	}
+
+	payload := []byte(`[{ "op": "test", "path": "/productCode", "value": "shirt" }]`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBuffer(payload))
+	r.Header.Set("Content-Type", "application/json-patch+json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 status code, got %d", w.Code)
+	}
+	if ps.PatchProductInvoked {
+		t.Fatal("expected PatchProduct() not to be invoked")
+	}
+}
+
+func TestHandler_PatchProductUnsupportedMediaType(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBufferString(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_PatchProductID(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBufferString(`{ "productId": "200" }`))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_PatchProductStatusAndSchedule(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	unpublishAt := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 3, Status: catalog.StatusReview, UnpublishAt: &unpublishAt}, nil
+	}
+	ps.PatchProductFn = func(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error) {
+		if patch.Status == nil || *patch.Status != catalog.StatusActive {
+			t.Fatalf("unexpected status: %v", patch.Status)
+		}
+		if patch.PublishAt == nil || *patch.PublishAt == nil || !(*patch.PublishAt).Equal(time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected publishAt: %v", patch.PublishAt)
+		}
+		if patch.UnpublishAt == nil || *patch.UnpublishAt != nil {
+			t.Fatalf("expected unpublishAt to be cleared, got %v", patch.UnpublishAt)
+		}
+		return &catalog.Product{ID: id, ProductCode: "tee", Version: 4, Status: catalog.StatusActive}, nil
+	}
+
+	payload := []byte(`{ "status": "active", "publishAt": "2020-03-01T10:00:00+01:00", "unpublishAt": null }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("PATCH", "/product/100", bytes.NewBuffer(payload))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Unknown statuses are rejected before the service is called.
+	ps.PatchProductInvoked = false
+	w = httptest.NewRecorder()
+	r = newRequest("PATCH", "/product/100", bytes.NewBufferString(`{ "status": "live" }`))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusUnprocessableEntity || ps.PatchProductInvoked {
+		t.Fatalf("expected 422 status code, got %d: %s", w.Code, w.Body.String())
+	}
+}
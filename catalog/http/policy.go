@@ -0,0 +1,289 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"golang.org/x/net/context"
+)
+
+// Policy decides which callers may perform which actions. Actions are
+// named "<resource>:<verb>", e.g. "product:update"; every route declares
+// the action it performs. A request is allowed when an allow rule matches
+// it and no deny rule does.
+//
+// A policy is read from JSON such as:
+//
+//	{
+//	  "roleClaim": "https://geauxcommerce.com/roles",
+//	  "rules": [
+//	    {"name": "readers", "effect": "allow", "scopes": ["read:product"], "actions": ["*:read"]},
+//	    {"name": "apparel editors", "effect": "allow", "roles": ["apparel-editor"],
+//	     "actions": ["product:update", "price:*"], "conditions": {"categories": ["12"]}},
+//	    {"name": "contractors never delete", "effect": "deny", "roles": ["contractor"], "actions": ["*:delete"]}
+//	  ]
+//	}
+type Policy struct {
+	// RoleClaim names the token claim listing the caller's roles;
+	// "roles" when empty.
+	RoleClaim string        `json:"roleClaim,omitempty"`
+	Rules     []*PolicyRule `json:"rules"`
+}
+
+// PolicyRule allows or denies actions to the callers it matches. A caller
+// matches when it has one of the scopes, one of the roles and is one of
+// the subjects; empty lists match every caller.
+type PolicyRule struct {
+	Name   string `json:"name"`
+	Effect string `json:"effect"`
+
+	Scopes   []string `json:"scopes,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Subjects []string `json:"subjects,omitempty"`
+
+	// Actions are patterns such as "product:update", "price:*" or "*:read".
+	Actions    []string         `json:"actions"`
+	Conditions *PolicyCondition `json:"conditions,omitempty"`
+}
+
+// PolicyCondition restricts a rule to some resources.
+type PolicyCondition struct {
+	// Categories restricts the rule to the products in, and the categories
+	// at or below, one of these categories. Routes that do not name a
+	// product or a category, such as creating a product, never meet it.
+	// Assigning a product to a category only meets it when both the
+	// category and the product already do.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Policy rule effects.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// defaultPolicy grants the scopes issued before policies existed: reading
+// with read:product, changing products with write:product and the history,
+// purging, webhooks and API keys with admin:product. Provisioning tenants
+// and changing the categories, attributes and price lists, which all
+// tenants share, takes admin:tenant, which only the operators of the
+// deployment hold.
+const defaultPolicy = `{
+  "rules": [
+    {"name": "read:product", "effect": "allow", "scopes": ["read:product"],
+     "actions": ["product:read", "price:read", "variant:read", "pricelist:read", "category:read", "attribute:read"]},
+    {"name": "write:product", "effect": "allow", "scopes": ["write:product"],
+     "actions": ["product:create", "product:update", "product:delete", "product:restore", "product:batch",
+                 "price:create", "price:update", "price:delete",
+                 "variant:create", "variant:update", "variant:delete",
+                 "category:assign", "category:unassign"]},
+    {"name": "admin:product", "effect": "allow", "scopes": ["admin:product"],
+     "actions": ["product:purge", "product:history", "webhook:*", "apikey:*"]},
+    {"name": "admin:tenant", "effect": "allow", "scopes": ["admin:tenant"],
+     "actions": ["tenant:*", "pricelist:create",
+                 "category:create", "category:update", "category:delete",
+                 "attribute:create", "attribute:update", "attribute:delete"]}
+  ]
+}`
+
+// DefaultPolicy is the policy enforced when none is configured.
+var DefaultPolicy = mustParsePolicy(defaultPolicy)
+
+// ParsePolicy reads and validates a JSON policy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("policy: %v", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadPolicy reads a JSON policy file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+func mustParsePolicy(data string) *Policy {
+	p, err := ParsePolicy([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// validate checks that every rule is named, has an effect and valid
+// action patterns.
+func (p *Policy) validate() error {
+	for i, rule := range p.Rules {
+		if rule == nil || rule.Name == "" {
+			return fmt.Errorf("policy: rule %d has no name", i+1)
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("policy: rule %q: effect must be allow or deny", rule.Name)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("policy: rule %q has no actions", rule.Name)
+		}
+		for _, a := range rule.Actions {
+			if _, err := path.Match(a, ""); err != nil || !strings.Contains(a, ":") && a != "*" {
+				return fmt.Errorf("policy: rule %q: invalid action %q", rule.Name, a)
+			}
+		}
+	}
+	return nil
+}
+
+// PolicyRequest is an action a caller attempts.
+type PolicyRequest struct {
+	Claims *auth.Claims
+	Action string
+	// Categories returns the IDs of the categories the resource is in,
+	// including their ancestors. It is only called for rules with a
+	// category condition and is nil for routes without a resource.
+	Categories func(ctx context.Context) ([]string, error)
+	// Others return the categories of the further resources the action
+	// touches, such as the product category:assign adds to the category.
+	Others []func(ctx context.Context) ([]string, error)
+}
+
+// Decision is the outcome of an authorization. Reason explains it.
+type Decision struct {
+	Allowed bool
+	Rule    string
+	Reason  string
+}
+
+// Authorize decides the request. Deny rules win over allow rules.
+func (p *Policy) Authorize(ctx context.Context, req *PolicyRequest) (*Decision, error) {
+	roleClaim := p.RoleClaim
+	if roleClaim == "" {
+		roleClaim = "roles"
+	}
+	roles := req.Claims.Strings(roleClaim)
+	var allow *PolicyRule
+	var unmet []string
+	for _, rule := range p.Rules {
+		if !rule.matchesAction(req.Action) || !rule.matchesCaller(req.Claims, roles) {
+			continue
+		}
+		if rule.Effect == EffectAllow && allow != nil {
+			continue
+		}
+		ok, err := rule.conditionsMet(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unmet = append(unmet, fmt.Sprintf("rule %q only applies to categories %s",
+				rule.Name, strings.Join(rule.Conditions.Categories, ", ")))
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return &Decision{Rule: rule.Name, Reason: fmt.Sprintf("rule %q denies %s", rule.Name, req.Action)}, nil
+		}
+		allow = rule
+	}
+	if allow != nil {
+		return &Decision{Allowed: true, Rule: allow.Name}, nil
+	}
+	reason := fmt.Sprintf("no rule allows %s", req.Action)
+	if len(unmet) > 0 {
+		reason += "; " + strings.Join(unmet, "; ")
+	}
+	return &Decision{Reason: reason}, nil
+}
+
+// namesScope reports whether a rule of the policy matches callers by the
+// scope.
+func (p *Policy) namesScope(scope string) bool {
+	for _, rule := range p.Rules {
+		if contains(rule.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAction reports whether one of the rule's patterns matches action.
+func (rule *PolicyRule) matchesAction(action string) bool {
+	for _, a := range rule.Actions {
+		if ok, _ := path.Match(a, action); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCaller reports whether the caller has one of the scopes, one of
+// the roles and is one of the subjects of the rule.
+func (rule *PolicyRule) matchesCaller(c *auth.Claims, roles []string) bool {
+	if len(rule.Scopes) > 0 && !anyOf(rule.Scopes, c.HasScope) {
+		return false
+	}
+	if len(rule.Roles) > 0 && !anyOf(rule.Roles, func(r string) bool { return contains(roles, r) }) {
+		return false
+	}
+	if len(rule.Subjects) > 0 && !contains(rule.Subjects, c.Subject) {
+		return false
+	}
+	return true
+}
+
+// conditionsMet reports whether the resources of the request meet the
+// condition of the rule: all of them for an allow rule, since the caller
+// acts on every one, and any of them for a deny rule.
+func (rule *PolicyRule) conditionsMet(ctx context.Context, req *PolicyRequest) (bool, error) {
+	ok, err := rule.Conditions.met(ctx, req.Categories)
+	for _, others := range req.Others {
+		if err != nil || ok == (rule.Effect == EffectDeny) {
+			break
+		}
+		ok, err = rule.Conditions.met(ctx, others)
+	}
+	return ok, err
+}
+
+// met reports whether the resource with the categories meets the
+// condition. A nil condition is always met.
+func (cond *PolicyCondition) met(ctx context.Context, resource func(ctx context.Context) ([]string, error)) (bool, error) {
+	if cond == nil || len(cond.Categories) == 0 {
+		return true, nil
+	}
+	if resource == nil {
+		return false, nil
+	}
+	categories, err := resource(ctx)
+	if err != nil {
+		return false, err
+	}
+	return anyOf(cond.Categories, func(id string) bool { return contains(categories, id) }), nil
+}
+
+func anyOf(values []string, f func(string) bool) bool {
+	for _, v := range values {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+const testPolicy = `{
+  "roleClaim": "https://example.com/roles",
+  "rules": [
+    {"name": "readers", "effect": "allow", "scopes": ["read:product"], "actions": ["*:read"]},
+    {"name": "apparel editors", "effect": "allow", "roles": ["apparel-editor"],
+     "actions": ["product:update", "price:*", "category:assign"], "conditions": {"categories": ["7"]}},
+    {"name": "editors batch", "effect": "allow", "roles": ["apparel-editor"], "actions": ["product:batch"]},
+    {"name": "contractors never delete", "effect": "deny", "roles": ["contractor"], "actions": ["*:delete"]}
+  ]
+}`
+
+func TestParsePolicy(t *testing.T) {
+	if _, err := ParsePolicy([]byte(testPolicy)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, data := range []string{
+		`{"rules": [{"effect": "allow", "actions": ["product:read"]}]}`,
+		`{"rules": [{"name": "x", "effect": "permit", "actions": ["product:read"]}]}`,
+		`{"rules": [{"name": "x", "effect": "allow"}]}`,
+		`{"rules": [{"name": "x", "effect": "allow", "actions": ["product"]}]}`,
+		`{"rules": [{"name": "x", "effect": "allow", "actions": ["product:[read"]}]}`,
+		`{"rules": {}}`,
+	} {
+		if _, err := ParsePolicy([]byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	p, _ := ParsePolicy([]byte(testPolicy))
+	claims := func(scope string, roles ...string) *auth.Claims {
+		r := make([]interface{}, len(roles))
+		for i, role := range roles {
+			r[i] = role
+		}
+		return &auth.Claims{Subject: "test", Scope: scope, Raw: map[string]interface{}{"https://example.com/roles": r}}
+	}
+	categories := func(ids ...string) func(ctx context.Context) ([]string, error) {
+		return func(ctx context.Context) ([]string, error) { return ids, nil }
+	}
+
+	tests := []struct {
+		name       string
+		claims     *auth.Claims
+		action     string
+		categories func(ctx context.Context) ([]string, error)
+		allowed    bool
+		reason     string
+	}{
+		{"reader", claims("read:product"), "category:read", nil, true, ""},
+		{"reader writing", claims("read:product"), "product:update", nil, false, "no rule allows product:update"},
+		{"editor in category", claims("", "apparel-editor"), "price:create", categories("1", "7"), true, ""},
+		{"editor outside category", claims("", "apparel-editor"), "product:update", categories("1", "9"), false,
+			`no rule allows product:update; rule "apparel editors" only applies to categories 7`},
+		{"editor without resource", claims("", "apparel-editor"), "product:update", nil, false,
+			`rule "apparel editors" only applies to categories 7`},
+		{"contractor reading", claims("read:product", "contractor"), "product:read", nil, true, ""},
+		{"contractor deleting", claims("read:product", "contractor", "apparel-editor"), "price:delete", categories("7"), false,
+			`rule "contractors never delete" denies price:delete`},
+	}
+	for _, tt := range tests {
+		d, err := p.Authorize(context.Background(), &PolicyRequest{Claims: tt.claims, Action: tt.action, Categories: tt.categories})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if d.Allowed != tt.allowed || !strings.Contains(d.Reason, tt.reason) {
+			t.Errorf("%s: unexpected decision %+v", tt.name, d)
+		}
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		scope   string
+		action  string
+		allowed bool
+	}{
+		{"read:product", "variant:read", true},
+		{"read:product", "product:update", false},
+		{"write:product", "category:assign", true},
+		{"write:product", "product:purge", false},
+		{"write:product", "category:delete", false},
+		{"write:product", "attribute:update", false},
+		{"admin:tenant", "pricelist:create", true},
+		{"admin:product", "webhook:replay", true},
+	} {
+		d, err := DefaultPolicy.Authorize(context.Background(), &PolicyRequest{Claims: &auth.Claims{Scope: tt.scope}, Action: tt.action})
+		if err != nil || d.Allowed != tt.allowed {
+			t.Errorf("%s %s: unexpected decision %+v, %v", tt.scope, tt.action, d, err)
+		}
+	}
+}
+
+func TestHandler_Policy(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.CategoryService = &cs
+	h.Policy, _ = ParsePolicy([]byte(testPolicy))
+	defer func() { h.Policy = nil }()
+
+	ps.DeleteProductFn = func(ctx context.Context, id string, version int64) error {
+		return nil
+	}
+	ps.UpdateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return nil
+	}
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+	cs.AssignProductFn = func(ctx context.Context, categoryID, productID string) error {
+		return nil
+	}
+	cs.ProductCategoriesFn = func(ctx context.Context, productID string) ([]*catalog.Category, error) {
+		if productID == "100" {
+			return []*catalog.Category{{ID: "12"}}, nil
+		}
+		return []*catalog.Category{{ID: "30"}}, nil
+	}
+	cs.BreadcrumbsFn = func(ctx context.Context, id string) ([]*catalog.Category, error) {
+		// Category 12 is below 7.
+		if id == "12" {
+			return []*catalog.Category{{ID: "7"}, {ID: "12"}}, nil
+		}
+		return []*catalog.Category{{ID: id}}, nil
+	}
+	editor := signToken(jwt.MapClaims{"sub": "editor", "https://example.com/roles": []string{"apparel-editor", "contractor"}})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+		reason string
+	}{
+		{"update in category", "PUT", "/product", `{"productId": "100", "productCode": "tee"}`, http.StatusAccepted, ""},
+		{"update outside category", "PUT", "/product", `{"productId": "200", "productCode": "mug"}`, http.StatusForbidden,
+			`rule \"apparel editors\" only applies to categories 7`},
+		{"delete", "DELETE", "/product/100", "", http.StatusForbidden, `rule \"contractors never delete\" denies product:delete`},
+		{"create", "POST", "/product", `{"productCode": "hat"}`, http.StatusForbidden, "no rule allows product:create"},
+		{"assign product in category", "PUT", "/category/7/products/100", "", http.StatusOK, ""},
+		{"assign product outside category", "PUT", "/category/7/products/200", "", http.StatusForbidden,
+			`rule \"apparel editors\" only applies to categories 7`},
+	}
+	for _, tt := range tests {
+		ps.UpdateProductInvoked, ps.DeleteProductInvoked, cs.AssignProductInvoked = false, false, false
+		w := httptest.NewRecorder()
+		r := newRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		r.Header.Set("Authorization", "Bearer "+editor)
+		h.Router.ServeHTTP(w, r)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d %s", tt.name, tt.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tt.reason) {
+			t.Errorf("%s: expected the reason %s, got %s", tt.name, tt.reason, w.Body.String())
+		}
+		if tt.code == http.StatusForbidden && (ps.UpdateProductInvoked || ps.DeleteProductInvoked || cs.AssignProductInvoked) {
+			t.Errorf("%s: expected the service not to be invoked", tt.name)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// GetPriceLists retrieves all price lists.
+func (h *Handler) GetPriceLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.PriceService.PriceLists(r.Context())
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, lists)
+}
+
+// GetPriceList retrieves a single price list.
+func (h *Handler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	pl, err := h.PriceService.PriceList(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, pl)
+}
+
+// AddPriceList adds a price list.
+func (h *Handler) AddPriceList(w http.ResponseWriter, r *http.Request) {
+	pl := &catalog.PriceList{}
+	if err := json.NewDecoder(r.Body).Decode(pl); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddPriceList: %v", err))
+		return
+	}
+	if err := h.PriceService.CreatePriceList(r.Context(), pl); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, pl)
+}
+
+// GetPrices retrieves the prices of a product. The "variant", "priceList",
+// "currency" and "at" (RFC 3339 timestamp) query parameters filter the prices.
+func (h *Handler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := catalog.PriceQuery{
+		VariantID:   v.Get("variant"),
+		PriceListID: v.Get("priceList"),
+		Currency:    v.Get("currency"),
+	}
+	if at := v.Get("at"); at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			respondWithBadRequest(w, r, catalog.ValidationError("invalid query parameters",
+				[]catalog.FieldError{{Field: "at", Message: "must be an RFC 3339 timestamp"}}))
+			return
+		}
+		q.At = &t
+	}
+	product, err := h.routeProduct(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	prices, err := h.PriceService.Prices(r.Context(), product.ID, q)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, prices)
+}
+
+// AddPrice adds a price to a product.
+func (h *Handler) AddPrice(w http.ResponseWriter, r *http.Request) {
+	price := &catalog.Price{}
+	if err := json.NewDecoder(r.Body).Decode(price); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddPrice: %v", err))
+		return
+	}
+	product, err := h.routeProduct(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	price.ProductID = product.ID
+	if !h.checkPriceVariant(w, r, price) {
+		return
+	}
+	if err := h.PriceService.CreatePrice(r.Context(), price); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, price)
+}
+
+// UpdatePrice replaces a price of a product.
+func (h *Handler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
+	price := &catalog.Price{}
+	if err := json.NewDecoder(r.Body).Decode(price); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdatePrice: %v", err))
+		return
+	}
+	product, err := h.routeProduct(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	price.ProductID = product.ID
+	price.ID = mux.Vars(r)["priceId"]
+	if !h.checkPriceVariant(w, r, price) {
+		return
+	}
+	if err := h.PriceService.UpdatePrice(r.Context(), price); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, price)
+}
+
+// checkPriceVariant checks that the variant a price is for, if any, is a
+// variant of the product of the price; the foreign key only checks that
+// the variant exists. It responds 400 for a variant of another product or
+// one that does not exist, and returns false.
+func (h *Handler) checkPriceVariant(w http.ResponseWriter, r *http.Request, price *catalog.Price) bool {
+	if price.VariantID == "" {
+		return true
+	}
+	v, err := h.VariantService.Variant(r.Context(), price.VariantID)
+	if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+		respondWithServiceError(w, r, err)
+		return false
+	}
+	if err != nil || v.ProductID != price.ProductID {
+		respondWithBadRequest(w, r, catalog.ValidationError("price is invalid",
+			[]catalog.FieldError{{Field: "variantId", Message: "must be a variant of product " + price.ProductID}}))
+		return false
+	}
+	return true
+}
+
+// DeletePrice deletes a price of a product.
+func (h *Handler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	product, err := h.routeProduct(r)
+	var price *catalog.Price
+	if err == nil {
+		price, err = h.PriceService.Price(r.Context(), vars["priceId"])
+	}
+	if err == nil && price.ProductID != product.ID {
+		err = catalog.Errorf(catalog.ErrNotFound, "price %v not found", vars["priceId"])
+	}
+	if err == nil {
+		err = h.PriceService.DeletePrice(r.Context(), price.ID)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
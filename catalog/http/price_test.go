@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetPrices(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.PriceService
+	h.PriceService = &ps
+	h.ProductService = routeProductService("100")
+
+	ps.PricesFn = func(ctx context.Context, productID string, q catalog.PriceQuery) ([]*catalog.Price, error) {
+		if productID != "100" || q.Currency != "USD" || q.At == nil || !q.At.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected query: %v %+v", productID, q)
+		}
+		return []*catalog.Price{{ID: "1", ProductID: "100", PriceListID: "1", Currency: "USD", ListAmount: 1999}}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100/prices?currency=USD&at=2026-01-02T00:00:00Z", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if !ps.PricesInvoked {
+		t.Fatal("expected Prices() to be invoked.")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetPricesBadTimestamp(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.PriceService
+	h.PriceService = &ps
+	h.ProductService = routeProductService("100")
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100/prices?at=yesterday", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if ps.PricesInvoked {
+		t.Fatal("expected Prices() not to be invoked.")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_AddPrice(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.PriceService
+	h.PriceService = &ps
+	h.ProductService = routeProductService("100")
+
+	ps.CreatePriceFn = func(ctx context.Context, p *catalog.Price) error {
+		if p.ProductID != "100" || p.ListAmount != 1999 || p.SaleAmount == nil || *p.SaleAmount != 1499 {
+			t.Fatalf("unexpected price: %+v", p)
+		}
+		p.ID = "1"
+		return nil
+	}
+
+	payload := []byte(`{ "priceListId": "1", "currency": "USD", "listAmount": 1999, "saleAmount": 1499 }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100/prices", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_DeletePriceOfOtherProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.PriceService
+	h.PriceService = &ps
+	h.ProductService = routeProductService("100")
+
+	ps.PriceFn = func(ctx context.Context, id string) (*catalog.Price, error) {
+		return &catalog.Price{ID: id, ProductID: "200"}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("DELETE", "/product/100/prices/1", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if ps.DeletePriceInvoked {
+		t.Fatal("expected DeletePrice() not to be invoked.")
+	}
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_AddPriceOfMissingProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.PriceService
+	h.PriceService = &ps
+	var products mock.ProductService
+	h.ProductService = &products
+
+	products.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
+	}
+
+	payload := []byte(`{ "priceListId": "1", "currency": "USD", "listAmount": 1999 }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100/prices", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if ps.CreatePriceInvoked {
+		t.Fatal("expected CreatePrice() not to be invoked.")
+	}
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+}
+
+// routeProductService returns a mock serving the published product with
+// the ID, as the parent of nested resources.
+func routeProductService(id string) *mock.ProductService {
+	var ps mock.ProductService
+	ps.ProductFn = func(ctx context.Context, productID string) (*catalog.Product, error) {
+		if productID != id {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", productID)
+		}
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+	return &ps
+}
+
+func TestHandler_AddPriceOfOtherVariant(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.PriceService
+	var vs mock.VariantService
+	h.PriceService = &ps
+	h.VariantService = &vs
+	h.ProductService = routeProductService("100")
+
+	vs.VariantFn = func(ctx context.Context, id string) (*catalog.Variant, error) {
+		if id != "7" {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "variant %v not found", id)
+		}
+		return &catalog.Variant{ID: id, ProductID: "200"}, nil
+	}
+
+	for _, variant := range []string{"7", "8"} {
+		payload := []byte(`{ "variantId": "` + variant + `", "priceListId": "1", "currency": "USD", "listAmount": 1999 }`)
+
+		// Invoke the handler.
+		w := httptest.NewRecorder()
+		r := newRequest("POST", "/product/100/prices", bytes.NewBuffer(payload))
+		h.Router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("variant %s: expected 400 status code, got %d", variant, w.Code)
+		}
+		if !bytes.Contains(w.Body.Bytes(), []byte(`"variantId"`)) {
+			t.Fatalf("variant %s: expected a variantId field error: %s", variant, w.Body.String())
+		}
+	}
+	if ps.CreatePriceInvoked {
+		t.Fatal("expected CreatePrice() not to be invoked.")
+	}
+}
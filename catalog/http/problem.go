@@ -0,0 +1,147 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// problemBase prefixes the problem type URIs returned by the API.
+const problemBase = "https://catalog-api.endpoints.demogeauxcommerce.cloud.goog/problems/"
+
+// Problem types returned by the API.
+const (
+	ProblemBadRequest    = problemBase + "bad-request"
+	ProblemUnauthorized  = problemBase + "unauthorized"
+	ProblemForbidden     = problemBase + "forbidden"
+	ProblemNotFound      = problemBase + "not-found"
+	ProblemConflict      = problemBase + "conflict"
+	ProblemPrecondition  = problemBase + "precondition-failed"
+	ProblemValidation    = problemBase + "validation"
+	ProblemRateLimited   = problemBase + "rate-limited"
+	ProblemQuotaExceeded = problemBase + "quota-exceeded"
+	ProblemInternal      = problemBase + "internal"
+)
+
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Detail   string               `json:"detail,omitempty"`
+	Instance string               `json:"instance,omitempty"`
+	TraceID  string               `json:"traceId,omitempty"`
+	Errors   []catalog.FieldError `json:"errors,omitempty"`
+}
+
+// problemTypes maps the status codes the API returns to a problem type.
+var problemTypes = map[int]string{
+	http.StatusBadRequest:          ProblemBadRequest,
+	http.StatusUnauthorized:        ProblemUnauthorized,
+	http.StatusForbidden:           ProblemForbidden,
+	http.StatusNotFound:            ProblemNotFound,
+	http.StatusConflict:            ProblemConflict,
+	http.StatusPreconditionFailed:  ProblemPrecondition,
+	http.StatusUnprocessableEntity: ProblemValidation,
+	http.StatusTooManyRequests:     ProblemRateLimited,
+	http.StatusInternalServerError: ProblemInternal,
+}
+
+// newProblem returns a Problem for the status code and request.
+func newProblem(r *http.Request, code int, detail string) *Problem {
+	typ, ok := problemTypes[code]
+	if !ok {
+		typ = "about:blank"
+	}
+	return &Problem{
+		Type:     typ,
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		TraceID:  traceID(r),
+	}
+}
+
+// traceID identifies the request in logs and traces. It prefers a caller
+// supplied X-Request-Id and falls back to the OpenCensus trace id.
+func traceID(r *http.Request) string {
+	if id := r.Header.Get("X-Request-Id"); id != "" {
+		return id
+	}
+	if span := trace.FromContext(r.Context()); span != nil {
+		return span.SpanContext().TraceID.String()
+	}
+	return ""
+}
+
+// respondWithProblem writes p as an application/problem+json response.
+func respondWithProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	response, err := json.Marshal(p)
+	if err != nil {
+		log.WithField("httpRequest", r).
+			Errorf("Error marshalling problem: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(p.Status)
+	w.Write(response)
+}
+
+// respondWithError writes a problem response with the status code and detail message.
+func respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	respondWithProblem(w, r, newProblem(r, code, message))
+}
+
+// respondWithServiceError maps an error returned by a catalog service to
+// a problem response. Errors that are not catalog domain errors are logged
+// and reported as a 500 without exposing their details.
+func respondWithServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	code := statusForError(err)
+	if code == http.StatusInternalServerError {
+		log.WithField("httpRequest", r).
+			Errorf("Error handling request: %v", err)
+		respondWithError(w, r, code, "An internal error occurred.")
+		return
+	}
+	p := newProblem(r, code, err.Error())
+	if errors.Is(err, catalog.ErrQuotaExceeded) {
+		p.Type = ProblemQuotaExceeded
+	}
+	p.Errors = fieldErrors(err)
+	respondWithProblem(w, r, p)
+}
+
+// respondWithBadRequest writes a 400 problem response for a malformed
+// request, listing the failed fields when err carries them.
+func respondWithBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	p := newProblem(r, http.StatusBadRequest, err.Error())
+	p.Errors = fieldErrors(err)
+	respondWithProblem(w, r, p)
+}
+
+// fieldErrors returns the field errors carried by a *catalog.Error.
+func fieldErrors(err error) []catalog.FieldError {
+	var cErr *catalog.Error
+	if errors.As(err, &cErr) {
+		return cErr.Fields
+	}
+	return nil
+}
+
+// statusForError returns the HTTP status for a catalog error kind.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, catalog.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, catalog.ErrConflict), errors.Is(err, catalog.ErrVersionConflict),
+		errors.Is(err, catalog.ErrQuotaExceeded):
+		return http.StatusConflict
+	case errors.Is(err, catalog.ErrInvalid):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/ratelimit"
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimiter limits how often every client may call the API, so that a
+// runaway client cannot overload the database.
+type RateLimiter struct {
+	Store ratelimit.Store
+	// Address limits the requests from every client address before they
+	// are authenticated, so that guessing tokens and API keys, each
+	// costing a lookup, is limited too.
+	Address ratelimit.Limit
+	// Read limits the GET and HEAD requests of an authenticated client
+	// and Write all others.
+	Read  ratelimit.Limit
+	Write ratelimit.Limit
+}
+
+// limitAddress takes a token from the bucket of the address of the
+// client, before the request is authenticated. See take.
+func (h *Handler) limitAddress(w http.ResponseWriter, r *http.Request) bool {
+	if h.RateLimiter == nil {
+		return true
+	}
+	return h.take(w, r, "addr:"+clientAddress(r), h.RateLimiter.Address)
+}
+
+// limit takes a token from the bucket of the authenticated caller for the
+// request, its read bucket for GET and HEAD requests and its write bucket
+// for the others. See take.
+func (h *Handler) limit(w http.ResponseWriter, r *http.Request) bool {
+	if h.RateLimiter == nil {
+		return true
+	}
+	class, l := "write", h.RateLimiter.Write
+	if r.Method == "GET" || r.Method == "HEAD" {
+		class, l = "read", h.RateLimiter.Read
+	}
+	return h.take(w, r, class+":"+clientKey(r), l)
+}
+
+// take takes a token from the bucket of the key for the limit and sets the
+// RateLimit headers. It responds 429 with a Retry-After header when the
+// bucket is empty, and returns false. A zero limit does not limit. Errors
+// of the store let the request through, since the limiter must not take
+// the API down with it.
+func (h *Handler) take(w http.ResponseWriter, r *http.Request, key string, l ratelimit.Limit) bool {
+	if l.Unlimited() {
+		return true
+	}
+	res, err := h.RateLimiter.Store.Take(r.Context(), key, l)
+	if err != nil {
+		log.Errorf("Error rate limiting %s: %v", key, err)
+		return true
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", l.Burst, ceilSeconds(l.Period)))
+	if res.Allowed {
+		return true
+	}
+	log.Debugf("Rate limited %s for %v", key, res.RetryAfter)
+	w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+	respondWithError(w, r, http.StatusTooManyRequests,
+		fmt.Sprintf("Too many requests: limited to %s, retry in %s seconds", l, ceilSeconds(res.RetryAfter)))
+	return false
+}
+
+// clientKey identifies the caller of an authenticated request: the API
+// key, the subject of the token, or else the address of the client.
+func clientKey(r *http.Request) string {
+	if claims := auth.ClaimsFromContext(r.Context()); claims != nil && claims.Subject != "" {
+		if r.Header.Get(apiKeyHeader) != "" {
+			// The subject is apikey:<id>.
+			return claims.Subject
+		}
+		return "sub:" + claims.Subject
+	}
+	return "ip:" + clientAddress(r)
+}
+
+// clientAddress returns the IP address the request came from.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats the duration in whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
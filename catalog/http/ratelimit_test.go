@@ -0,0 +1,147 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"github.com/mvonbodun/go-package-test/catalog/ratelimit"
+	"golang.org/x/net/context"
+)
+
+// failingStore is a rate limit store that cannot be reached.
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, l ratelimit.Limit) (*ratelimit.Result, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+	h.RateLimiter = &RateLimiter{
+		Store: ratelimit.NewMemoryStore(),
+		Read:  ratelimit.Limit{Burst: 2, Period: time.Minute},
+		Write: ratelimit.Limit{Burst: 1, Period: time.Minute},
+	}
+	defer func() { h.RateLimiter = nil }()
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+	ps.CreateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		product.ID = "15"
+		return nil
+	}
+
+	get := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := newRequest("GET", "/product/100", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.Router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(testToken)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" ||
+		w.Header().Get("RateLimit-Remaining") != "1" || w.Header().Get("RateLimit-Reset") != "30" ||
+		w.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	get(testToken)
+	w = get(testToken)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" ||
+		w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("expected 429, got %d %v", w.Code, w.Header())
+	}
+	if !strings.Contains(w.Body.String(), ProblemRateLimited) {
+		t.Errorf("expected a rate-limited problem, got %s", w.Body.String())
+	}
+
+	// Other clients have their own buckets.
+	if w := get(adminToken); w.Code != http.StatusOK {
+		t.Errorf("expected admin to be allowed, got %d", w.Code)
+	}
+
+	// Writes are limited apart from reads.
+	w = httptest.NewRecorder()
+	r := newRequest("POST", "/product", bytes.NewBufferString(`{"productCode": "prod15"}`))
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+
+	// A store that fails lets the requests through.
+	h.RateLimiter.Store = failingStore{}
+	if w := get(testToken); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("expected to be let through, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestHandler_RateLimitAddress(t *testing.T) {
+	// Inject our mock into our handler.
+	var ks mock.APIKeyService
+	h.APIKeyService = &ks
+	h.RateLimiter = &RateLimiter{
+		Store:   ratelimit.NewMemoryStore(),
+		Address: ratelimit.Limit{Burst: 2, Period: time.Minute},
+	}
+	defer func() { h.RateLimiter = nil }()
+
+	lookups := 0
+	ks.APIKeyByHashFn = func(ctx context.Context, hash string) (*catalog.APIKey, error) {
+		lookups++
+		return nil, catalog.Errorf(catalog.ErrNotFound, "API key not found")
+	}
+
+	// Guessing API keys is limited before the keys are looked up.
+	codes := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, code := range codes {
+		w := httptest.NewRecorder()
+		r := newRequest("GET", "/product/100", nil)
+		r.RemoteAddr = "192.0.2.7:4711"
+		r.Header.Del("Authorization")
+		r.Header.Set("X-API-Key", "ck_guess")
+		h.Router.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("guess %d: expected %d, got %d %s", i, code, w.Code, w.Body.String())
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("expected 2 lookups, got %d", lookups)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		key    string
+		want   string
+	}{
+		{"token", &auth.Claims{Subject: "alice"}, "", "sub:alice"},
+		{"API key", &auth.Claims{Subject: "apikey:7"}, "ck_scanner", "apikey:7"},
+		{"token without subject", &auth.Claims{}, "", "ip:192.0.2.1"},
+		{"anonymous", nil, "", "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/products", nil)
+		if tt.claims != nil {
+			r = r.WithContext(auth.WithClaims(r.Context(), tt.claims))
+		}
+		if tt.key != "" {
+			r.Header.Set("X-API-Key", tt.key)
+		}
+		if got := clientKey(r); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+)
+
+// DefaultTenantClaim is the token claim naming the tenant of the caller
+// when the handler names no other.
+const DefaultTenantClaim = "tenant"
+
+// tenantHeader names the tenant an operator of the deployment acts for.
+const tenantHeader = "X-Tenant-ID"
+
+// tenantAdminScope lets the operators provision tenants and act for any
+// of them with the X-Tenant-ID header.
+const tenantAdminScope = "admin:tenant"
+
+// tenantClaim returns the token claim naming the tenant of the caller.
+func (h *Handler) tenantClaim() string {
+	if h.TenantClaim == "" {
+		return DefaultTenantClaim
+	}
+	return h.TenantClaim
+}
+
+// resolveTenant returns the request acting for the tenant named by the
+// tenant claim of the caller, or else for the default tenant. Only
+// callers with the admin:tenant scope may name another tenant with the
+// X-Tenant-ID header. It responds 403 when others do, 400 for an invalid
+// tenant, and returns nil.
+func (h *Handler) resolveTenant(w http.ResponseWriter, r *http.Request) *http.Request {
+	var tenant string
+	if claims := auth.ClaimsFromContext(r.Context()); claims != nil {
+		tenant, _ = claims.Raw[h.tenantClaim()].(string)
+	}
+	if header := r.Header.Get(tenantHeader); header != "" && header != tenant {
+		if !requestHasScope(r, tenantAdminScope) {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", scope=%q`, realm, tenantAdminScope))
+			respondWithError(w, r, http.StatusForbidden,
+				fmt.Sprintf("Forbidden: %s needed to act for tenant %s", tenantAdminScope, header))
+			return nil
+		}
+		tenant = header
+	}
+	if tenant == "" {
+		tenant = catalog.DefaultTenant
+	}
+	if !catalog.ValidTenantID(tenant) {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid tenant %q", tenant))
+		return nil
+	}
+	return r.WithContext(catalog.WithTenant(r.Context(), tenant))
+}
+
+// GetTenants retrieves all tenants with their product counts.
+func (h *Handler) GetTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.TenantService.Tenants(r.Context())
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, tenants)
+}
+
+// GetTenant retrieves a single tenant.
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, err := h.TenantService.Tenant(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, t)
+}
+
+// AddTenant provisions a tenant with an ID, a name and a product quota.
+func (h *Handler) AddTenant(w http.ResponseWriter, r *http.Request) {
+	t := &catalog.Tenant{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddTenant: %v", err))
+		return
+	}
+	if err := h.TenantService.CreateTenant(r.Context(), t); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, t)
+}
+
+// UpdateTenant replaces the name and product quota of a tenant.
+func (h *Handler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	t := &catalog.Tenant{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdateTenant: %v", err))
+		return
+	}
+	t.ID = mux.Vars(r)["id"]
+	if err := h.TenantService.UpdateTenant(r.Context(), t); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	// Read the tenant back for its product count.
+	t, err := h.TenantService.Tenant(r.Context(), t.ID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, t)
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/auth"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_TenantResolution(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var ks mock.APIKeyService
+	h.ProductService = &ps
+	h.APIKeyService = &ks
+
+	var tenant string
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		tenant = catalog.TenantFromContext(ctx)
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+	ks.APIKeyByHashFn = func(ctx context.Context, hash string) (*catalog.APIKey, error) {
+		return &catalog.APIKey{ID: "1", TenantID: "acme", Scopes: []string{"read:product"}}, nil
+	}
+	ks.TouchAPIKeyFn = func(ctx context.Context, id string, t time.Time) error {
+		return nil
+	}
+	acmeToken := signToken(jwt.MapClaims{"sub": "alice", "scope": "read:product", "tenant": "acme"})
+
+	tests := []struct {
+		name   string
+		token  string
+		key    string
+		header string
+		code   int
+		tenant string
+	}{
+		{"claim", acmeToken, "", "", http.StatusOK, "acme"},
+		{"claim and same header", acmeToken, "", "acme", http.StatusOK, "acme"},
+		{"claim and other header", acmeToken, "", "globex", http.StatusForbidden, ""},
+		{"header", testToken, "", "globex", http.StatusForbidden, ""},
+		{"operator header", operatorToken, "", "globex", http.StatusOK, "globex"},
+		{"neither", testToken, "", "", http.StatusOK, catalog.DefaultTenant},
+		{"invalid header", operatorToken, "", "Globex Inc", http.StatusBadRequest, ""},
+		{"API key", "", "ck_scanner", "", http.StatusOK, "acme"},
+		{"API key and other header", "", "ck_scanner", "globex", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		tenant = ""
+		w := httptest.NewRecorder()
+		r := newRequest("GET", "/product/100", nil)
+		r.Header.Del("Authorization")
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.key != "" {
+			r.Header.Set("X-API-Key", tt.key)
+		}
+		if tt.header != "" {
+			r.Header.Set("X-Tenant-ID", tt.header)
+		}
+		h.Router.ServeHTTP(w, r)
+		if w.Code != tt.code || tenant != tt.tenant {
+			t.Errorf("%s: unexpected response %d %s for tenant %q", tt.name, w.Code, w.Body.String(), tenant)
+		}
+	}
+}
+
+func TestHandler_AddProductQuotaExceeded(t *testing.T) {
+	// Inject our mock into our handler.
+	var ps mock.ProductService
+	h.ProductService = &ps
+
+	ps.CreateProductFn = func(ctx context.Context, product *catalog.Product) error {
+		return catalog.Errorf(catalog.ErrQuotaExceeded, "tenant %v has reached its quota of %d products",
+			catalog.TenantFromContext(ctx), 10)
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product", bytes.NewBufferString(`{"productCode": "prod15"}`))
+	r.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{"sub": "alice", "scope": "write:product", "tenant": "acme"}))
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "tenant acme has reached its quota") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	// The quota is told apart from other conflicts by the problem type.
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Type != ProblemQuotaExceeded {
+		t.Errorf("expected a quota-exceeded problem, got %+v %v", p, err)
+	}
+}
+
+func TestHandler_AddTenant(t *testing.T) {
+	// Inject our mock into our handler.
+	var ts mock.TenantService
+	h.TenantService = &ts
+
+	ts.CreateTenantFn = func(ctx context.Context, tenant *catalog.Tenant) error {
+		if tenant.ID != "acme" || tenant.MaxProducts != 100 {
+			t.Fatalf("unexpected tenant: %+v", tenant)
+		}
+		return nil
+	}
+	payload := `{"id":"acme","name":"Acme","maxProducts":100}`
+
+	// Provisioning tenants takes the admin:tenant scope.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/tenants", strings.NewReader(payload))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden || ts.CreateTenantInvoked {
+		t.Fatalf("expected 403 without the admin:tenant scope, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("POST", "/tenants", strings.NewReader(payload))
+	r.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{"sub": "operator", "scope": "admin:tenant"}))
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	var tenant catalog.Tenant
+	if err := json.Unmarshal(w.Body.Bytes(), &tenant); err != nil || tenant.ID != "acme" {
+		t.Errorf("unexpected tenant %+v, %v", tenant, err)
+	}
+}
+
+func TestHandler_UpdateTenant(t *testing.T) {
+	// Inject our mock into our handler.
+	var ts mock.TenantService
+	h.TenantService = &ts
+
+	ts.UpdateTenantFn = func(ctx context.Context, tenant *catalog.Tenant) error {
+		if tenant.ID != "acme" || tenant.MaxProducts != 50 {
+			t.Fatalf("unexpected tenant: %+v", tenant)
+		}
+		return nil
+	}
+	ts.TenantFn = func(ctx context.Context, id string) (*catalog.Tenant, error) {
+		return &catalog.Tenant{ID: id, Name: "Acme", MaxProducts: 50, Products: 42}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("PUT", "/tenants/acme", strings.NewReader(`{"name":"Acme","maxProducts":50}`))
+	r.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{"sub": "operator", "scope": "admin:tenant"}))
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"products":42`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_AddAPIKeyTenant(t *testing.T) {
+	// Inject our mock into our handler.
+	var ks mock.APIKeyService
+	h.APIKeyService = &ks
+
+	ks.CreateAPIKeyFn = func(ctx context.Context, k *catalog.APIKey, hash string) error {
+		k.ID = "4"
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/apikeys", strings.NewReader(`{"name":"scanner","scopes":["read:product"]}`))
+	r.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{"sub": "admin", "scope": "read:product admin:product", "tenant": "acme"}))
+	h.Router.ServeHTTP(w, r)
+	var k catalog.APIKey
+	if err := json.Unmarshal(w.Body.Bytes(), &k); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	// The key acts for the tenant it was issued in.
+	if k.TenantID != "acme" || auth.APIKeyPrefix(k.Key) != k.Prefix {
+		t.Errorf("unexpected API key: %+v", k)
+	}
+}
+
+func TestHandler_NestedResourcesOfOtherTenant(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var prices mock.PriceService
+	var vs mock.VariantService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.PriceService = &prices
+	h.VariantService = &vs
+	h.CategoryService = &cs
+
+	// Product 100 belongs to acme; the prices, variants and category
+	// assignments are looked up by ID alone.
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		if catalog.TenantFromContext(ctx) != "acme" {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
+		}
+		return &catalog.Product{ID: id, Status: catalog.StatusActive}, nil
+	}
+	prices.PriceFn = func(ctx context.Context, id string) (*catalog.Price, error) {
+		return &catalog.Price{ID: id, ProductID: "100"}, nil
+	}
+	vs.VariantFn = func(ctx context.Context, id string) (*catalog.Variant, error) {
+		return &catalog.Variant{ID: id, ProductID: "100"}, nil
+	}
+	globexToken := signToken(jwt.MapClaims{"sub": "bob", "scope": "read:product write:product", "tenant": "globex"})
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/product/100/prices", ""},
+		{"POST", "/product/100/prices", `{ "priceListId": "1", "currency": "USD", "listAmount": 1999 }`},
+		{"PUT", "/product/100/prices/1", `{ "priceListId": "1", "currency": "USD", "listAmount": 1999 }`},
+		{"DELETE", "/product/100/prices/1", ""},
+		{"GET", "/product/100/variants", ""},
+		{"POST", "/product/100/variants", `{ "sku": "TEE-M-RED" }`},
+		{"GET", "/product/100/variants/1", ""},
+		{"PUT", "/product/100/variants/1", `{ "sku": "TEE-M-RED" }`},
+		{"DELETE", "/product/100/variants/1", ""},
+		{"GET", "/product/100/categories", ""},
+		{"PUT", "/category/7/products/100", ""},
+		{"DELETE", "/category/7/products/100", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := newRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		r.Header.Set("Authorization", "Bearer "+globexToken)
+		h.Router.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404 status code, got %d", tt.method, tt.path, w.Code)
+		}
+	}
+	if prices.PricesInvoked || prices.CreatePriceInvoked || prices.UpdatePriceInvoked || prices.DeletePriceInvoked {
+		t.Fatal("expected no prices of the other tenant to be read or written.")
+	}
+	if vs.VariantsInvoked || vs.CreateVariantInvoked || vs.UpdateVariantInvoked || vs.DeleteVariantInvoked {
+		t.Fatal("expected no variants of the other tenant to be read or written.")
+	}
+	if cs.ProductCategoriesInvoked || cs.AssignProductInvoked || cs.UnassignProductInvoked {
+		t.Fatal("expected no categories of products of the other tenant to be read or changed.")
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// GetVariants retrieves the variants of a product.
+func (h *Handler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	product, err := h.routeProduct(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	variants, err := h.VariantService.Variants(r.Context(), product.ID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, variants)
+}
+
+// GetVariant retrieves a single variant of a product.
+func (h *Handler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	v, err := h.productVariant(r)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, v)
+}
+
+// AddVariant adds a variant to a product.
+func (h *Handler) AddVariant(w http.ResponseWriter, r *http.Request) {
+	v := &catalog.Variant{}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddVariant: %v", err))
+		return
+	}
+	product, err := h.routeProduct(r)
+	if err == nil {
+		v.ProductID = product.ID
+		err = h.VariantService.CreateVariant(r.Context(), v)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusCreated, v)
+}
+
+// UpdateVariant replaces a variant of a product.
+func (h *Handler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	v := &catalog.Variant{}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during UpdateVariant: %v", err))
+		return
+	}
+	product, err := h.routeProduct(r)
+	if err == nil {
+		v.ProductID = product.ID
+		v.ID = mux.Vars(r)["variantId"]
+		err = h.VariantService.UpdateVariant(r.Context(), v)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, v)
+}
+
+// DeleteVariant deletes a variant of a product.
+func (h *Handler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	v, err := h.productVariant(r)
+	if err == nil {
+		err = h.VariantService.DeleteVariant(r.Context(), v.ID)
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// productVariant loads the variant named in the route, checking that it
+// belongs to the product in the route, which is loaded first so that the
+// variants of other tenants are not found.
+func (h *Handler) productVariant(r *http.Request) (*catalog.Variant, error) {
+	product, err := h.routeProduct(r)
+	if err != nil {
+		return nil, err
+	}
+	vars := mux.Vars(r)
+	v, err := h.VariantService.Variant(r.Context(), vars["variantId"])
+	if err != nil {
+		return nil, err
+	}
+	if v.ProductID != product.ID {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "variant %v not found", vars["variantId"])
+	}
+	return v, nil
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_GetProductEmbedVariants(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var vs mock.VariantService
+	h.ProductService = &ps
+	h.VariantService = &vs
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Status: catalog.StatusActive}, nil
+	}
+	vs.VariantsFn = func(ctx context.Context, productID string) ([]*catalog.Variant, error) {
+		return []*catalog.Variant{{ID: "1", ProductID: productID, SKU: "TEE-M", Options: map[string]string{"size": "M"}}}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100?embed=variants", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if !vs.VariantsInvoked {
+		t.Fatal("expected Variants() to be invoked.")
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"sku":"TEE-M"`)) {
+		t.Fatalf("expected embedded variants: %s", w.Body.String())
+	}
+}
+
+func TestHandler_GetProductWithoutEmbed(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var vs mock.VariantService
+	h.ProductService = &ps
+	h.VariantService = &vs
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, ProductCode: "tee", Status: catalog.StatusActive}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if vs.VariantsInvoked {
+		t.Fatal("expected Variants() not to be invoked.")
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte(`"variants"`)) {
+		t.Fatalf("expected no variants: %s", w.Body.String())
+	}
+}
+
+func TestHandler_AddVariant(t *testing.T) {
+	// Inject our mock into our handler.
+	var vs mock.VariantService
+	h.VariantService = &vs
+	h.ProductService = routeProductService("100")
+
+	vs.CreateVariantFn = func(ctx context.Context, v *catalog.Variant) error {
+		if v.ProductID != "100" || v.Options["color"] != "red" {
+			t.Fatalf("unexpected variant: %+v", v)
+		}
+		v.ID = "1"
+		return nil
+	}
+
+	payload := []byte(`{ "sku": "TEE-M-RED", "gtin": "4006381333931", "options": { "size": "M", "color": "red" } }`)
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/product/100/variants", bytes.NewBuffer(payload))
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_GetVariantOfOtherProduct(t *testing.T) {
+	// Inject our mock into our handler.
+	var vs mock.VariantService
+	h.VariantService = &vs
+	h.ProductService = routeProductService("100")
+
+	vs.VariantFn = func(ctx context.Context, id string) (*catalog.Variant, error) {
+		return &catalog.Variant{ID: id, ProductID: "200"}, nil
+	}
+
+	// Invoke the handler.
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/product/100/variants/1", nil)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 status code, got %d", w.Code)
+	}
+}
+
+func TestHandler_NestedResourcesOfUnpublishedProduct(t *testing.T) {
+	// Inject our mocks into our handler.
+	var ps mock.ProductService
+	var prices mock.PriceService
+	var vs mock.VariantService
+	var cs mock.CategoryService
+	h.ProductService = &ps
+	h.PriceService = &prices
+	h.VariantService = &vs
+	h.CategoryService = &cs
+
+	ps.ProductFn = func(ctx context.Context, id string) (*catalog.Product, error) {
+		return &catalog.Product{ID: id, Status: catalog.StatusDraft}, nil
+	}
+	prices.PricesFn = func(ctx context.Context, productID string, q catalog.PriceQuery) ([]*catalog.Price, error) {
+		return []*catalog.Price{}, nil
+	}
+	vs.VariantsFn = func(ctx context.Context, productID string) ([]*catalog.Variant, error) {
+		return []*catalog.Variant{}, nil
+	}
+	vs.VariantFn = func(ctx context.Context, id string) (*catalog.Variant, error) {
+		return &catalog.Variant{ID: id, ProductID: "100"}, nil
+	}
+	cs.ProductCategoriesFn = func(ctx context.Context, productID string) ([]*catalog.Category, error) {
+		return []*catalog.Category{}, nil
+	}
+
+	for _, path := range []string{"/product/100/prices", "/product/100/variants", "/product/100/variants/1", "/product/100/categories"} {
+		// Invoke the handler as a reader and as an admin.
+		w := httptest.NewRecorder()
+		h.Router.ServeHTTP(w, newRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404 status code, got %d", path, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		r := newRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer "+adminToken)
+		h.Router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 status code for admins, got %d", path, w.Code)
+		}
+	}
+}
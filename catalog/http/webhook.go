@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// GetWebhooks retrieves all webhooks.
+func (h *Handler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.WebhookService.Webhooks(r.Context())
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, webhooks)
+}
+
+// GetWebhook retrieves a single webhook.
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, err := h.WebhookService.Webhook(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, wh)
+}
+
+// AddWebhook subscribes a webhook to product events. The secret is
+// accepted but never returned.
+func (h *Handler) AddWebhook(w http.ResponseWriter, r *http.Request) {
+	wh := &catalog.Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(wh); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, fmt.Sprintf("Error decoding Json during AddWebhook: %v", err))
+		return
+	}
+	if err := h.WebhookService.CreateWebhook(r.Context(), wh); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	wh.Secret = ""
+	respondWithJson(w, r, http.StatusCreated, wh)
+}
+
+// DeleteWebhook unsubscribes a webhook and drops its deliveries.
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.WebhookService.DeleteWebhook(r.Context(), mux.Vars(r)["id"]); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// GetWebhookDeliveries retrieves a page of the deliveries of a webhook,
+// newest first. The "status" query parameter keeps only the deliveries in
+// that state, e.g. status=dead for the dead letters.
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	var fields []catalog.FieldError
+	limit, err := intParam(v.Get("limit"), 1, catalog.MaxPageSize)
+	if err != nil {
+		fields = append(fields, catalog.FieldError{Field: "limit", Message: err.Error()})
+	}
+	status := catalog.DeliveryStatus(v.Get("status"))
+	if status != "" && !status.Valid() {
+		fields = append(fields, catalog.FieldError{Field: "status", Message: "must be pending, failed, succeeded or dead"})
+	}
+	if err := catalog.ValidationError("invalid query parameters", fields); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	page, err := h.WebhookService.Deliveries(r.Context(), mux.Vars(r)["id"], status,
+		catalog.Page{Cursor: v.Get("cursor"), Limit: limit})
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, page)
+}
+
+// GetWebhookDelivery retrieves a single delivery of a webhook.
+func (h *Handler) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	d, err := h.WebhookService.Delivery(r.Context(), vars["id"], vars["deliveryId"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusOK, d)
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again right away,
+// whatever its state, with a fresh set of attempts.
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	d, err := h.WebhookService.ReplayDelivery(r.Context(), vars["id"], vars["deliveryId"])
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	respondWithJson(w, r, http.StatusAccepted, d)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestHandler_AddWebhook(t *testing.T) {
+	// Inject our mock into our handler.
+	var ws mock.WebhookService
+	h.WebhookService = &ws
+
+	ws.CreateWebhookFn = func(ctx context.Context, wh *catalog.Webhook) error {
+		if wh.URL != "https://partner.example.com/hooks" || wh.Secret != "0123456789abcdef" {
+			t.Fatalf("unexpected webhook: %+v", wh)
+		}
+		wh.ID = "3"
+		return nil
+	}
+
+	body := []byte(`{"url":"https://partner.example.com/hooks","eventTypes":["product.created"],"secret":"0123456789abcdef"}`)
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/webhooks", bytes.NewReader(body))
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden || ws.CreateWebhookInvoked {
+		t.Fatalf("expected 403 without the admin scope, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("POST", "/webhooks", bytes.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ws.CreateWebhookInvoked {
+		t.Fatal("expected CreateWebhook() to be invoked.")
+	}
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_AddWebhookInvalid(t *testing.T) {
+	// Inject our mock into our handler.
+	var ws mock.WebhookService
+	h.WebhookService = &ws
+
+	ws.CreateWebhookFn = func(ctx context.Context, wh *catalog.Webhook) error {
+		return wh.Validate()
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/webhooks", strings.NewReader(`{"url":"partner","secret":"short"}`))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"secret"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_GetWebhookDeliveries(t *testing.T) {
+	// Inject our mock into our handler.
+	var ws mock.WebhookService
+	h.WebhookService = &ws
+
+	ws.DeliveriesFn = func(ctx context.Context, webhookID string, status catalog.DeliveryStatus, page catalog.Page) (*catalog.DeliveryPage, error) {
+		if webhookID != "3" || status != catalog.DeliveryDead || page.Cursor != "abc" || page.Limit != 10 {
+			t.Fatalf("unexpected arguments: %v, %v, %+v", webhookID, status, page)
+		}
+		return &catalog.DeliveryPage{Deliveries: []*catalog.WebhookDelivery{
+			{ID: "8", WebhookID: "3", Status: catalog.DeliveryDead, Attempts: 8, Payload: json.RawMessage(`{"id":12}`)},
+		}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/webhooks/3/deliveries?status=dead&cursor=abc&limit=10", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ws.DeliveriesInvoked {
+		t.Fatal("expected Deliveries() to be invoked.")
+	}
+	var page catalog.DeliveryPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(page.Deliveries) != 1 || string(page.Deliveries[0].Payload) != `{"id":12}` {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_GetWebhookDeliveriesInvalidStatus(t *testing.T) {
+	// Inject our mock into our handler.
+	var ws mock.WebhookService
+	h.WebhookService = &ws
+
+	w := httptest.NewRecorder()
+	r := newRequest("GET", "/webhooks/3/deliveries?status=lost", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest || ws.DeliveriesInvoked {
+		t.Fatalf("expected 400 for an unknown status, got %d", w.Code)
+	}
+}
+
+func TestHandler_ReplayWebhookDelivery(t *testing.T) {
+	// Inject our mock into our handler.
+	var ws mock.WebhookService
+	h.WebhookService = &ws
+
+	ws.ReplayDeliveryFn = func(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error) {
+		if webhookID != "3" {
+			t.Fatalf("unexpected webhook: %v", webhookID)
+		}
+		if id != "8" {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "delivery %v of webhook 3 not found", id)
+		}
+		return &catalog.WebhookDelivery{ID: "8", WebhookID: "3", Status: catalog.DeliveryPending}, nil
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequest("POST", "/webhooks/3/deliveries/8:replay", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+
+	// Validate mock.
+	if !ws.ReplayDeliveryInvoked {
+		t.Fatal("expected ReplayDelivery() to be invoked.")
+	}
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), `"status":"pending"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r = newRequest("POST", "/webhooks/3/deliveries/9:replay", nil)
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	h.Router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown delivery, got %d", w.Code)
+	}
+}
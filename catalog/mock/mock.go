@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"time"
+
 	"github.com/mvonbodun/go-package-test/catalog"
 	"golang.org/x/net/context"
 )
@@ -9,32 +11,536 @@ type ProductService struct {
 	ProductFn 		func(ctx context.Context, id string) (*catalog.Product, error)
 	ProductInvoked bool
 
-	ProductsFn 		func(ctx context.Context) ([]*catalog.Product, error)
+	ProductByCodeFn      func(ctx context.Context, code string) (*catalog.Product, error)
+	ProductByCodeInvoked bool
+
+	ProductsFn 		func(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error)
 	ProductsInvoked bool
 
 	CreateProductFn func(ctx context.Context, product *catalog.Product) error
 	CreateProductInvoked bool
 
-	DeleteProductFn func(ctx context.Context, id string) error
+	UpdateProductFn func(ctx context.Context, product *catalog.Product) error
+	UpdateProductInvoked bool
+
+	PatchProductFn      func(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error)
+	PatchProductInvoked bool
+
+	DeleteProductFn func(ctx context.Context, id string, version int64) error
 	DeleteProductInvoked bool
+
+	RestoreProductFn      func(ctx context.Context, id string) (*catalog.Product, error)
+	RestoreProductInvoked bool
+
+	PurgeProductFn      func(ctx context.Context, id string) error
+	PurgeProductInvoked bool
+
+	PurgeDeletedProductsFn      func(ctx context.Context, before time.Time) (int64, error)
+	PurgeDeletedProductsInvoked bool
+
+	BatchProductsFn      func(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error)
+	BatchProductsInvoked bool
+
+	ProductHistoryFn      func(ctx context.Context, id string, page catalog.Page) (*catalog.RevisionPage, error)
+	ProductHistoryInvoked bool
+
+	ProductRevisionFn      func(ctx context.Context, id string, version int64) (*catalog.ProductRevision, error)
+	ProductRevisionInvoked bool
+
+	ProductAsOfFn      func(ctx context.Context, id string, at time.Time) (*catalog.Product, error)
+	ProductAsOfInvoked bool
 }
 
 func (s *ProductService) Product(ctx context.Context, id string) (*catalog.Product, error) {
 	s.ProductInvoked = true
-	return s.ProductFn(context.Background(), id)
+	return s.ProductFn(ctx, id)
+}
+
+func (s *ProductService) ProductByCode(ctx context.Context, code string) (*catalog.Product, error) {
+	s.ProductByCodeInvoked = true
+	return s.ProductByCodeFn(ctx, code)
 }
 
-func (s *ProductService) Products(ctx context.Context) ([]*catalog.Product, error) {
+func (s *ProductService) Products(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
 	s.ProductsInvoked = true
-	return s.ProductsFn(context.Background())
+	return s.ProductsFn(ctx, q)
 }
 
 func (s *ProductService) CreateProduct(ctx context.Context, product *catalog.Product) error {
 	s.CreateProductInvoked = true
-	return s.CreateProductFn(context.Background(), product)
+	return s.CreateProductFn(ctx, product)
 }
 
-func (s *ProductService) DeleteProduct(ctx context.Context, id string) error {
+func (s *ProductService) UpdateProduct(ctx context.Context, product *catalog.Product) error {
+	s.UpdateProductInvoked = true
+	return s.UpdateProductFn(ctx, product)
+}
+
+func (s *ProductService) PatchProduct(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error) {
+	s.PatchProductInvoked = true
+	return s.PatchProductFn(ctx, id, patch)
+}
+
+func (s *ProductService) DeleteProduct(ctx context.Context, id string, version int64) error {
 	s.DeleteProductInvoked = true
-	return s.DeleteProductFn(context.Background(), id)
-}
\ No newline at end of file
+	return s.DeleteProductFn(ctx, id, version)
+}
+
+func (s *ProductService) RestoreProduct(ctx context.Context, id string) (*catalog.Product, error) {
+	s.RestoreProductInvoked = true
+	return s.RestoreProductFn(ctx, id)
+}
+
+func (s *ProductService) PurgeProduct(ctx context.Context, id string) error {
+	s.PurgeProductInvoked = true
+	return s.PurgeProductFn(ctx, id)
+}
+
+func (s *ProductService) PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error) {
+	s.PurgeDeletedProductsInvoked = true
+	return s.PurgeDeletedProductsFn(ctx, before)
+}
+
+func (s *ProductService) BatchProducts(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+	s.BatchProductsInvoked = true
+	return s.BatchProductsFn(ctx, ops, atomic)
+}
+
+func (s *ProductService) ProductHistory(ctx context.Context, id string, page catalog.Page) (*catalog.RevisionPage, error) {
+	s.ProductHistoryInvoked = true
+	return s.ProductHistoryFn(ctx, id, page)
+}
+
+func (s *ProductService) ProductRevision(ctx context.Context, id string, version int64) (*catalog.ProductRevision, error) {
+	s.ProductRevisionInvoked = true
+	return s.ProductRevisionFn(ctx, id, version)
+}
+
+func (s *ProductService) ProductAsOf(ctx context.Context, id string, at time.Time) (*catalog.Product, error) {
+	s.ProductAsOfInvoked = true
+	return s.ProductAsOfFn(ctx, id, at)
+}
+
+type PriceService struct {
+	PriceListFn      func(ctx context.Context, id string) (*catalog.PriceList, error)
+	PriceListInvoked bool
+
+	PriceListsFn      func(ctx context.Context) ([]*catalog.PriceList, error)
+	PriceListsInvoked bool
+
+	CreatePriceListFn      func(ctx context.Context, pl *catalog.PriceList) error
+	CreatePriceListInvoked bool
+
+	PriceFn      func(ctx context.Context, id string) (*catalog.Price, error)
+	PriceInvoked bool
+
+	PricesFn      func(ctx context.Context, productID string, q catalog.PriceQuery) ([]*catalog.Price, error)
+	PricesInvoked bool
+
+	CreatePriceFn      func(ctx context.Context, p *catalog.Price) error
+	CreatePriceInvoked bool
+
+	UpdatePriceFn      func(ctx context.Context, p *catalog.Price) error
+	UpdatePriceInvoked bool
+
+	DeletePriceFn      func(ctx context.Context, id string) error
+	DeletePriceInvoked bool
+}
+
+func (s *PriceService) PriceList(ctx context.Context, id string) (*catalog.PriceList, error) {
+	s.PriceListInvoked = true
+	return s.PriceListFn(ctx, id)
+}
+
+func (s *PriceService) PriceLists(ctx context.Context) ([]*catalog.PriceList, error) {
+	s.PriceListsInvoked = true
+	return s.PriceListsFn(ctx)
+}
+
+func (s *PriceService) CreatePriceList(ctx context.Context, pl *catalog.PriceList) error {
+	s.CreatePriceListInvoked = true
+	return s.CreatePriceListFn(ctx, pl)
+}
+
+func (s *PriceService) Price(ctx context.Context, id string) (*catalog.Price, error) {
+	s.PriceInvoked = true
+	return s.PriceFn(ctx, id)
+}
+
+func (s *PriceService) Prices(ctx context.Context, productID string, q catalog.PriceQuery) ([]*catalog.Price, error) {
+	s.PricesInvoked = true
+	return s.PricesFn(ctx, productID, q)
+}
+
+func (s *PriceService) CreatePrice(ctx context.Context, p *catalog.Price) error {
+	s.CreatePriceInvoked = true
+	return s.CreatePriceFn(ctx, p)
+}
+
+func (s *PriceService) UpdatePrice(ctx context.Context, p *catalog.Price) error {
+	s.UpdatePriceInvoked = true
+	return s.UpdatePriceFn(ctx, p)
+}
+
+func (s *PriceService) DeletePrice(ctx context.Context, id string) error {
+	s.DeletePriceInvoked = true
+	return s.DeletePriceFn(ctx, id)
+}
+
+type VariantService struct {
+	VariantFn      func(ctx context.Context, id string) (*catalog.Variant, error)
+	VariantInvoked bool
+
+	VariantsFn      func(ctx context.Context, productID string) ([]*catalog.Variant, error)
+	VariantsInvoked bool
+
+	CreateVariantFn      func(ctx context.Context, v *catalog.Variant) error
+	CreateVariantInvoked bool
+
+	UpdateVariantFn      func(ctx context.Context, v *catalog.Variant) error
+	UpdateVariantInvoked bool
+
+	DeleteVariantFn      func(ctx context.Context, id string) error
+	DeleteVariantInvoked bool
+}
+
+func (s *VariantService) Variant(ctx context.Context, id string) (*catalog.Variant, error) {
+	s.VariantInvoked = true
+	return s.VariantFn(ctx, id)
+}
+
+func (s *VariantService) Variants(ctx context.Context, productID string) ([]*catalog.Variant, error) {
+	s.VariantsInvoked = true
+	return s.VariantsFn(ctx, productID)
+}
+
+func (s *VariantService) CreateVariant(ctx context.Context, v *catalog.Variant) error {
+	s.CreateVariantInvoked = true
+	return s.CreateVariantFn(ctx, v)
+}
+
+func (s *VariantService) UpdateVariant(ctx context.Context, v *catalog.Variant) error {
+	s.UpdateVariantInvoked = true
+	return s.UpdateVariantFn(ctx, v)
+}
+
+func (s *VariantService) DeleteVariant(ctx context.Context, id string) error {
+	s.DeleteVariantInvoked = true
+	return s.DeleteVariantFn(ctx, id)
+}
+
+type CategoryService struct {
+	CategoryFn      func(ctx context.Context, id string) (*catalog.Category, error)
+	CategoryInvoked bool
+
+	ChildrenFn      func(ctx context.Context, parentID string) ([]*catalog.Category, error)
+	ChildrenInvoked bool
+
+	BreadcrumbsFn      func(ctx context.Context, id string) ([]*catalog.Category, error)
+	BreadcrumbsInvoked bool
+
+	CreateCategoryFn      func(ctx context.Context, c *catalog.Category) error
+	CreateCategoryInvoked bool
+
+	UpdateCategoryFn      func(ctx context.Context, c *catalog.Category) error
+	UpdateCategoryInvoked bool
+
+	DeleteCategoryFn      func(ctx context.Context, id string) error
+	DeleteCategoryInvoked bool
+
+	AssignProductFn      func(ctx context.Context, categoryID, productID string) error
+	AssignProductInvoked bool
+
+	UnassignProductFn      func(ctx context.Context, categoryID, productID string) error
+	UnassignProductInvoked bool
+
+	ProductCategoriesFn      func(ctx context.Context, productID string) ([]*catalog.Category, error)
+	ProductCategoriesInvoked bool
+}
+
+func (s *CategoryService) Category(ctx context.Context, id string) (*catalog.Category, error) {
+	s.CategoryInvoked = true
+	return s.CategoryFn(ctx, id)
+}
+
+func (s *CategoryService) Children(ctx context.Context, parentID string) ([]*catalog.Category, error) {
+	s.ChildrenInvoked = true
+	return s.ChildrenFn(ctx, parentID)
+}
+
+func (s *CategoryService) Breadcrumbs(ctx context.Context, id string) ([]*catalog.Category, error) {
+	s.BreadcrumbsInvoked = true
+	return s.BreadcrumbsFn(ctx, id)
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, c *catalog.Category) error {
+	s.CreateCategoryInvoked = true
+	return s.CreateCategoryFn(ctx, c)
+}
+
+func (s *CategoryService) UpdateCategory(ctx context.Context, c *catalog.Category) error {
+	s.UpdateCategoryInvoked = true
+	return s.UpdateCategoryFn(ctx, c)
+}
+
+func (s *CategoryService) DeleteCategory(ctx context.Context, id string) error {
+	s.DeleteCategoryInvoked = true
+	return s.DeleteCategoryFn(ctx, id)
+}
+
+func (s *CategoryService) AssignProduct(ctx context.Context, categoryID, productID string) error {
+	s.AssignProductInvoked = true
+	return s.AssignProductFn(ctx, categoryID, productID)
+}
+
+func (s *CategoryService) UnassignProduct(ctx context.Context, categoryID, productID string) error {
+	s.UnassignProductInvoked = true
+	return s.UnassignProductFn(ctx, categoryID, productID)
+}
+
+func (s *CategoryService) ProductCategories(ctx context.Context, productID string) ([]*catalog.Category, error) {
+	s.ProductCategoriesInvoked = true
+	return s.ProductCategoriesFn(ctx, productID)
+}
+
+type AttributeService struct {
+	AttributeDefinitionFn      func(ctx context.Context, name string) (*catalog.AttributeDefinition, error)
+	AttributeDefinitionInvoked bool
+
+	AttributeDefinitionsFn      func(ctx context.Context) ([]*catalog.AttributeDefinition, error)
+	AttributeDefinitionsInvoked bool
+
+	CreateAttributeDefinitionFn      func(ctx context.Context, d *catalog.AttributeDefinition) error
+	CreateAttributeDefinitionInvoked bool
+
+	UpdateAttributeDefinitionFn      func(ctx context.Context, d *catalog.AttributeDefinition) error
+	UpdateAttributeDefinitionInvoked bool
+
+	DeleteAttributeDefinitionFn      func(ctx context.Context, name string) error
+	DeleteAttributeDefinitionInvoked bool
+}
+
+func (s *AttributeService) AttributeDefinition(ctx context.Context, name string) (*catalog.AttributeDefinition, error) {
+	s.AttributeDefinitionInvoked = true
+	return s.AttributeDefinitionFn(ctx, name)
+}
+
+func (s *AttributeService) AttributeDefinitions(ctx context.Context) ([]*catalog.AttributeDefinition, error) {
+	s.AttributeDefinitionsInvoked = true
+	return s.AttributeDefinitionsFn(ctx)
+}
+
+func (s *AttributeService) CreateAttributeDefinition(ctx context.Context, d *catalog.AttributeDefinition) error {
+	s.CreateAttributeDefinitionInvoked = true
+	return s.CreateAttributeDefinitionFn(ctx, d)
+}
+
+func (s *AttributeService) UpdateAttributeDefinition(ctx context.Context, d *catalog.AttributeDefinition) error {
+	s.UpdateAttributeDefinitionInvoked = true
+	return s.UpdateAttributeDefinitionFn(ctx, d)
+}
+
+func (s *AttributeService) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	s.DeleteAttributeDefinitionInvoked = true
+	return s.DeleteAttributeDefinitionFn(ctx, name)
+}
+
+type EventService struct {
+	PublishPendingFn      func(ctx context.Context, p catalog.EventPublisher, limit int) (int, error)
+	PublishPendingInvoked bool
+
+	EventsAfterFn      func(ctx context.Context, after int64, limit int) ([]*catalog.ProductEvent, error)
+	EventsAfterInvoked bool
+
+	LastEventIDFn      func(ctx context.Context) (int64, error)
+	LastEventIDInvoked bool
+}
+
+func (s *EventService) PublishPending(ctx context.Context, p catalog.EventPublisher, limit int) (int, error) {
+	s.PublishPendingInvoked = true
+	return s.PublishPendingFn(ctx, p, limit)
+}
+
+func (s *EventService) EventsAfter(ctx context.Context, after int64, limit int) ([]*catalog.ProductEvent, error) {
+	s.EventsAfterInvoked = true
+	return s.EventsAfterFn(ctx, after, limit)
+}
+
+func (s *EventService) LastEventID(ctx context.Context) (int64, error) {
+	s.LastEventIDInvoked = true
+	return s.LastEventIDFn(ctx)
+}
+
+type WebhookService struct {
+	WebhookFn      func(ctx context.Context, id string) (*catalog.Webhook, error)
+	WebhookInvoked bool
+
+	WebhooksFn      func(ctx context.Context) ([]*catalog.Webhook, error)
+	WebhooksInvoked bool
+
+	CreateWebhookFn      func(ctx context.Context, w *catalog.Webhook) error
+	CreateWebhookInvoked bool
+
+	DeleteWebhookFn      func(ctx context.Context, id string) error
+	DeleteWebhookInvoked bool
+
+	EnqueueDeliveriesFn      func(ctx context.Context, e *catalog.ProductEvent) (int, error)
+	EnqueueDeliveriesInvoked bool
+
+	ClaimDeliveriesFn      func(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error)
+	ClaimDeliveriesInvoked bool
+
+	RecordAttemptFn      func(ctx context.Context, d *catalog.WebhookDelivery) error
+	RecordAttemptInvoked bool
+
+	DeliveriesFn      func(ctx context.Context, webhookID string, status catalog.DeliveryStatus, page catalog.Page) (*catalog.DeliveryPage, error)
+	DeliveriesInvoked bool
+
+	DeliveryFn      func(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error)
+	DeliveryInvoked bool
+
+	ReplayDeliveryFn      func(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error)
+	ReplayDeliveryInvoked bool
+}
+
+func (s *WebhookService) Webhook(ctx context.Context, id string) (*catalog.Webhook, error) {
+	s.WebhookInvoked = true
+	return s.WebhookFn(ctx, id)
+}
+
+func (s *WebhookService) Webhooks(ctx context.Context) ([]*catalog.Webhook, error) {
+	s.WebhooksInvoked = true
+	return s.WebhooksFn(ctx)
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, w *catalog.Webhook) error {
+	s.CreateWebhookInvoked = true
+	return s.CreateWebhookFn(ctx, w)
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	s.DeleteWebhookInvoked = true
+	return s.DeleteWebhookFn(ctx, id)
+}
+
+func (s *WebhookService) EnqueueDeliveries(ctx context.Context, e *catalog.ProductEvent) (int, error) {
+	s.EnqueueDeliveriesInvoked = true
+	return s.EnqueueDeliveriesFn(ctx, e)
+}
+
+func (s *WebhookService) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error) {
+	s.ClaimDeliveriesInvoked = true
+	return s.ClaimDeliveriesFn(ctx, limit, lease)
+}
+
+func (s *WebhookService) RecordAttempt(ctx context.Context, d *catalog.WebhookDelivery) error {
+	s.RecordAttemptInvoked = true
+	return s.RecordAttemptFn(ctx, d)
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, status catalog.DeliveryStatus, page catalog.Page) (*catalog.DeliveryPage, error) {
+	s.DeliveriesInvoked = true
+	return s.DeliveriesFn(ctx, webhookID, status, page)
+}
+
+func (s *WebhookService) Delivery(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error) {
+	s.DeliveryInvoked = true
+	return s.DeliveryFn(ctx, webhookID, id)
+}
+
+func (s *WebhookService) ReplayDelivery(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error) {
+	s.ReplayDeliveryInvoked = true
+	return s.ReplayDeliveryFn(ctx, webhookID, id)
+}
+
+type APIKeyService struct {
+	APIKeyFn      func(ctx context.Context, id string) (*catalog.APIKey, error)
+	APIKeyInvoked bool
+
+	APIKeysFn      func(ctx context.Context) ([]*catalog.APIKey, error)
+	APIKeysInvoked bool
+
+	CreateAPIKeyFn      func(ctx context.Context, k *catalog.APIKey, hash string) error
+	CreateAPIKeyInvoked bool
+
+	APIKeyByHashFn      func(ctx context.Context, hash string) (*catalog.APIKey, error)
+	APIKeyByHashInvoked bool
+
+	RotateAPIKeyFn      func(ctx context.Context, id, prefix, hash string, grace time.Duration) (*catalog.APIKey, error)
+	RotateAPIKeyInvoked bool
+
+	RevokeAPIKeyFn      func(ctx context.Context, id string) error
+	RevokeAPIKeyInvoked bool
+
+	TouchAPIKeyFn      func(ctx context.Context, id string, t time.Time) error
+	TouchAPIKeyInvoked bool
+}
+
+func (s *APIKeyService) APIKey(ctx context.Context, id string) (*catalog.APIKey, error) {
+	s.APIKeyInvoked = true
+	return s.APIKeyFn(ctx, id)
+}
+
+func (s *APIKeyService) APIKeys(ctx context.Context) ([]*catalog.APIKey, error) {
+	s.APIKeysInvoked = true
+	return s.APIKeysFn(ctx)
+}
+
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, k *catalog.APIKey, hash string) error {
+	s.CreateAPIKeyInvoked = true
+	return s.CreateAPIKeyFn(ctx, k, hash)
+}
+
+func (s *APIKeyService) APIKeyByHash(ctx context.Context, hash string) (*catalog.APIKey, error) {
+	s.APIKeyByHashInvoked = true
+	return s.APIKeyByHashFn(ctx, hash)
+}
+
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id, prefix, hash string, grace time.Duration) (*catalog.APIKey, error) {
+	s.RotateAPIKeyInvoked = true
+	return s.RotateAPIKeyFn(ctx, id, prefix, hash, grace)
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	s.RevokeAPIKeyInvoked = true
+	return s.RevokeAPIKeyFn(ctx, id)
+}
+
+func (s *APIKeyService) TouchAPIKey(ctx context.Context, id string, t time.Time) error {
+	s.TouchAPIKeyInvoked = true
+	return s.TouchAPIKeyFn(ctx, id, t)
+}
+
+type TenantService struct {
+	TenantFn      func(ctx context.Context, id string) (*catalog.Tenant, error)
+	TenantInvoked bool
+
+	TenantsFn      func(ctx context.Context) ([]*catalog.Tenant, error)
+	TenantsInvoked bool
+
+	CreateTenantFn      func(ctx context.Context, t *catalog.Tenant) error
+	CreateTenantInvoked bool
+
+	UpdateTenantFn      func(ctx context.Context, t *catalog.Tenant) error
+	UpdateTenantInvoked bool
+}
+
+func (s *TenantService) Tenant(ctx context.Context, id string) (*catalog.Tenant, error) {
+	s.TenantInvoked = true
+	return s.TenantFn(ctx, id)
+}
+
+func (s *TenantService) Tenants(ctx context.Context) ([]*catalog.Tenant, error) {
+	s.TenantsInvoked = true
+	return s.TenantsFn(ctx)
+}
+
+func (s *TenantService) CreateTenant(ctx context.Context, t *catalog.Tenant) error {
+	s.CreateTenantInvoked = true
+	return s.CreateTenantFn(ctx, t)
+}
+
+func (s *TenantService) UpdateTenant(ctx context.Context, t *catalog.Tenant) error {
+	s.UpdateTenantInvoked = true
+	return s.UpdateTenantFn(ctx, t)
+}
@@ -0,0 +1,249 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure APIKeyService implements catalog.APIKeyService
+var _ catalog.APIKeyService = &APIKeyService{}
+
+// APIKeyService represents a service for managing API keys.
+type APIKeyService struct {
+	client *Client
+	get    *sql.Stmt
+	list   *sql.Stmt
+	insert *sql.Stmt
+	byHash *sql.Stmt
+	rotate *sql.Stmt
+	revoke *sql.Stmt
+	touch  *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetAPIKeyStatement    SqlStatement
+	ListAPIKeysStatement  SqlStatement
+	InsertAPIKeyStatement SqlStatement
+	APIKeyByHashStatement SqlStatement
+	RotateAPIKeyStatement SqlStatement
+	RevokeAPIKeyStatement SqlStatement
+	TouchAPIKeyStatement  SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *APIKeyService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getapikeystmt, listapikeysstmt, insertapikeystmt, apikeybyhashstmt,
+		rotateapikeystmt, revokeapikeystmt, touchapikeystmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *APIKeyService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetAPIKeyStatement:
+			err = prepare(&s.get, "get api key", string(stmt))
+		case ListAPIKeysStatement:
+			err = prepare(&s.list, "list api keys", string(stmt))
+		case InsertAPIKeyStatement:
+			err = prepare(&s.insert, "insert api key", string(stmt))
+		case APIKeyByHashStatement:
+			err = prepare(&s.byHash, "api key by hash", string(stmt))
+		case RotateAPIKeyStatement:
+			err = prepare(&s.rotate, "rotate api key", string(stmt))
+		case RevokeAPIKeyStatement:
+			err = prepare(&s.revoke, "revoke api key", string(stmt))
+		case TouchAPIKeyStatement:
+			err = prepare(&s.touch, "touch api key", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apiKeyColumns is the column list selected for every API key read. The
+// hashes are never read back.
+const apiKeyColumns = "id, name, tenant_id, prefix, scopes, expires_at, last_used_at, rotated_at, revoked_at, created_by, created_at"
+
+// scanAPIKey scans a row selected with apiKeyColumns.
+func scanAPIKey(row rowScanner) (*catalog.APIKey, error) {
+	var k catalog.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, rotatedAt, revokedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.TenantID, &k.Prefix, &scopes, &expiresAt, &lastUsedAt, &rotatedAt, &revokedAt,
+		&k.CreatedBy, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.ExpiresAt = nullTime(expiresAt)
+	k.LastUsedAt = nullTime(lastUsedAt)
+	k.RotatedAt = nullTime(rotatedAt)
+	k.RevokedAt = nullTime(revokedAt)
+	return &k, nil
+}
+
+var getapikeystmt GetAPIKeyStatement = "SELECT " + apiKeyColumns + " FROM api_key WHERE tenant_id = ? AND id = ?"
+
+// APIKey returns an API key of the context's tenant by ID.
+func (s *APIKeyService) APIKey(ctx context.Context, id string) (*catalog.APIKey, error) {
+	k, err := scanAPIKey(s.get.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "API key %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving API key: %v, %v", id, err)
+		return nil, err
+	}
+	return k, nil
+}
+
+var listapikeysstmt ListAPIKeysStatement = "SELECT " + apiKeyColumns + " FROM api_key WHERE tenant_id = ? ORDER BY id"
+
+// APIKeys returns the API keys of the context's tenant, revoked ones
+// included, ordered by ID.
+func (s *APIKeyService) APIKeys(ctx context.Context) ([]*catalog.APIKey, error) {
+	rows, err := s.list.QueryContext(ctx, catalog.TenantFromContext(ctx))
+	if err != nil {
+		log.Errorf("Error retrieving API keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*catalog.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+var insertapikeystmt InsertAPIKeyStatement = "INSERT api_key SET name=?, tenant_id=?, prefix=?, hash=?, scopes=?, expires_at=?, " +
+	"created_by=?, created_at=?"
+
+// CreateAPIKey stores a new API key and assigns its ID. A key without a
+// tenant is issued for the context's tenant.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, k *catalog.APIKey, hash string) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	if k.TenantID == "" {
+		k.TenantID = catalog.TenantFromContext(ctx)
+	}
+	createdAt := time.Now().UTC()
+	res, err := s.insert.ExecContext(ctx, k.Name, k.TenantID, k.Prefix, hash, strings.Join(k.Scopes, " "), timeArg(k.ExpiresAt),
+		k.CreatedBy, createdAt)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	k.ID = strconv.FormatInt(id, 10)
+	k.CreatedAt = createdAt
+	return nil
+}
+
+var apikeybyhashstmt APIKeyByHashStatement = "SELECT " + apiKeyColumns + " FROM api_key " +
+	"WHERE hash = ? OR (previous_hash = ? AND previous_expires_at > UTC_TIMESTAMP(6)) LIMIT 1"
+
+// APIKeyByHash returns the API key whose current secret, or previous
+// secret within its grace period, has the hash.
+func (s *APIKeyService) APIKeyByHash(ctx context.Context, hash string) (*catalog.APIKey, error) {
+	k, err := scanAPIKey(s.byHash.QueryRowContext(ctx, hash, hash))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "API key not found")
+	}
+	if err != nil {
+		log.Errorf("Error retrieving API key by hash: %v", err)
+		return nil, err
+	}
+	return k, nil
+}
+
+var rotateapikeystmt RotateAPIKeyStatement = "UPDATE api_key SET previous_hash=hash, previous_expires_at=?, " +
+	"hash=?, prefix=?, rotated_at=? WHERE tenant_id=? AND id=? AND revoked_at IS NULL"
+
+// RotateAPIKey replaces the secret of an API key of the context's tenant
+// that is not revoked.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id, prefix, hash string, grace time.Duration) (*catalog.APIKey, error) {
+	now := time.Now().UTC()
+	res, err := s.rotate.ExecContext(ctx, now.Add(grace), hash, prefix, now, catalog.TenantFromContext(ctx), id)
+	if err != nil {
+		log.Error(err)
+		return nil, translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	k, err := s.APIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if affect == 0 {
+		return nil, catalog.Errorf(catalog.ErrConflict, "API key %v is revoked", id)
+	}
+	return k, nil
+}
+
+var revokeapikeystmt RevokeAPIKeyStatement = "UPDATE api_key SET revoked_at=?, previous_hash=NULL " +
+	"WHERE tenant_id=? AND id=? AND revoked_at IS NULL"
+
+// RevokeAPIKey revokes an API key of the context's tenant. A key already
+// revoked keeps the time it was first revoked.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	res, err := s.revoke.ExecContext(ctx, time.Now().UTC(), catalog.TenantFromContext(ctx), id)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		// Tell an unknown key from one revoked before.
+		_, err := s.APIKey(ctx, id)
+		return err
+	}
+	return nil
+}
+
+var touchapikeystmt TouchAPIKeyStatement = "UPDATE api_key SET last_used_at=? WHERE id=?"
+
+// TouchAPIKey records when an API key was last used.
+func (s *APIKeyService) TouchAPIKey(ctx context.Context, id string, t time.Time) error {
+	if _, err := s.touch.ExecContext(ctx, t.UTC(), id); err != nil {
+		log.Errorf("Error recording the use of API key %v: %v", id, err)
+		return err
+	}
+	return nil
+}
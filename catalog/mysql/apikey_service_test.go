@@ -0,0 +1,154 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var apiKeyColumnNames = []string{"id", "name", "tenant_id", "prefix", "scopes", "expires_at", "last_used_at", "rotated_at",
+	"revoked_at", "created_by", "created_at"}
+
+func TestAPIKeyService_CreateAPIKey(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT api_key SET name=\\?, tenant_id=\\?, prefix=\\?, hash=\\?, scopes=\\?, expires_at=\\?")
+	mock.ExpectExec("INSERT api_key SET").
+		WithArgs("scanner", "acme", "ck_abcdefgh", "0f0f", "read:product write:product", nil, "admin", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	client := NewClient()
+	client.db = db
+	client.apiKeyService.prepareSqlStmt(insertapikeystmt)
+
+	k := &catalog.APIKey{Name: "scanner", Prefix: "ck_abcdefgh", Scopes: []string{"read:product", "write:product"},
+		CreatedBy: "admin"}
+	if err := client.apiKeyService.CreateAPIKey(catalog.WithTenant(context.Background(), "acme"), k, "0f0f"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.ID != "4" || k.TenantID != "acme" || k.CreatedAt.IsZero() {
+		t.Errorf("unexpected API key: %+v", k)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAPIKeyService_CreateAPIKeyInvalid(t *testing.T) {
+	client := NewClient()
+	expired := time.Now().Add(-time.Hour)
+	k := &catalog.APIKey{Scopes: []string{"read:product write:product"}, ExpiresAt: &expired}
+	err := client.apiKeyService.CreateAPIKey(context.Background(), k, "0f0f")
+	e, ok := err.(*catalog.Error)
+	if !ok || e.Kind != catalog.ErrInvalid || len(e.Fields) != 3 {
+		t.Errorf("expected 3 invalid fields, got %v", err)
+	}
+}
+
+func TestAPIKeyService_APIKeyByHash(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, name, .* FROM api_key WHERE hash = \\? OR \\(previous_hash = \\?")
+	mock.ExpectQuery("SELECT id, name, .* FROM api_key").WithArgs("0f0f", "0f0f").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames).
+			AddRow("4", "scanner", "acme", "ck_abcdefgh", "read:product", nil, createdAt, nil, nil, "admin", createdAt))
+	mock.ExpectQuery("SELECT id, name, .* FROM api_key").WithArgs("ffff", "ffff").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames))
+
+	client := NewClient()
+	client.db = db
+	client.apiKeyService.prepareSqlStmt(apikeybyhashstmt)
+
+	k, err := client.apiKeyService.APIKeyByHash(context.Background(), "0f0f")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.ID != "4" || k.TenantID != "acme" || len(k.Scopes) != 1 || k.LastUsedAt == nil || k.ExpiresAt != nil {
+		t.Errorf("unexpected API key: %+v", k)
+	}
+	_, err = client.apiKeyService.APIKeyByHash(context.Background(), "ffff")
+	if e, ok := err.(*catalog.Error); !ok || e.Kind != catalog.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAPIKeyService_RotateAPIKeyRevoked(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("UPDATE api_key SET previous_hash=hash")
+	mock.ExpectPrepare("SELECT id, name, .* FROM api_key WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectExec("UPDATE api_key SET previous_hash=hash").
+		WithArgs(sqlmock.AnyArg(), "0f0f", "ck_abcdefgh", sqlmock.AnyArg(), "default", "4").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, name, .* FROM api_key").WithArgs("default", "4").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames).
+			AddRow("4", "scanner", "default", "ck_12345678", "read:product", nil, nil, nil, createdAt, "admin", createdAt))
+
+	client := NewClient()
+	client.db = db
+	client.apiKeyService.prepareSqlStmt(rotateapikeystmt, getapikeystmt)
+
+	_, err = client.apiKeyService.RotateAPIKey(context.Background(), "4", "ck_abcdefgh", "0f0f", time.Hour)
+	if e, ok := err.(*catalog.Error); !ok || e.Kind != catalog.ErrConflict {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAPIKeyService_RevokeAPIKeyNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("UPDATE api_key SET revoked_at=\\?")
+	mock.ExpectPrepare("SELECT id, name, .* FROM api_key WHERE tenant_id = \\? AND id = \\?")
+	// Keys of other tenants are not found.
+	mock.ExpectExec("UPDATE api_key SET revoked_at").WithArgs(sqlmock.AnyArg(), "acme", "9").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, name, .* FROM api_key").WithArgs("acme", "9").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumnNames))
+
+	client := NewClient()
+	client.db = db
+	client.apiKeyService.prepareSqlStmt(revokeapikeystmt, getapikeystmt)
+
+	err = client.apiKeyService.RevokeAPIKey(catalog.WithTenant(context.Background(), "acme"), "9")
+	if e, ok := err.(*catalog.Error); !ok || e.Kind != catalog.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
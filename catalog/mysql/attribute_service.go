@@ -0,0 +1,216 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure AttributeService implements catalog.AttributeService
+var _ catalog.AttributeService = &AttributeService{}
+
+// AttributeService represents a service for managing attribute definitions.
+type AttributeService struct {
+	client *Client
+	get    *sql.Stmt
+	list   *sql.Stmt
+	insert *sql.Stmt
+	update *sql.Stmt
+	delete *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetAttributeStatement    SqlStatement
+	ListAttributesStatement  SqlStatement
+	InsertAttributeStatement SqlStatement
+	UpdateAttributeStatement SqlStatement
+	DeleteAttributeStatement SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *AttributeService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getattributestmt, listattributesstmt, insertattributestmt,
+		updateattributestmt, deleteattributestmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *AttributeService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetAttributeStatement:
+			err = prepare(&s.get, "get attribute", string(stmt))
+		case ListAttributesStatement:
+			err = prepare(&s.list, "list attributes", string(stmt))
+		case InsertAttributeStatement:
+			err = prepare(&s.insert, "insert attribute", string(stmt))
+		case UpdateAttributeStatement:
+			err = prepare(&s.update, "update attribute", string(stmt))
+		case DeleteAttributeStatement:
+			err = prepare(&s.delete, "delete attribute", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanAttribute scans a row of name, type, allowed_values, unit, required.
+func scanAttribute(row interface{ Scan(...interface{}) error }) (*catalog.AttributeDefinition, error) {
+	var d catalog.AttributeDefinition
+	var allowed sql.NullString
+	if err := row.Scan(&d.Name, &d.Type, &allowed, &d.Unit, &d.Required); err != nil {
+		return nil, err
+	}
+	if allowed.Valid {
+		if err := json.Unmarshal([]byte(allowed.String), &d.AllowedValues); err != nil {
+			return nil, fmt.Errorf("mysql: allowed values of attribute %v: %v", d.Name, err)
+		}
+	}
+	return &d, nil
+}
+
+// allowedValues returns the JSON stored for the allowed values of d.
+func allowedValues(d *catalog.AttributeDefinition) (interface{}, error) {
+	if len(d.AllowedValues) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(d.AllowedValues)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+var getattributestmt GetAttributeStatement = "SELECT name, type, allowed_values, unit, required FROM attribute_definition WHERE name = ?"
+
+// AttributeDefinition returns an attribute definition by name.
+func (s *AttributeService) AttributeDefinition(ctx context.Context, name string) (*catalog.AttributeDefinition, error) {
+	d, err := scanAttribute(s.get.QueryRowContext(ctx, name))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "attribute %v not found", name)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving attribute: %v, %v", name, err)
+		return nil, err
+	}
+	return d, nil
+}
+
+var listattributesstmt ListAttributesStatement = "SELECT name, type, allowed_values, unit, required FROM attribute_definition ORDER BY name"
+
+// AttributeDefinitions returns all attribute definitions ordered by name.
+func (s *AttributeService) AttributeDefinitions(ctx context.Context) ([]*catalog.AttributeDefinition, error) {
+	return queryAttributes(ctx, s.list)
+}
+
+// queryAttributes runs a statement selecting attribute definitions.
+func queryAttributes(ctx context.Context, stmt *sql.Stmt) ([]*catalog.AttributeDefinition, error) {
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		log.Errorf("Error retrieving attributes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	defs := []*catalog.AttributeDefinition{}
+	for rows.Next() {
+		d, err := scanAttribute(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return defs, nil
+}
+
+var insertattributestmt InsertAttributeStatement = "INSERT attribute_definition SET name=?, type=?, allowed_values=?, unit=?, required=?"
+
+// CreateAttributeDefinition stores a new attribute definition.
+func (s *AttributeService) CreateAttributeDefinition(ctx context.Context, d *catalog.AttributeDefinition) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	allowed, err := allowedValues(d)
+	if err != nil {
+		return err
+	}
+	if _, err := s.insert.ExecContext(ctx, d.Name, d.Type, allowed, d.Unit, d.Required); err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	return nil
+}
+
+var updateattributestmt UpdateAttributeStatement = "UPDATE attribute_definition SET allowed_values=?, unit=?, required=? WHERE name=?"
+
+// UpdateAttributeDefinition changes the allowed values, unit and required
+// flag of a definition. Values already stored are not revalidated.
+func (s *AttributeService) UpdateAttributeDefinition(ctx context.Context, d *catalog.AttributeDefinition) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	allowed, err := allowedValues(d)
+	if err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := scanAttribute(tx.StmtContext(ctx, s.get).QueryRowContext(ctx, d.Name))
+		if err == sql.ErrNoRows {
+			return catalog.Errorf(catalog.ErrNotFound, "attribute %v not found", d.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if current.Type != d.Type {
+			return catalog.ValidationError("attribute definition is invalid", []catalog.FieldError{
+				{Field: "type", Message: "cannot be changed"}})
+		}
+		if _, err := tx.StmtContext(ctx, s.update).ExecContext(ctx, allowed, d.Unit, d.Required, d.Name); err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+		return nil
+	})
+}
+
+var deleteattributestmt DeleteAttributeStatement = "DELETE FROM attribute_definition WHERE name=?"
+
+// DeleteAttributeDefinition deletes a definition. A definition that
+// products still have values for cannot be deleted.
+func (s *AttributeService) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	res, err := s.delete.ExecContext(ctx, name)
+	if err != nil {
+		log.Error(err)
+		if err = translateError(err); errors.Is(err, catalog.ErrConflict) {
+			return catalog.Errorf(catalog.ErrConflict, "attribute %v is in use by products", name)
+		}
+		return err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "attribute %v not found", name)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+// attributeColumns are the columns selected for attribute definitions.
+var attributeColumns = []string{"name", "type", "allowed_values", "unit", "required"}
+
+func TestAttributeService_AttributeDefinition(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition WHERE name = \\?").
+		ExpectQuery().WithArgs("material").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).
+			AddRow("material", "string", `["cotton","wool"]`, "", true))
+
+	client := NewClient()
+	client.db = db
+	client.attributeService.prepareSqlStmt(getattributestmt)
+	d, err := client.attributeService.AttributeDefinition(context.Background(), "material")
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(d.AllowedValues) != 2 || d.AllowedValues[1] != "wool" || !d.Required {
+		t.Errorf("unexpected definition: %+v", d)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttributeService_CreateAttributeDefinition(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT attribute_definition SET name=\\?, type=\\?, allowed_values=\\?, unit=\\?, required=\\?").
+		ExpectExec().WithArgs("voltage", "integer", `["110","230"]`, "V", false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	client := NewClient()
+	client.db = db
+	client.attributeService.prepareSqlStmt(insertattributestmt)
+	d := &catalog.AttributeDefinition{Name: "voltage", Type: catalog.AttributeInteger, AllowedValues: []string{"110", "230"}, Unit: "V"}
+	if err := client.attributeService.CreateAttributeDefinition(context.Background(), d); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttributeService_CreateAttributeDefinitionInvalid(t *testing.T) {
+	client := NewClient()
+	d := &catalog.AttributeDefinition{Name: "Weight", Type: catalog.AttributeNumber, AllowedValues: []string{"heavy"}}
+	err := client.attributeService.CreateAttributeDefinition(context.Background(), d)
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || !errors.Is(err, catalog.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid but got: %v", err)
+	}
+	if len(cErr.Fields) != 2 {
+		t.Errorf("expected name and allowedValues field errors, got %v", cErr.Fields)
+	}
+}
+
+func TestAttributeService_UpdateAttributeDefinitionType(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition WHERE name = \\?")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WithArgs("weight").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).AddRow("weight", "number", nil, "kg", false))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.attributeService.prepareSqlStmt(getattributestmt)
+	d := &catalog.AttributeDefinition{Name: "weight", Type: catalog.AttributeString}
+	if err := client.attributeService.UpdateAttributeDefinition(context.Background(), d); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttributeService_DeleteAttributeDefinitionInUse(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("DELETE FROM attribute_definition WHERE name=\\?").
+		ExpectExec().WithArgs("material").
+		WillReturnError(&mysql.MySQLError{Number: errRowIsReferenced, Message: "foreign key constraint fails"})
+
+	client := NewClient()
+	client.db = db
+	client.attributeService.prepareSqlStmt(deleteattributestmt)
+	if err := client.attributeService.DeleteAttributeDefinition(context.Background(), "material"); !errors.Is(err, catalog.ErrConflict) {
+		t.Errorf("expected ErrConflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,355 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure CategoryService implements catalog.CategoryService
+var _ catalog.CategoryService = &CategoryService{}
+
+// CategoryService represents a service for managing the category tree.
+//
+// The tree is stored as an adjacency list (category.parent_id) together with
+// a closure table (category_closure) holding a row for every ancestor and
+// descendant pair, including each category paired with itself at depth 0.
+// The closure table answers subtree and breadcrumb queries with a single join.
+type CategoryService struct {
+	client            *Client
+	get               *sql.Stmt
+	children          *sql.Stmt
+	roots             *sql.Stmt
+	breadcrumbs       *sql.Stmt
+	insert            *sql.Stmt
+	insertClosure     *sql.Stmt
+	update            *sql.Stmt
+	delete            *sql.Stmt
+	isDescendant      *sql.Stmt
+	detachSubtree     *sql.Stmt
+	attachSubtree     *sql.Stmt
+	assign            *sql.Stmt
+	unassign          *sql.Stmt
+	productCategories *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetCategoryStatement       SqlStatement
+	ChildCategoriesStatement   SqlStatement
+	RootCategoriesStatement    SqlStatement
+	BreadcrumbsStatement       SqlStatement
+	InsertCategoryStatement    SqlStatement
+	InsertClosureStatement     SqlStatement
+	UpdateCategoryStatement    SqlStatement
+	DeleteCategoryStatement    SqlStatement
+	IsDescendantStatement      SqlStatement
+	DetachSubtreeStatement     SqlStatement
+	AttachSubtreeStatement     SqlStatement
+	AssignProductStatement     SqlStatement
+	UnassignProductStatement   SqlStatement
+	ProductCategoriesStatement SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *CategoryService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getcategorystmt, childcategoriesstmt, rootcategoriesstmt, breadcrumbsstmt,
+		insertcategorystmt, insertclosurestmt, updatecategorystmt, deletecategorystmt, isdescendantstmt,
+		detachsubtreestmt, attachsubtreestmt, assignproductstmt, unassignproductstmt, productcategoriesstmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *CategoryService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetCategoryStatement:
+			err = prepare(&s.get, "get category", string(stmt))
+		case ChildCategoriesStatement:
+			err = prepare(&s.children, "child categories", string(stmt))
+		case RootCategoriesStatement:
+			err = prepare(&s.roots, "root categories", string(stmt))
+		case BreadcrumbsStatement:
+			err = prepare(&s.breadcrumbs, "breadcrumbs", string(stmt))
+		case InsertCategoryStatement:
+			err = prepare(&s.insert, "insert category", string(stmt))
+		case InsertClosureStatement:
+			err = prepare(&s.insertClosure, "insert closure", string(stmt))
+		case UpdateCategoryStatement:
+			err = prepare(&s.update, "update category", string(stmt))
+		case DeleteCategoryStatement:
+			err = prepare(&s.delete, "delete category", string(stmt))
+		case IsDescendantStatement:
+			err = prepare(&s.isDescendant, "is descendant", string(stmt))
+		case DetachSubtreeStatement:
+			err = prepare(&s.detachSubtree, "detach subtree", string(stmt))
+		case AttachSubtreeStatement:
+			err = prepare(&s.attachSubtree, "attach subtree", string(stmt))
+		case AssignProductStatement:
+			err = prepare(&s.assign, "assign product", string(stmt))
+		case UnassignProductStatement:
+			err = prepare(&s.unassign, "unassign product", string(stmt))
+		case ProductCategoriesStatement:
+			err = prepare(&s.productCategories, "product categories", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const categoryColumns = "c.id, c.parent_id, c.name, c.slug, c.position"
+
+// scanCategory scans a row selected with categoryColumns.
+func scanCategory(row interface{ Scan(...interface{}) error }) (*catalog.Category, error) {
+	var c catalog.Category
+	var parentID sql.NullString
+	if err := row.Scan(&c.ID, &parentID, &c.Name, &c.Slug, &c.Position); err != nil {
+		return nil, err
+	}
+	c.ParentID = parentID.String
+	return &c, nil
+}
+
+// queryCategories runs a statement selecting categoryColumns.
+func queryCategories(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]*catalog.Category, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		log.Errorf("Error retrieving categories: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	categories := []*catalog.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+var getcategorystmt GetCategoryStatement = "SELECT " + categoryColumns + " FROM category c WHERE c.id = ?"
+
+// Category returns a Category by ID.
+func (s *CategoryService) Category(ctx context.Context, id string) (*catalog.Category, error) {
+	c, err := scanCategory(s.get.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "category %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving category: %v, %v", id, err)
+		return nil, err
+	}
+	return c, nil
+}
+
+var childcategoriesstmt ChildCategoriesStatement = "SELECT " + categoryColumns +
+	" FROM category c WHERE c.parent_id = ? ORDER BY c.position, c.name"
+
+var rootcategoriesstmt RootCategoriesStatement = "SELECT " + categoryColumns +
+	" FROM category c WHERE c.parent_id IS NULL ORDER BY c.position, c.name"
+
+// Children returns the ordered children of a category, or the root categories.
+func (s *CategoryService) Children(ctx context.Context, parentID string) ([]*catalog.Category, error) {
+	if parentID == "" {
+		return queryCategories(ctx, s.roots)
+	}
+	return queryCategories(ctx, s.children, parentID)
+}
+
+var breadcrumbsstmt BreadcrumbsStatement = "SELECT " + categoryColumns +
+	" FROM category c JOIN category_closure cc ON cc.ancestor_id = c.id" +
+	" WHERE cc.descendant_id = ? ORDER BY cc.depth DESC"
+
+// Breadcrumbs returns the path from the root down to the category.
+func (s *CategoryService) Breadcrumbs(ctx context.Context, id string) ([]*catalog.Category, error) {
+	crumbs, err := queryCategories(ctx, s.breadcrumbs, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(crumbs) == 0 {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "category %v not found", id)
+	}
+	return crumbs, nil
+}
+
+var insertcategorystmt InsertCategoryStatement = "INSERT category SET parent_id=?, name=?, slug=?, position=?"
+
+// insertclosurestmt links a new category to itself and to every ancestor of its parent.
+var insertclosurestmt InsertClosureStatement = "INSERT INTO category_closure (ancestor_id, descendant_id, depth) " +
+	"SELECT ancestor_id, ?, depth + 1 FROM category_closure WHERE descendant_id = ? " +
+	"UNION ALL SELECT ?, ?, 0"
+
+// CreateCategory stores a new category under its parent.
+func (s *CategoryService) CreateCategory(ctx context.Context, c *catalog.Category) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.StmtContext(ctx, s.insert).ExecContext(ctx, nullString(c.ParentID), c.Name, c.Slug, c.Position)
+		if err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		c.ID = strconv.FormatInt(id, 10)
+		if _, err := tx.StmtContext(ctx, s.insertClosure).ExecContext(ctx, c.ID, nullString(c.ParentID), c.ID, c.ID); err != nil {
+			log.Error(err)
+			return err
+		}
+		return nil
+	})
+}
+
+var updatecategorystmt UpdateCategoryStatement = "UPDATE category SET parent_id=?, name=?, slug=?, position=? WHERE id=?"
+
+var isdescendantstmt IsDescendantStatement = "SELECT COUNT(*) FROM category_closure WHERE ancestor_id = ? AND descendant_id = ?"
+
+// detachsubtreestmt removes the links between the subtree of a category
+// and the ancestors it is being moved away from, keeping links within the subtree.
+var detachsubtreestmt DetachSubtreeStatement = "DELETE a FROM category_closure a " +
+	"JOIN category_closure d ON a.descendant_id = d.descendant_id " +
+	"LEFT JOIN category_closure x ON x.ancestor_id = d.ancestor_id AND x.descendant_id = a.ancestor_id " +
+	"WHERE d.ancestor_id = ? AND x.ancestor_id IS NULL"
+
+// attachsubtreestmt links every node of a subtree to the new parent and its ancestors.
+var attachsubtreestmt AttachSubtreeStatement = "INSERT INTO category_closure (ancestor_id, descendant_id, depth) " +
+	"SELECT p.ancestor_id, d.descendant_id, p.depth + d.depth + 1 " +
+	"FROM category_closure p JOIN category_closure d WHERE p.descendant_id = ? AND d.ancestor_id = ?"
+
+// UpdateCategory updates a category. Changing its parent moves the whole subtree.
+func (s *CategoryService) UpdateCategory(ctx context.Context, c *catalog.Category) error {
+	if len(c.ID) == 0 {
+		return catalog.Errorf(catalog.ErrInvalid, "category with unassigned ID passed in to UpdateCategory")
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := scanCategory(tx.StmtContext(ctx, s.get).QueryRowContext(ctx, c.ID))
+		if err == sql.ErrNoRows {
+			return catalog.Errorf(catalog.ErrNotFound, "category %v not found", c.ID)
+		}
+		if err != nil {
+			return err
+		}
+		moved := current.ParentID != c.ParentID
+		if moved && c.ParentID != "" {
+			var n int
+			if err := tx.StmtContext(ctx, s.isDescendant).QueryRowContext(ctx, c.ID, c.ParentID).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				return catalog.ValidationError("category is invalid", []catalog.FieldError{
+					{Field: "parentId", Message: "must not be a descendant of the category"}})
+			}
+		}
+		if _, err := tx.StmtContext(ctx, s.update).ExecContext(ctx,
+			nullString(c.ParentID), c.Name, c.Slug, c.Position, c.ID); err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+		if !moved {
+			return nil
+		}
+		if _, err := tx.StmtContext(ctx, s.detachSubtree).ExecContext(ctx, c.ID); err != nil {
+			log.Error(err)
+			return err
+		}
+		if c.ParentID != "" {
+			if _, err := tx.StmtContext(ctx, s.attachSubtree).ExecContext(ctx, c.ParentID, c.ID); err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var deletecategorystmt DeleteCategoryStatement = "DELETE FROM category WHERE id=?"
+
+// DeleteCategory deletes a category. Categories with children cannot be deleted.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id string) error {
+	res, err := s.delete.ExecContext(ctx, id)
+	if err != nil {
+		log.Error(err)
+		if err = translateError(err); errors.Is(err, catalog.ErrConflict) {
+			return catalog.Errorf(catalog.ErrConflict, "category %v has child categories", id)
+		}
+		return err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "category %v not found", id)
+	}
+	return nil
+}
+
+var assignproductstmt AssignProductStatement = "INSERT INTO product_category (product_id, category_id) VALUES (?, ?) " +
+	"ON DUPLICATE KEY UPDATE category_id = category_id"
+
+// AssignProduct adds a product to a category. Assigning it twice has no effect.
+func (s *CategoryService) AssignProduct(ctx context.Context, categoryID, productID string) error {
+	if _, err := s.assign.ExecContext(ctx, productID, categoryID); err != nil {
+		log.Error(err)
+		if err = translateError(err); errors.Is(err, catalog.ErrInvalid) {
+			return catalog.Errorf(catalog.ErrNotFound, "category %v or product %v not found", categoryID, productID)
+		}
+		return err
+	}
+	return nil
+}
+
+var unassignproductstmt UnassignProductStatement = "DELETE FROM product_category WHERE product_id = ? AND category_id = ?"
+
+// UnassignProduct removes a product from a category.
+func (s *CategoryService) UnassignProduct(ctx context.Context, categoryID, productID string) error {
+	res, err := s.unassign.ExecContext(ctx, productID, categoryID)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "product %v is not in category %v", productID, categoryID)
+	}
+	return nil
+}
+
+var productcategoriesstmt ProductCategoriesStatement = "SELECT " + categoryColumns +
+	" FROM category c JOIN product_category pc ON pc.category_id = c.id WHERE pc.product_id = ? ORDER BY c.name"
+
+// ProductCategories returns the categories a product is directly assigned to.
+func (s *CategoryService) ProductCategories(ctx context.Context, productID string) ([]*catalog.Category, error) {
+	return queryCategories(ctx, s.productCategories, productID)
+}
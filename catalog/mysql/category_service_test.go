@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestCategoryService_CreateCategory(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT category SET parent_id=\\?, name=\\?, slug=\\?, position=\\?")
+	mock.ExpectPrepare("INSERT INTO category_closure")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT category SET").WithArgs("2", "Shirts", "shirts", 1).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("INSERT INTO category_closure").WithArgs("9", "2", "9", "9").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.categoryService.prepareSqlStmt(insertcategorystmt, insertclosurestmt)
+	c := &catalog.Category{ParentID: "2", Name: "Shirts", Slug: "shirts", Position: 1}
+	if err := client.categoryService.CreateCategory(context.Background(), c); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if c.ID != "9" {
+		t.Errorf("expected category id 9, got %v", c.ID)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCategoryService_CreateCategoryInvalid(t *testing.T) {
+	client := NewClient()
+	c := &catalog.Category{Name: "Shirts", Slug: "Shirts & Tops"}
+	if err := client.categoryService.CreateCategory(context.Background(), c); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+}
+
+func TestCategoryService_UpdateCategoryMovesSubtree(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "parent_id", "name", "slug", "position"}
+	mock.ExpectPrepare("SELECT c.id, c.parent_id, c.name, c.slug, c.position FROM category c WHERE c.id = \\?")
+	mock.ExpectPrepare("SELECT COUNT\\(\\*\\) FROM category_closure")
+	mock.ExpectPrepare("UPDATE category SET")
+	mock.ExpectPrepare("DELETE a FROM category_closure a")
+	mock.ExpectPrepare("INSERT INTO category_closure")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM category c WHERE c.id = \\?").WithArgs("9").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("9", "2", "Shirts", "shirts", 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM category_closure").WithArgs("9", "3").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE category SET").WithArgs("3", "Shirts", "shirts", 1, "9").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE a FROM category_closure a").WithArgs("9").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO category_closure").WithArgs("3", "9").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.categoryService.prepareSqlStmt(getcategorystmt, isdescendantstmt, updatecategorystmt,
+		detachsubtreestmt, attachsubtreestmt)
+	c := &catalog.Category{ID: "9", ParentID: "3", Name: "Shirts", Slug: "shirts", Position: 1}
+	if err := client.categoryService.UpdateCategory(context.Background(), c); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCategoryService_UpdateCategoryIntoOwnSubtree(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "parent_id", "name", "slug", "position"}
+	mock.ExpectPrepare("SELECT c.id, c.parent_id, c.name, c.slug, c.position FROM category c WHERE c.id = \\?")
+	mock.ExpectPrepare("SELECT COUNT\\(\\*\\) FROM category_closure")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM category c WHERE c.id = \\?").WithArgs("2").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("2", nil, "Apparel", "apparel", 0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM category_closure").WithArgs("2", "9").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.categoryService.prepareSqlStmt(getcategorystmt, isdescendantstmt)
+	c := &catalog.Category{ID: "2", ParentID: "9", Name: "Apparel", Slug: "apparel"}
+	if err := client.categoryService.UpdateCategory(context.Background(), c); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCategoryService_Breadcrumbs(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "parent_id", "name", "slug", "position"}
+	mock.ExpectPrepare("SELECT (.+) FROM category c JOIN category_closure cc")
+	mock.ExpectQuery("SELECT (.+) FROM category c JOIN category_closure cc ON cc.ancestor_id = c.id " +
+		"WHERE cc.descendant_id = \\? ORDER BY cc.depth DESC").
+		WithArgs("9").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("2", nil, "Apparel", "apparel", 0).
+			AddRow("9", "2", "Shirts", "shirts", 1))
+
+	client := NewClient()
+	client.db = db
+	client.categoryService.prepareSqlStmt(breadcrumbsstmt)
+	crumbs, err := client.categoryService.Breadcrumbs(context.Background(), "9")
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(crumbs) != 2 || crumbs[0].ParentID != "" || crumbs[1].Slug != "shirts" {
+		t.Errorf("unexpected breadcrumbs: %+v %+v", crumbs[0], crumbs[1])
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCategoryService_DeleteCategoryWithChildren(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("DELETE FROM category WHERE id=\\?")
+	mock.ExpectExec("DELETE FROM category WHERE id=\\?").WithArgs("2").
+		WillReturnError(&mysql.MySQLError{Number: errRowIsReferenced, Message: "foreign key constraint fails"})
+
+	client := NewClient()
+	client.db = db
+	client.categoryService.prepareSqlStmt(deletecategorystmt)
+	if err := client.categoryService.DeleteCategory(context.Background(), "2"); !errors.Is(err, catalog.ErrConflict) {
+		t.Errorf("expected ErrConflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
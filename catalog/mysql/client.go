@@ -1,9 +1,9 @@
 package mysql
 
 import (
-	"github.com/go-sql-driver/mysql"
 	"github.com/mvonbodun/go-package-test/catalog"
 	_ "github.com/go-sql-driver/mysql"
+	"golang.org/x/net/context"
 	"database/sql"
 	log "github.com/sirupsen/logrus"
 	"github.com/basvanbeek/ocsql"
@@ -11,7 +11,15 @@ import (
 
 type Client struct {
 	// Services
-	productService ProductService
+	productService   ProductService
+	priceService     PriceService
+	variantService   VariantService
+	categoryService  CategoryService
+	attributeService AttributeService
+	eventService     EventService
+	webhookService   WebhookService
+	apiKeyService    APIKeyService
+	tenantService    TenantService
 
 	// Reference to the database
 	db *sql.DB
@@ -21,14 +29,22 @@ func NewClient() *Client {
 	c := &Client{
 	}
 	c.productService.client = c
+	c.priceService.client = c
+	c.variantService.client = c
+	c.categoryService.client = c
+	c.attributeService.client = c
+	c.eventService.client = c
+	c.webhookService.client = c
+	c.apiKeyService.client = c
+	c.tenantService.client = c
 	return c
 }
 
 // Open opens the connection to the MySql database
 func (c *Client) Open(config MySQLConfig) error {
 	log.Debug("Before opening the database")
-	// Check database and table exist.  If not, create them.
-	if err := config.ensureTableExists(); err != nil {
+	// Check the database exists.  If not, create it.
+	if err := config.ensureDatabaseExists(); err != nil {
 		return err
 	}
 	// Setup the OpenCensus database tracing
@@ -36,15 +52,7 @@ func (c *Client) Open(config MySQLConfig) error {
 	if err != nil {
 		log.Errorf("Failed to register the ocsql driver: %v", err)
 	}
-	mc := mysql.NewConfig()
-	mc.User = config.Username
-	mc.Passwd = config.Password
-	mc.Net = "tcp"
-	mc.Addr = config.Host
-	mc.Params = map[string]string{"charset": "utf8"}
-	mc.DBName = "catalog"
-	log.Infof("formattedDSN: %v", mc.FormatDSN())
-	db, err := sql.Open(ocDriverName, mc.FormatDSN())
+	db, err := sql.Open(ocDriverName, config.dsn("catalog"))
 	if err != nil {
 		log.Errorf("Failed to open the catalog Database: %v",err)
 	}
@@ -55,14 +63,73 @@ func (c *Client) Open(config MySQLConfig) error {
 	if err != nil {
 		log.Errorf("Could not ping the catalog database: %v\n", err)
 	}
+	// Bring the schema up to date
+	if !config.SkipMigrations {
+		if err := c.migrate(); err != nil {
+			return err
+		}
+	}
 	// Prepare the SQL statements
 	err = c.productService.prepareSqlStmts()
+	if err == nil {
+		err = c.priceService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.variantService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.categoryService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.attributeService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.eventService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.webhookService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.apiKeyService.prepareSqlStmts()
+	}
+	if err == nil {
+		err = c.tenantService.prepareSqlStmts()
+	}
 	if err != nil {
 		log.Errorf("mysql client: Failed to prepare sql statements: %v", err)
 	}
 	return err
 }
 
+// migrate applies any pending schema migrations.
+func (c *Client) migrate() error {
+	m, err := newMigrator(c.db)
+	if err != nil {
+		return err
+	}
+	n, err := m.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	log.Infof("Applied %d schema migrations", n)
+	return nil
+}
+
+// inTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func (c *Client) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Errorf("mysql: rollback failed: %v", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
 // Close closes the underlying MySql database
 func (c *Client) Close() error {
 	if c.db != nil {
@@ -76,3 +143,43 @@ func (c *Client) Close() error {
 func (c *Client) ProductService() catalog.ProductService {
 	return &c.productService
 }
+
+// PriceService returns the price service associated with the client
+func (c *Client) PriceService() catalog.PriceService {
+	return &c.priceService
+}
+
+// VariantService returns the variant service associated with the client
+func (c *Client) VariantService() catalog.VariantService {
+	return &c.variantService
+}
+
+// CategoryService returns the category service associated with the client
+func (c *Client) CategoryService() catalog.CategoryService {
+	return &c.categoryService
+}
+
+// AttributeService returns the attribute service associated with the client
+func (c *Client) AttributeService() catalog.AttributeService {
+	return &c.attributeService
+}
+
+// EventService returns the event service associated with the client
+func (c *Client) EventService() catalog.EventService {
+	return &c.eventService
+}
+
+// WebhookService returns the webhook service associated with the client
+func (c *Client) WebhookService() catalog.WebhookService {
+	return &c.webhookService
+}
+
+// APIKeyService returns the API key service associated with the client
+func (c *Client) APIKeyService() catalog.APIKeyService {
+	return &c.apiKeyService
+}
+
+// TenantService returns the tenant service associated with the client
+func (c *Client) TenantService() catalog.TenantService {
+	return &c.tenantService
+}
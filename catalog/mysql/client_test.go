@@ -9,5 +9,17 @@ func TestNewClient(t *testing.T) {
 	if c.productService.client == nil {
 		t.Errorf("failed to return productService client")
 	}
+	if c.priceService.client == nil {
+		t.Errorf("failed to return priceService client")
+	}
+	if c.variantService.client == nil {
+		t.Errorf("failed to return variantService client")
+	}
+	if c.categoryService.client == nil {
+		t.Errorf("failed to return categoryService client")
+	}
+	if c.attributeService.client == nil {
+		t.Errorf("failed to return attributeService client")
+	}
 }
 
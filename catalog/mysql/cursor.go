@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// cursor is the keyset position encoded into the opaque page cursor
+// handed out to callers. It records the sort order it was issued for and
+// the sort values and id of the last row returned.
+type cursor struct {
+	Sort   string   `json:"s,omitempty"`
+	Values []string `json:"v,omitempty"`
+	ID     int64    `json:"id"`
+}
+
+// encodeCursor returns the opaque string form of a cursor.
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses an opaque cursor. An empty string is the start of the listing.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, catalog.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, catalog.ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+)
+
+// MySQL server error numbers mapped to catalog errors.
+const (
+	errDupEntry            = 1062
+	errDataTooLong         = 1406
+	errNoReferencedRow     = 1452
+	errRowIsReferenced     = 1451
+	errBadNull             = 1048
+	errTruncatedWrongValue = 1292
+)
+
+// translateError converts constraint violations reported by MySQL into
+// catalog domain errors. Other errors are returned unchanged. The messages
+// reach the clients, so the text of the server, naming keys, columns and
+// values, is only logged.
+func translateError(err error) error {
+	mErr, ok := err.(*mysql.MySQLError)
+	if !ok {
+		return err
+	}
+	switch mErr.Number {
+	case errDupEntry:
+		log.Debugf("MySQL error %d: %s", mErr.Number, mErr.Message)
+		return catalog.Errorf(catalog.ErrConflict, "duplicate value")
+	case errRowIsReferenced:
+		return catalog.Errorf(catalog.ErrConflict, "record is still referenced")
+	case errDataTooLong, errBadNull, errTruncatedWrongValue, errNoReferencedRow:
+		log.Debugf("MySQL error %d: %s", mErr.Number, mErr.Message)
+		return catalog.Errorf(catalog.ErrInvalid, "invalid value")
+	}
+	return err
+}
+
+// productCodeKey is the unique index on product.productcode.
+const productCodeKey = "product_productcode"
+
+// translateProductError is translateError for product writes, reporting a
+// duplicate product code as a conflict on the productCode field.
+func translateProductError(err error, code string) error {
+	if mErr, ok := err.(*mysql.MySQLError); ok && mErr.Number == errDupEntry && strings.Contains(mErr.Message, productCodeKey) {
+		return &catalog.Error{
+			Kind:    catalog.ErrConflict,
+			Message: fmt.Sprintf("product code %q is already in use", code),
+			Fields:  []catalog.FieldError{{Field: "productCode", Message: "is already in use"}},
+		}
+	}
+	return translateError(err)
+}
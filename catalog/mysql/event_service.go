@@ -0,0 +1,218 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure EventService implements catalog.EventService
+var _ catalog.EventService = &EventService{}
+
+// EventService relays the product events queued in the product_event
+// outbox table.
+type EventService struct {
+	client        *Client
+	pending       *sql.Stmt
+	markPublished *sql.Stmt
+	after         *sql.Stmt
+	lastID        *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	InsertEventStatement   SqlStatement
+	PendingEventsStatement SqlStatement
+	MarkPublishedStatement SqlStatement
+	EventsAfterStatement   SqlStatement
+	LastEventIDStatement   SqlStatement
+)
+
+// eventColumns is the column list selected for every event read.
+const eventColumns = "id, event_type, tenant_id, product_id, version, created_at, payload"
+
+var inserteventstmt InsertEventStatement = "INSERT product_event SET event_type=?, tenant_id=?, product_id=?, version=?, payload=?, " +
+	"created_at=UTC_TIMESTAMP(6)"
+
+// pendingeventsstmt locks the events it reads, so that relays running in
+// other instances wait instead of publishing the same events.
+var pendingeventsstmt PendingEventsStatement = "SELECT " + eventColumns + " FROM product_event " +
+	"WHERE published_at IS NULL ORDER BY id LIMIT ? FOR UPDATE"
+
+var markpublishedstmt MarkPublishedStatement = "UPDATE product_event SET published_at=UTC_TIMESTAMP(6) WHERE id=?"
+
+// eventSettleWindow is how old events must be before the change feed
+// reads them. Event ids are taken when the event is written, not when its
+// transaction commits, so a reader that went past an id could otherwise
+// miss an event with a lower id committed after it. Product transactions
+// commit well within the window.
+const eventSettleWindow = 5 * time.Second
+
+var eventsafterstmt EventsAfterStatement = "SELECT " + eventColumns + " FROM product_event " +
+	"WHERE tenant_id = ? AND id > ? AND created_at <= UTC_TIMESTAMP(6) - INTERVAL ? MICROSECOND ORDER BY id LIMIT ?"
+
+var lasteventidstmt LastEventIDStatement = "SELECT COALESCE(MAX(id), 0) FROM product_event " +
+	"WHERE tenant_id = ? AND created_at <= UTC_TIMESTAMP(6) - INTERVAL ? MICROSECOND"
+
+// eventTypes maps the history actions to the events they raise.
+var eventTypes = map[string]string{
+	catalog.HistoryCreate:  catalog.EventProductCreated,
+	catalog.HistoryUpdate:  catalog.EventProductUpdated,
+	catalog.HistoryDelete:  catalog.EventProductDeleted,
+	catalog.HistoryRestore: catalog.EventProductRestored,
+}
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *EventService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(pendingeventsstmt, markpublishedstmt, eventsafterstmt, lasteventidstmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *EventService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case PendingEventsStatement:
+			err = prepare(&s.pending, "pending events", string(stmt))
+		case MarkPublishedStatement:
+			err = prepare(&s.markPublished, "mark published", string(stmt))
+		case EventsAfterStatement:
+			err = prepare(&s.after, "events after", string(stmt))
+		case LastEventIDStatement:
+			err = prepare(&s.lastID, "last event id", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordEvent queues the event of a product change in the outbox within
+// the transaction making the change.
+func (s *ProductService) recordEvent(ctx context.Context, tx *sql.Tx, eventType, id string, version int64, product *catalog.Product) error {
+	payload, err := productDoc(product)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, s.insertEvent).ExecContext(ctx, eventType, catalog.TenantFromContext(ctx), id, version, payload); err != nil {
+		log.Errorf("Error queueing the product event: %v, %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// scanEvent scans a row selected with eventColumns.
+func scanEvent(row rowScanner) (*catalog.ProductEvent, error) {
+	var e catalog.ProductEvent
+	var payload []byte
+	if err := row.Scan(&e.ID, &e.Type, &e.TenantID, &e.ProductID, &e.Version, &e.OccurredAt, &payload); err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		e.Product = &catalog.Product{}
+		if err := json.Unmarshal(payload, e.Product); err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+// PublishPending publishes the oldest unpublished events in order and
+// marks them published in the same transaction. The events stay locked
+// while they are published, so publishers should not block for long. The
+// events published before a failing one stay marked published.
+func (s *EventService) PublishPending(ctx context.Context, p catalog.EventPublisher, limit int) (int, error) {
+	published := 0
+	var publishErr error
+	err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.StmtContext(ctx, s.pending).QueryContext(ctx, limit)
+		if err != nil {
+			log.Errorf("Error retrieving pending events: %v", err)
+			return err
+		}
+		var events []*catalog.ProductEvent
+		for rows.Next() {
+			e, err := scanEvent(rows)
+			if err != nil {
+				rows.Close()
+				log.Errorf("Error scanning over rows: %v", err)
+				return err
+			}
+			events = append(events, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			log.Errorf("Error iterating over rows: %v", err)
+			return err
+		}
+		for _, e := range events {
+			if publishErr = p.Publish(ctx, e); publishErr != nil {
+				break
+			}
+			if _, err := tx.StmtContext(ctx, s.markPublished).ExecContext(ctx, e.ID); err != nil {
+				log.Error(err)
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// EventsAfter returns up to limit events of the context's tenant numbered
+// above after, in order, leaving out those younger than eventSettleWindow.
+func (s *EventService) EventsAfter(ctx context.Context, after int64, limit int) ([]*catalog.ProductEvent, error) {
+	rows, err := s.after.QueryContext(ctx, catalog.TenantFromContext(ctx), after, settleMicroseconds(), limit)
+	if err != nil {
+		log.Errorf("Error retrieving the events after %d: %v", after, err)
+		return nil, err
+	}
+	defer rows.Close()
+	events := []*catalog.ProductEvent{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastEventID returns the number of the newest event of the context's
+// tenant older than eventSettleWindow, or 0 when there are none.
+func (s *EventService) LastEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.lastID.QueryRowContext(ctx, catalog.TenantFromContext(ctx), settleMicroseconds()).Scan(&id); err != nil {
+		log.Errorf("Error retrieving the last event id: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// settleMicroseconds returns eventSettleWindow for the INTERVAL of the
+// statements.
+func settleMicroseconds() int64 {
+	return int64(eventSettleWindow / time.Microsecond)
+}
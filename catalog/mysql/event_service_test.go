@@ -0,0 +1,156 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var eventColumnNames = []string{"id", "event_type", "tenant_id", "product_id", "version", "created_at", "payload"}
+
+// publishFunc adapts a function to a catalog.EventPublisher.
+type publishFunc func(ctx context.Context, e *catalog.ProductEvent) error
+
+func (f publishFunc) Publish(ctx context.Context, e *catalog.ProductEvent) error {
+	return f(ctx, e)
+}
+
+func TestEventService_PublishPending(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event " +
+		"WHERE published_at IS NULL ORDER BY id LIMIT \\? FOR UPDATE")
+	mock.ExpectPrepare("UPDATE product_event SET published_at=UTC_TIMESTAMP\\(6\\) WHERE id=\\?")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(eventColumnNames).
+			AddRow(11, "product.updated", "default", "5", 4, createdAt, `{"productId":"5","productCode":"tee","version":4}`).
+			AddRow(12, "product.deleted", "default", "5", 5, createdAt, nil))
+	mock.ExpectExec("UPDATE product_event SET published_at").WithArgs(11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE product_event SET published_at").WithArgs(12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.eventService.prepareSqlStmt(pendingeventsstmt, markpublishedstmt)
+
+	var published []*catalog.ProductEvent
+	p := publishFunc(func(ctx context.Context, e *catalog.ProductEvent) error {
+		published = append(published, e)
+		return nil
+	})
+	n, err := client.eventService.PublishPending(context.Background(), p, 100)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 events published, got %d, %v", n, err)
+	}
+	if e := published[0]; e.ID != 11 || e.Type != catalog.EventProductUpdated || e.ProductID != "5" || e.Product.Version != 4 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e := published[1]; e.Type != catalog.EventProductDeleted || e.Product != nil {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEventService_PublishPendingFailedPublish(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event")
+	mock.ExpectPrepare("UPDATE product_event SET published_at")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(eventColumnNames).
+			AddRow(11, "product.created", "default", "5", 1, createdAt, `{"productId":"5","version":1}`).
+			AddRow(12, "product.updated", "default", "5", 2, createdAt, `{"productId":"5","version":2}`).
+			AddRow(13, "product.updated", "default", "5", 3, createdAt, `{"productId":"5","version":3}`))
+	mock.ExpectExec("UPDATE product_event SET published_at").WithArgs(11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.eventService.prepareSqlStmt(pendingeventsstmt, markpublishedstmt)
+
+	// The event after the failing one is not published either.
+	failure := errors.New("broker unavailable")
+	p := publishFunc(func(ctx context.Context, e *catalog.ProductEvent) error {
+		if e.ID == 12 {
+			return failure
+		}
+		if e.ID == 13 {
+			t.Errorf("expected event 13 not to be published")
+		}
+		return nil
+	})
+	n, err := client.eventService.PublishPending(context.Background(), p, 100)
+	if err != failure || n != 1 {
+		t.Errorf("expected 1 event published before the failure, got %d, %v", n, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEventService_EventsAfter(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event " +
+		"WHERE tenant_id = \\? AND id > \\? AND created_at <= UTC_TIMESTAMP\\(6\\) - INTERVAL \\? MICROSECOND ORDER BY id LIMIT \\?")
+	mock.ExpectPrepare("SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM product_event " +
+		"WHERE tenant_id = \\? AND created_at <= UTC_TIMESTAMP\\(6\\) - INTERVAL \\? MICROSECOND")
+	mock.ExpectQuery("SELECT id, event_type, tenant_id, product_id, version, created_at, payload FROM product_event").
+		WithArgs("acme", 10, 5000000, 50).
+		WillReturnRows(sqlmock.NewRows(eventColumnNames).
+			AddRow(11, "product.created", "acme", "5", 1, createdAt, `{"productId":"5","version":1}`).
+			AddRow(12, "product.deleted", "acme", "5", 2, createdAt, nil))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM product_event").
+		WithArgs("acme", 5000000).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+
+	client := NewClient()
+	client.db = db
+	client.eventService.prepareSqlStmt(eventsafterstmt, lasteventidstmt)
+
+	// Only the settled events of the context's tenant are read.
+	ctx := catalog.WithTenant(context.Background(), "acme")
+	events, err := client.eventService.EventsAfter(ctx, 10, 50)
+	if err != nil || len(events) != 2 || events[0].ID != 11 || events[0].TenantID != "acme" || events[1].Product != nil {
+		t.Errorf("unexpected events: %+v, %v", events, err)
+	}
+	if id, err := client.eventService.LastEventID(ctx); err != nil || id != 12 {
+		t.Errorf("expected last event 12, got %d, %v", id, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,312 @@
+package mysql
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// migrationFiles holds the schema migrations compiled into the binary.
+// Each version has an up and a down file named NNNN_description.up.sql
+// and NNNN_description.down.sql. Statements within a file are separated
+// by a semicolon at the end of a line.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the named MySQL lock held while migrations run so that
+// replicas starting at the same time apply them only once.
+const migrationLock = "catalog.schema_migrations"
+
+// migrationLockTimeout is how long to wait for another replica to finish migrating.
+const migrationLockTimeout = 5 * time.Minute
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT UNSIGNED NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (version)
+)`
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the embedded schema migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// loadMigrations reads and orders the embedded migrations.
+func loadMigrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("mysql: read migrations: %v", err)
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("mysql: unexpected migration file %q", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		b, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("mysql: read migration %q: %v", e.Name(), err)
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("mysql: migration %d has two names: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("mysql: migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into its statements.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current []string
+	for _, line := range strings.Split(script, "\n") {
+		current = append(current, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if stmt := strings.TrimSpace(strings.Join(current, "\n")); stmt != ";" {
+				stmts = append(stmts, strings.TrimSuffix(stmt, ";"))
+			}
+			current = nil
+		}
+	}
+	if stmt := strings.TrimSpace(strings.Join(current, "\n")); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+// NewMigrator returns a Migrator for the catalog database, creating the
+// database if it does not exist yet. Close the Migrator when done.
+func NewMigrator(config MySQLConfig) (*Migrator, error) {
+	if err := config.ensureDatabaseExists(); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("mysql", config.dsn("catalog"))
+	if err != nil {
+		return nil, fmt.Errorf("mysql: could not get a connection: %v", err)
+	}
+	m, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// newMigrator returns a Migrator using an open database.
+func newMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Close closes the Migrator's database connection.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// withLock runs fn on a single connection holding the migration lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("mysql: migration connection: %v", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLock, int(migrationLockTimeout.Seconds())).
+		Scan(&locked); err != nil {
+		return fmt.Errorf("mysql: acquire migration lock: %v", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("mysql: timed out waiting for the migration lock")
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLock); err != nil {
+			log.Warningf("mysql: release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("mysql: create schema_migrations: %v", err)
+	}
+	return fn(conn)
+}
+
+// applied returns the applied migration versions and when they were applied.
+func applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("mysql: read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	versions := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("mysql: read schema_migrations: %v", err)
+		}
+		versions[version] = at
+	}
+	return versions, rows.Err()
+}
+
+// run executes each statement of a migration script. A query whose
+// comment has an "-- abort: <reason>" line guards the statements after it:
+// the migration fails with the reason when the query returns a row.
+func run(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if reason, ok := abortReason(stmt); ok {
+			var found int
+			err := conn.QueryRowContext(ctx, stmt).Scan(&found)
+			if err == nil {
+				return fmt.Errorf("aborted: %s", reason)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abortReason returns the reason of the "-- abort:" line of the comment
+// leading the statement, if any.
+func abortReason(stmt string) (string, bool) {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if strings.HasPrefix(line, "-- abort:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "-- abort:")), true
+		}
+	}
+	return "", false
+}
+
+// Up applies every pending migration in version order and returns the
+// number applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			log.Infof("mysql: applying migration %d_%s", mig.Version, mig.Name)
+			if err := run(ctx, conn, mig.Up); err != nil {
+				return fmt.Errorf("mysql: migration %d_%s up: %v", mig.Version, mig.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+				mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("mysql: record migration %d: %v", mig.Version, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the most recently applied steps migrations and returns the
+// number reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			log.Infof("mysql: reverting migration %d_%s", mig.Version, mig.Name)
+			if err := run(ctx, conn, mig.Down); err != nil {
+				return fmt.Errorf("mysql: migration %d_%s down: %v", mig.Version, mig.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", mig.Version); err != nil {
+				return fmt.Errorf("mysql: unrecord migration %d: %v", mig.Version, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			at, ok := done[mig.Version]
+			status = append(status, MigrationStatus{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at})
+		}
+		return nil
+	})
+	return status, err
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 || migrations[0].Name != "create_product" {
+		t.Fatalf("unexpected first migration: %+v", migrations)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migrations out of order: %d after %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := "CREATE TABLE a (\n\tid INT\n);\n\nALTER TABLE a ADD COLUMN b INT;\nDROP TABLE c"
+	want := []string{"CREATE TABLE a (\n\tid INT\n)", "ALTER TABLE a ADD COLUMN b INT", "DROP TABLE c"}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestAbortReason(t *testing.T) {
+	stmt := "-- Guards the rollback.\n-- abort: rows remain\nSELECT 1 FROM a LIMIT 1"
+	if reason, ok := abortReason(stmt); !ok || reason != "rows remain" {
+		t.Errorf("expected the abort reason, got %q, %v", reason, ok)
+	}
+	if _, ok := abortReason("-- Drops a.\nDROP TABLE a"); ok {
+		t.Error("expected no abort reason for a plain statement")
+	}
+}
+
+func TestMigrator_Up(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE one (id INT);", Down: "DROP TABLE one;"},
+		{Version: 2, Name: "two", Up: "CREATE TABLE two (id INT);", Down: "DROP TABLE two;"},
+	}}
+
+	mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").WithArgs(migrationLock, 300).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectExec("CREATE TABLE two").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "two").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SELECT RELEASE_LOCK\\(\\?\\)").WithArgs(migrationLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	n, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 migration applied, got %d", n)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrator_DownAborted(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE one (id INT);",
+			Down: "-- abort: rows remain\nSELECT 1 FROM one LIMIT 1;\nDROP TABLE one;"},
+	}}
+
+	mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").WithArgs(migrationLock, 300).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectQuery("SELECT 1 FROM one LIMIT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectExec("SELECT RELEASE_LOCK\\(\\?\\)").WithArgs(migrationLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The table is not dropped while the guard finds rows.
+	if _, err := m.Down(context.Background(), 1); err == nil || !strings.Contains(err.Error(), "aborted: rows remain") {
+		t.Errorf("expected the rollback to be aborted, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrator_LockTimeout(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+
+	m := &Migrator{db: db}
+	if _, err := m.Up(context.Background()); err == nil {
+		t.Error("expected error, but got none")
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
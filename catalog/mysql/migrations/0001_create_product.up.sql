@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS product (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	productcode VARCHAR(255) NULL,
+	shortdesc VARCHAR(255) NULL,
+	longdesc text NULL,
+	PRIMARY KEY (id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS price;
+DROP TABLE IF EXISTS price_list;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS price_list (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	code VARCHAR(64) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY price_list_code (code)
+);
+
+CREATE TABLE IF NOT EXISTS price (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	product_id INT UNSIGNED NOT NULL,
+	price_list_id INT UNSIGNED NOT NULL,
+	currency CHAR(3) NOT NULL,
+	list_amount BIGINT NOT NULL,
+	sale_amount BIGINT NULL,
+	valid_from DATETIME NULL,
+	valid_to DATETIME NULL,
+	PRIMARY KEY (id),
+	KEY price_product (product_id, price_list_id, currency),
+	CONSTRAINT price_product_fk FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE CASCADE,
+	CONSTRAINT price_price_list_fk FOREIGN KEY (price_list_id) REFERENCES price_list (id)
+);
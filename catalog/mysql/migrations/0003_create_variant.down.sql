@@ -0,0 +1,6 @@
+ALTER TABLE price
+	DROP FOREIGN KEY price_variant_fk,
+	DROP COLUMN variant_id;
+
+DROP TABLE IF EXISTS variant_option;
+DROP TABLE IF EXISTS variant;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS variant (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	product_id INT UNSIGNED NOT NULL,
+	sku VARCHAR(255) NOT NULL,
+	gtin VARCHAR(14) NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY variant_sku (sku),
+	UNIQUE KEY variant_gtin (gtin),
+	KEY variant_product (product_id),
+	CONSTRAINT variant_product_fk FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS variant_option (
+	variant_id INT UNSIGNED NOT NULL,
+	axis VARCHAR(64) NOT NULL,
+	value VARCHAR(255) NOT NULL,
+	PRIMARY KEY (variant_id, axis),
+	CONSTRAINT variant_option_variant_fk FOREIGN KEY (variant_id) REFERENCES variant (id) ON DELETE CASCADE
+);
+
+ALTER TABLE price
+	ADD COLUMN variant_id INT UNSIGNED NULL AFTER product_id,
+	ADD CONSTRAINT price_variant_fk FOREIGN KEY (variant_id) REFERENCES variant (id) ON DELETE CASCADE;
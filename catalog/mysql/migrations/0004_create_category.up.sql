@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS category (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	parent_id INT UNSIGNED NULL,
+	name VARCHAR(255) NOT NULL,
+	slug VARCHAR(255) NOT NULL,
+	position INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	UNIQUE KEY category_slug (slug),
+	KEY category_parent (parent_id, position),
+	CONSTRAINT category_parent_fk FOREIGN KEY (parent_id) REFERENCES category (id)
+);
+
+CREATE TABLE IF NOT EXISTS category_closure (
+	ancestor_id INT UNSIGNED NOT NULL,
+	descendant_id INT UNSIGNED NOT NULL,
+	depth INT UNSIGNED NOT NULL,
+	PRIMARY KEY (ancestor_id, descendant_id),
+	KEY category_closure_descendant (descendant_id, depth),
+	CONSTRAINT category_closure_ancestor_fk FOREIGN KEY (ancestor_id) REFERENCES category (id) ON DELETE CASCADE,
+	CONSTRAINT category_closure_descendant_fk FOREIGN KEY (descendant_id) REFERENCES category (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_category (
+	product_id INT UNSIGNED NOT NULL,
+	category_id INT UNSIGNED NOT NULL,
+	PRIMARY KEY (product_id, category_id),
+	KEY product_category_category (category_id, product_id),
+	CONSTRAINT product_category_product_fk FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE CASCADE,
+	CONSTRAINT product_category_category_fk FOREIGN KEY (category_id) REFERENCES category (id) ON DELETE CASCADE
+);
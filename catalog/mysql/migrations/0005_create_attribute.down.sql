@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_attribute;
+DROP TABLE IF EXISTS attribute_definition;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS attribute_definition (
+	name VARCHAR(64) NOT NULL,
+	type VARCHAR(16) NOT NULL,
+	allowed_values TEXT NULL,
+	unit VARCHAR(32) NOT NULL DEFAULT '',
+	required BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (name)
+);
+
+CREATE TABLE IF NOT EXISTS product_attribute (
+	product_id INT UNSIGNED NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	value VARCHAR(255) NOT NULL,
+	value_number DOUBLE NULL,
+	PRIMARY KEY (product_id, name),
+	KEY product_attribute_value (name, value),
+	KEY product_attribute_number (name, value_number),
+	CONSTRAINT product_attribute_product_fk FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE CASCADE,
+	CONSTRAINT product_attribute_definition_fk FOREIGN KEY (name) REFERENCES attribute_definition (name)
+);
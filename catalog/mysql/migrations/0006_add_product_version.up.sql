@@ -0,0 +1,2 @@
+ALTER TABLE product
+	ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE product
+	DROP INDEX product_productcode;
@@ -0,0 +1,4 @@
+-- Fails with a duplicate entry error while products share a code; give
+-- them distinct codes and run the migration again.
+ALTER TABLE product
+	ADD UNIQUE KEY product_productcode (productcode);
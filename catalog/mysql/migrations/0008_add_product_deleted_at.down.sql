@@ -0,0 +1,14 @@
+-- Rolling back loses the deleted_at marks, which would turn soft-deleted
+-- products back into live ones. The rollback is refused before changing
+-- anything while soft-deleted products exist; purge or restore them first.
+-- abort: soft-deleted products exist; purge or restore them before reverting 0008
+SELECT 1 FROM product WHERE deleted_at IS NOT NULL LIMIT 1;
+
+ALTER TABLE product
+	DROP INDEX product_productcode,
+	DROP COLUMN live_productcode,
+	ADD UNIQUE KEY product_productcode (productcode);
+
+ALTER TABLE product
+	DROP INDEX product_deleted_at,
+	DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+ALTER TABLE product
+	ADD COLUMN deleted_at DATETIME NULL,
+	ADD KEY product_deleted_at (deleted_at);
+
+-- Product codes only need to be unique among products that are not
+-- deleted, so a deleted product's code can be reused.
+ALTER TABLE product
+	ADD COLUMN live_productcode VARCHAR(255) AS (IF(deleted_at IS NULL, productcode, NULL)) STORED,
+	DROP INDEX product_productcode,
+	ADD UNIQUE KEY product_productcode (live_productcode);
@@ -0,0 +1,5 @@
+ALTER TABLE product
+	DROP INDEX product_status,
+	DROP COLUMN unpublish_at,
+	DROP COLUMN publish_at,
+	DROP COLUMN status;
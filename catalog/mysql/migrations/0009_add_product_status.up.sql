@@ -0,0 +1,8 @@
+ALTER TABLE product
+	ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'draft',
+	ADD COLUMN publish_at DATETIME NULL,
+	ADD COLUMN unpublish_at DATETIME NULL,
+	ADD KEY product_status (status);
+
+-- Products created before the lifecycle existed are already public.
+UPDATE product SET status = 'active';
@@ -0,0 +1,15 @@
+-- Every change of a product is recorded with the product as it was before
+-- and after. The rows outlive the product so purged products stay audited.
+CREATE TABLE IF NOT EXISTS product_history (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	product_id INT UNSIGNED NOT NULL,
+	version INT UNSIGNED NOT NULL,
+	action VARCHAR(16) NOT NULL,
+	actor VARCHAR(255) NOT NULL DEFAULT '',
+	changed_at DATETIME(6) NOT NULL,
+	before_doc JSON NULL,
+	after_doc JSON NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY product_history_version (product_id, version),
+	KEY product_history_changed_at (product_id, changed_at)
+);
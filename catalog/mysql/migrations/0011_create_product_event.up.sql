@@ -0,0 +1,13 @@
+-- The outbox of product events. Events are written in the transaction that
+-- changes the product and marked published once the relay delivered them.
+CREATE TABLE IF NOT EXISTS product_event (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	event_type VARCHAR(32) NOT NULL,
+	product_id INT UNSIGNED NOT NULL,
+	version INT UNSIGNED NOT NULL,
+	payload JSON NULL,
+	created_at DATETIME(6) NOT NULL,
+	published_at DATETIME(6) NULL,
+	PRIMARY KEY (id),
+	KEY product_event_published (published_at, id)
+);
@@ -0,0 +1,30 @@
+-- Webhook subscriptions and the deliveries of product events to them.
+-- event_types is a comma separated list; empty subscribes to every event.
+CREATE TABLE IF NOT EXISTS webhook (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	url VARCHAR(2048) NOT NULL,
+	event_types VARCHAR(255) NOT NULL DEFAULT '',
+	secret VARCHAR(255) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	webhook_id INT UNSIGNED NOT NULL,
+	event_id BIGINT UNSIGNED NOT NULL,
+	event_type VARCHAR(32) NOT NULL,
+	payload JSON NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INT UNSIGNED NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME(6) NULL,
+	last_attempt_at DATETIME(6) NULL,
+	last_status_code INT NOT NULL DEFAULT 0,
+	last_error VARCHAR(1024) NOT NULL DEFAULT '',
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY webhook_delivery_event (webhook_id, event_id),
+	KEY webhook_delivery_due (next_attempt_at),
+	KEY webhook_delivery_status (webhook_id, status, id),
+	CONSTRAINT webhook_delivery_webhook_fk FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+-- API keys authenticating machine clients. Only the SHA-256 hashes of the
+-- keys are stored; after a rotation previous_hash stays valid until
+-- previous_expires_at. scopes is a space separated list.
+CREATE TABLE IF NOT EXISTS api_key (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	previous_hash CHAR(64) NULL,
+	previous_expires_at DATETIME(6) NULL,
+	scopes VARCHAR(1024) NOT NULL,
+	expires_at DATETIME(6) NULL,
+	last_used_at DATETIME(6) NULL,
+	rotated_at DATETIME(6) NULL,
+	revoked_at DATETIME(6) NULL,
+	created_by VARCHAR(255) NOT NULL DEFAULT '',
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY api_key_hash (hash),
+	KEY api_key_previous_hash (previous_hash)
+);
@@ -0,0 +1,19 @@
+ALTER TABLE api_key
+	DROP COLUMN tenant_id;
+
+ALTER TABLE product_event
+	DROP KEY product_event_tenant,
+	DROP COLUMN tenant_id;
+
+ALTER TABLE product_history
+	DROP COLUMN tenant_id;
+
+-- Fails with a duplicate entry error while tenants share a product code.
+ALTER TABLE product
+	DROP FOREIGN KEY product_tenant_fk,
+	DROP KEY product_tenant_deleted,
+	DROP INDEX product_productcode,
+	DROP COLUMN tenant_id,
+	ADD UNIQUE KEY product_productcode (live_productcode);
+
+DROP TABLE IF EXISTS tenant;
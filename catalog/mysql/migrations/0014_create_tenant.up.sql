@@ -0,0 +1,30 @@
+-- Tenants are the merchants whose catalogs share the deployment. Products,
+-- their history, their events and the API keys belong to a tenant; the
+-- rows that predate tenants belong to the default tenant.
+CREATE TABLE IF NOT EXISTS tenant (
+	id VARCHAR(63) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	max_products INT UNSIGNED NOT NULL DEFAULT 0,
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (id)
+);
+
+INSERT IGNORE INTO tenant (id, name, created_at) VALUES ('default', 'Default', UTC_TIMESTAMP(6));
+
+-- The codes of live products are only unique within a tenant.
+ALTER TABLE product
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+	DROP INDEX product_productcode,
+	ADD UNIQUE KEY product_productcode (tenant_id, live_productcode),
+	ADD KEY product_tenant_deleted (tenant_id, deleted_at),
+	ADD CONSTRAINT product_tenant_fk FOREIGN KEY (tenant_id) REFERENCES tenant (id);
+
+ALTER TABLE product_history
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE product_event
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+	ADD KEY product_event_tenant (tenant_id, id);
+
+ALTER TABLE api_key
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
@@ -0,0 +1,6 @@
+ALTER TABLE webhook_delivery
+	DROP COLUMN tenant_id;
+
+ALTER TABLE webhook
+	DROP KEY webhook_tenant,
+	DROP COLUMN tenant_id;
@@ -0,0 +1,9 @@
+-- Webhooks belong to a tenant and only receive the events of its products.
+-- The webhooks that predate this belong to the default tenant, like its
+-- products.
+ALTER TABLE webhook
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+	ADD KEY webhook_tenant (tenant_id, id);
+
+ALTER TABLE webhook_delivery
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
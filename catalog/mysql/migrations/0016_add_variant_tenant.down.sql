@@ -0,0 +1,7 @@
+-- Fails with a duplicate entry error while tenants share a SKU or GTIN.
+ALTER TABLE variant
+	DROP INDEX variant_sku,
+	DROP INDEX variant_gtin,
+	DROP COLUMN tenant_id,
+	ADD UNIQUE KEY variant_sku (sku),
+	ADD UNIQUE KEY variant_gtin (gtin);
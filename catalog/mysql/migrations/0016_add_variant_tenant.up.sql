@@ -0,0 +1,12 @@
+-- The SKUs and GTINs of variants are only unique within a tenant, like the
+-- codes of products. Variants take the tenant of their product.
+ALTER TABLE variant
+	ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' AFTER product_id;
+
+UPDATE variant v JOIN product p ON p.id = v.product_id SET v.tenant_id = p.tenant_id;
+
+ALTER TABLE variant
+	DROP INDEX variant_sku,
+	DROP INDEX variant_gtin,
+	ADD UNIQUE KEY variant_sku (tenant_id, sku),
+	ADD UNIQUE KEY variant_gtin (tenant_id, gtin);
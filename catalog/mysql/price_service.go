@@ -0,0 +1,293 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure PriceService implements catalog.PriceService
+var _ catalog.PriceService = &PriceService{}
+
+// PriceService represents a service for managing price lists and prices.
+type PriceService struct {
+	client          *Client
+	getPriceList    *sql.Stmt
+	listPriceLists  *sql.Stmt
+	insertPriceList *sql.Stmt
+	getPrice        *sql.Stmt
+	listPrices      *sql.Stmt
+	insertPrice     *sql.Stmt
+	updatePrice     *sql.Stmt
+	deletePrice     *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetPriceListStatement    SqlStatement
+	ListPriceListsStatement  SqlStatement
+	InsertPriceListStatement SqlStatement
+	GetPriceStatement        SqlStatement
+	ListPricesStatement      SqlStatement
+	InsertPriceStatement     SqlStatement
+	UpdatePriceStatement     SqlStatement
+	DeletePriceStatement     SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *PriceService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getpriceliststmt, listpriceliststmt, insertpriceliststmt,
+		getpricestmt, listpricesstmt, insertpricestmt, updatepricestmt, deletepricestmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *PriceService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetPriceListStatement:
+			err = prepare(&s.getPriceList, "get price list", string(stmt))
+		case ListPriceListsStatement:
+			err = prepare(&s.listPriceLists, "list price lists", string(stmt))
+		case InsertPriceListStatement:
+			err = prepare(&s.insertPriceList, "insert price list", string(stmt))
+		case GetPriceStatement:
+			err = prepare(&s.getPrice, "get price", string(stmt))
+		case ListPricesStatement:
+			err = prepare(&s.listPrices, "list prices", string(stmt))
+		case InsertPriceStatement:
+			err = prepare(&s.insertPrice, "insert price", string(stmt))
+		case UpdatePriceStatement:
+			err = prepare(&s.updatePrice, "update price", string(stmt))
+		case DeletePriceStatement:
+			err = prepare(&s.deletePrice, "delete price", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var getpriceliststmt GetPriceListStatement = "SELECT id, code, name FROM price_list WHERE id = ?"
+
+// PriceList returns a PriceList by ID.
+func (s *PriceService) PriceList(ctx context.Context, id string) (*catalog.PriceList, error) {
+	var pl catalog.PriceList
+	err := s.getPriceList.QueryRowContext(ctx, id).Scan(&pl.ID, &pl.Code, &pl.Name)
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "price list %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving price list: %v, %v", id, err)
+		return nil, err
+	}
+	return &pl, nil
+}
+
+var listpriceliststmt ListPriceListsStatement = "SELECT id, code, name FROM price_list ORDER BY code"
+
+// PriceLists returns all PriceLists ordered by code.
+func (s *PriceService) PriceLists(ctx context.Context) ([]*catalog.PriceList, error) {
+	rows, err := s.listPriceLists.QueryContext(ctx)
+	if err != nil {
+		log.Errorf("Error retrieving price lists: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	lists := []*catalog.PriceList{}
+	for rows.Next() {
+		var pl catalog.PriceList
+		if err := rows.Scan(&pl.ID, &pl.Code, &pl.Name); err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		lists = append(lists, &pl)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return lists, nil
+}
+
+var insertpriceliststmt InsertPriceListStatement = "INSERT price_list SET code=?, name=?"
+
+// CreatePriceList stores a new price list in the database.
+func (s *PriceService) CreatePriceList(ctx context.Context, pl *catalog.PriceList) error {
+	if err := pl.Validate(); err != nil {
+		return err
+	}
+	res, err := s.insertPriceList.ExecContext(ctx, pl.Code, pl.Name)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	pl.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+const priceColumns = "id, product_id, variant_id, price_list_id, currency, list_amount, sale_amount, valid_from, valid_to"
+
+// scanPrice scans a row selected with priceColumns.
+func scanPrice(row interface{ Scan(...interface{}) error }) (*catalog.Price, error) {
+	var p catalog.Price
+	var variantID sql.NullString
+	err := row.Scan(&p.ID, &p.ProductID, &variantID, &p.PriceListID, &p.Currency,
+		&p.ListAmount, &p.SaleAmount, &p.ValidFrom, &p.ValidTo)
+	if err != nil {
+		return nil, err
+	}
+	p.VariantID = variantID.String
+	return &p, nil
+}
+
+// nullString returns nil for an empty string so it is stored as NULL.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+var getpricestmt GetPriceStatement = "SELECT " + priceColumns + " FROM price WHERE id = ?"
+
+// Price returns a Price by ID.
+func (s *PriceService) Price(ctx context.Context, id string) (*catalog.Price, error) {
+	p, err := scanPrice(s.getPrice.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "price %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving price: %v, %v", id, err)
+		return nil, err
+	}
+	return p, nil
+}
+
+var listpricesstmt ListPricesStatement = "SELECT " + priceColumns +
+	" FROM price WHERE product_id = ? ORDER BY price_list_id, currency, valid_from"
+
+// Prices returns the prices of a product matching the query.
+func (s *PriceService) Prices(ctx context.Context, productID string, q catalog.PriceQuery) ([]*catalog.Price, error) {
+	rows, err := s.listPrices.QueryContext(ctx, productID)
+	if err != nil {
+		log.Errorf("Error retrieving prices: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	prices := []*catalog.Price{}
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		// A product has few prices, so the filters are applied here
+		// rather than with a statement per combination.
+		if q.VariantID != "" && p.VariantID != q.VariantID {
+			continue
+		}
+		if q.PriceListID != "" && p.PriceListID != q.PriceListID {
+			continue
+		}
+		if q.Currency != "" && p.Currency != q.Currency {
+			continue
+		}
+		if q.At != nil && !p.ValidAt(*q.At) {
+			continue
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return prices, nil
+}
+
+var insertpricestmt InsertPriceStatement = "INSERT price SET product_id=?, variant_id=?, price_list_id=?, currency=?, " +
+	"list_amount=?, sale_amount=?, valid_from=?, valid_to=?"
+
+// CreatePrice stores a new price in the database.
+func (s *PriceService) CreatePrice(ctx context.Context, p *catalog.Price) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	res, err := s.insertPrice.ExecContext(ctx, p.ProductID, nullString(p.VariantID), p.PriceListID, p.Currency,
+		p.ListAmount, p.SaleAmount, p.ValidFrom, p.ValidTo)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	p.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+var updatepricestmt UpdatePriceStatement = "UPDATE price SET variant_id=?, price_list_id=?, currency=?, " +
+	"list_amount=?, sale_amount=?, valid_from=?, valid_to=? WHERE id=? AND product_id=?"
+
+// UpdatePrice updates an existing price of a product.
+func (s *PriceService) UpdatePrice(ctx context.Context, p *catalog.Price) error {
+	if len(p.ID) == 0 {
+		return catalog.Errorf(catalog.ErrInvalid, "price with unassigned ID passed in to UpdatePrice")
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	res, err := s.updatePrice.ExecContext(ctx, nullString(p.VariantID), p.PriceListID, p.Currency,
+		p.ListAmount, p.SaleAmount, p.ValidFrom, p.ValidTo, p.ID, p.ProductID)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "price %v not found", p.ID)
+	}
+	return nil
+}
+
+var deletepricestmt DeletePriceStatement = "DELETE FROM price WHERE id=?"
+
+// DeletePrice deletes a price.
+func (s *PriceService) DeletePrice(ctx context.Context, id string) error {
+	res, err := s.deletePrice.ExecContext(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "price %v not found", id)
+	}
+	return nil
+}
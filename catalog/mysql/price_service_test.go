@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var priceRowColumns = []string{"id", "product_id", "variant_id", "price_list_id", "currency",
+	"list_amount", "sale_amount", "valid_from", "valid_to"}
+
+func TestPriceService_Prices(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, product_id, variant_id, price_list_id, currency, list_amount, sale_amount, valid_from, valid_to FROM price WHERE product_id = \\?").
+		ExpectQuery().WithArgs("5").
+		WillReturnRows(sqlmock.NewRows(priceRowColumns).
+			AddRow("1", "5", nil, "1", "USD", 1999, 1499, jan, feb).
+			AddRow("2", "5", nil, "1", "USD", 1999, nil, feb, nil).
+			AddRow("3", "5", nil, "1", "EUR", 1799, nil, nil, nil))
+
+	client := NewClient()
+	client.db = db
+	client.priceService.prepareSqlStmt(listpricesstmt)
+	at := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	prices, err := client.priceService.Prices(context.Background(), "5", catalog.PriceQuery{Currency: "USD", At: &at})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(prices) != 1 || prices[0].ID != "1" || prices[0].Amount() != 1499 {
+		t.Fatalf("expected only the January sale price, got %+v", prices)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPriceService_CreatePrice(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT price SET product_id=\\?, variant_id=\\?, price_list_id=\\?, currency=\\?").
+		ExpectExec().
+		WithArgs("5", nil, "1", "USD", 1999, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	client := NewClient()
+	client.db = db
+	client.priceService.prepareSqlStmt(insertpricestmt)
+	price := &catalog.Price{ProductID: "5", PriceListID: "1", Currency: "USD", ListAmount: 1999}
+	if err := client.priceService.CreatePrice(context.Background(), price); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if price.ID != "7" {
+		t.Errorf("expected price id 7, got %v", price.ID)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPriceService_CreatePriceInvalid(t *testing.T) {
+	client := NewClient()
+	sale := int64(2500)
+	price := &catalog.Price{ProductID: "5", PriceListID: "1", Currency: "usd", ListAmount: 1999, SaleAmount: &sale}
+	err := client.priceService.CreatePrice(context.Background(), price)
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || len(cErr.Fields) != 2 {
+		t.Fatalf("expected currency and saleAmount field errors, got %v", err)
+	}
+}
+
+func TestPriceService_DeletePriceNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("DELETE FROM price WHERE id=\\?").
+		ExpectExec().WithArgs("9").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.priceService.prepareSqlStmt(deletepricestmt)
+	if err := client.priceService.DeletePrice(context.Background(), "9"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,145 @@
+package mysql
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Attribute values are stored one row per product and attribute in
+// product_attribute. The value column holds the canonical text form;
+// value_number repeats integer and number values as a DOUBLE so filters
+// compare them numerically.
+
+const productAttributeColumns = "pa.product_id, pa.name, d.type, pa.value"
+
+var productattributesstmt ProductAttributesStatement = "SELECT " + productAttributeColumns +
+	" FROM product_attribute pa JOIN attribute_definition d ON d.name = pa.name WHERE pa.product_id = ?"
+
+// insertproductattributestmt replaces an existing value of the attribute.
+var insertproductattributestmt InsertProductAttributeStatement = "INSERT product_attribute SET product_id=?, name=?, value=?, value_number=? " +
+	"ON DUPLICATE KEY UPDATE value=VALUES(value), value_number=VALUES(value_number)"
+
+var deleteproductattributesstmt DeleteProductAttributesStatement = "DELETE FROM product_attribute WHERE product_id=?"
+
+var deleteproductattributestmt DeleteProductAttributeStatement = "DELETE FROM product_attribute WHERE product_id=? AND name=?"
+
+// readAttributes adds the attribute values in rows to the products they belong to.
+func readAttributes(rows *sql.Rows, byID map[string]*catalog.Product) error {
+	defer rows.Close()
+	for rows.Next() {
+		var productID string
+		var d catalog.AttributeDefinition
+		var value string
+		if err := rows.Scan(&productID, &d.Name, &d.Type, &value); err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return err
+		}
+		if p, ok := byID[productID]; ok {
+			if p.Attributes == nil {
+				p.Attributes = map[string]interface{}{}
+			}
+			p.Attributes[d.Name] = d.Value(value)
+		}
+	}
+	return rows.Err()
+}
+
+// pageAttributes reads the attribute values of a page of products with a single query.
+func (s *ProductService) pageAttributes(ctx context.Context, products []*catalog.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	byID := make(map[string]*catalog.Product, len(products))
+	args := make([]interface{}, len(products))
+	for i, p := range products {
+		byID[p.ID] = p
+		args[i] = p.ID
+	}
+	query := "SELECT " + productAttributeColumns +
+		" FROM product_attribute pa JOIN attribute_definition d ON d.name = pa.name" +
+		" WHERE pa.product_id IN (?" + strings.Repeat(", ?", len(products)-1) + ")"
+	rows, err := s.client.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("Error retrieving product attributes: %v", err)
+		return err
+	}
+	return readAttributes(rows, byID)
+}
+
+// attributeValue is an attribute value ready to be stored.
+type attributeValue struct {
+	name  string
+	value string
+	// number is the value as a float64 for integer and number attributes, nil otherwise.
+	number interface{}
+}
+
+// canonicalAttributes validates the attribute values of a product against
+// the attribute definitions and returns them in their stored form. The
+// product's values are replaced by their typed form, e.g. "2" for an
+// integer attribute becomes 2.
+func (s *ProductService) canonicalAttributes(ctx context.Context, tx *sql.Tx, product *catalog.Product) ([]attributeValue, error) {
+	rows, err := tx.StmtContext(ctx, s.client.attributeService.list).QueryContext(ctx)
+	if err != nil {
+		log.Errorf("Error retrieving attributes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var defs []*catalog.AttributeDefinition
+	byName := map[string]*catalog.AttributeDefinition{}
+	for rows.Next() {
+		d, err := scanAttribute(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		defs = append(defs, d)
+		byName[d.Name] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	canonical, err := catalog.CanonicalAttributes(defs, product.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]attributeValue, 0, len(canonical))
+	for name, value := range canonical {
+		d := byName[name]
+		v := attributeValue{name: name, value: value}
+		if d.Type == catalog.AttributeInteger || d.Type == catalog.AttributeNumber {
+			v.number = numberValue(value)
+		}
+		values = append(values, v)
+		product.Attributes[name] = d.Value(value)
+	}
+	return values, nil
+}
+
+// writeAttributes inserts the attribute values of a product.
+func (s *ProductService) writeAttributes(ctx context.Context, tx *sql.Tx, productID string, values []attributeValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+	stmt := tx.StmtContext(ctx, s.insertAttribute)
+	for _, v := range values {
+		if _, err := stmt.ExecContext(ctx, productID, v.name, v.value, v.number); err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+// numberValue returns v as a float64 when it is numeric, and nil otherwise.
+func numberValue(v string) interface{} {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// batchChunkSize is the number of operations a best-effort batch applies
+// per transaction, keeping row locks and undo logs short.
+const batchChunkSize = 500
+
+// errBatchFailed rolls back an all-or-nothing batch after an operation failed.
+var errBatchFailed = errors.New("batch operation failed")
+
+// BatchProducts applies a list of upserts and deletes. An all-or-nothing
+// batch runs in a single transaction. A best-effort batch runs in chunks of
+// batchChunkSize operations per transaction; each operation runs under a
+// savepoint so a failing one is rolled back without losing the others.
+func (s *ProductService) BatchProducts(ctx context.Context, ops []*catalog.BatchOperation, atomic bool) ([]*catalog.BatchResult, error) {
+	if len(ops) > catalog.MaxBatchOperations {
+		return nil, catalog.Errorf(catalog.ErrInvalid, "a batch holds at most %d operations", catalog.MaxBatchOperations)
+	}
+	results := make([]*catalog.BatchResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		results[i] = &catalog.BatchResult{Err: op.Validate()}
+		failed = failed || results[i].Err != nil
+	}
+	if atomic {
+		if failed {
+			abortBatch(results)
+			return results, nil
+		}
+		err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+			for i, op := range ops {
+				if err := s.applyBatchOperation(ctx, tx, op, results[i]); err != nil {
+					if !isDomainError(err) {
+						return err
+					}
+					results[i].Err = err
+					return errBatchFailed
+				}
+			}
+			return nil
+		})
+		if err == errBatchFailed {
+			abortBatch(results)
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+	for start := 0; start < len(ops); start += batchChunkSize {
+		end := start + batchChunkSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+			for i := start; i < end; i++ {
+				if results[i].Err != nil {
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+					return err
+				}
+				err := s.applyBatchOperation(ctx, tx, ops[i], results[i])
+				if err != nil && !isDomainError(err) {
+					return err
+				}
+				if err != nil {
+					results[i].Product = nil
+					results[i].Err = err
+					if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_operation"); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// Earlier chunks are committed; report this and the remaining
+			// operations as failed.
+			log.Errorf("Error applying product batch: %v", err)
+			for i := start; i < len(ops); i++ {
+				if results[i].Err == nil {
+					results[i] = &catalog.BatchResult{Err: err}
+				}
+			}
+			break
+		}
+	}
+	return results, nil
+}
+
+// applyBatchOperation applies a single validated operation within a
+// transaction, recording the stored product in result.
+func (s *ProductService) applyBatchOperation(ctx context.Context, tx *sql.Tx, op *catalog.BatchOperation, result *catalog.BatchResult) error {
+	if op.Op == catalog.BatchDelete {
+		return s.deleteInTx(ctx, tx, op.ID, op.Version)
+	}
+	if op.Product.ID == "" {
+		if err := s.createInTx(ctx, tx, op.Product); err != nil {
+			return err
+		}
+		result.Created = true
+	} else if err := s.updateInTx(ctx, tx, op.Product); err != nil {
+		return err
+	}
+	result.Product = op.Product
+	return nil
+}
+
+// abortBatch marks the operations of a failed all-or-nothing batch that
+// did not fail themselves as aborted.
+func abortBatch(results []*catalog.BatchResult) {
+	for _, r := range results {
+		r.Product = nil
+		r.Created = false
+		if r.Err == nil {
+			r.Err = catalog.ErrBatchAborted
+		}
+	}
+}
+
+// isDomainError reports whether err is a catalog error about the request
+// rather than a failure of the database.
+func isDomainError(err error) bool {
+	var cErr *catalog.Error
+	return errors.As(err, &cErr)
+}
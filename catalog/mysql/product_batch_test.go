@@ -0,0 +1,152 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestProductService_BatchProductsBestEffort(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
+		WithArgs("default", "1234", "shortdesc for 1234", "", "draft", nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "7", 1, "create", "", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.created", "default", "7", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").
+		WithArgs("default", "9").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, lockversionstmt, inserthistorystmt, inserteventstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+	ops := []*catalog.BatchOperation{
+		{Op: catalog.BatchUpsert, Product: &catalog.Product{ProductCode: "1234", ShortDesc: "shortdesc for 1234"}},
+		{Op: catalog.BatchDelete, ID: "9"},
+		{Op: catalog.BatchUpsert, Product: &catalog.Product{}},
+	}
+	// execute the method
+	results, err := client.productService.BatchProducts(context.Background(), ops, false)
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if results[0].Err != nil || !results[0].Created || results[0].Product.ID != "7" {
+		t.Errorf("expected product 7 to be created, got %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, catalog.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", results[1].Err)
+	}
+	if !errors.Is(results[2].Err, catalog.ErrInvalid) {
+		t.Errorf("expected a validation error, got %v", results[2].Err)
+	}
+	// makes sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_BatchProductsAtomic(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
+		WithArgs("default", "1234", "", "", "draft", nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "7", 1, "create", "", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.created", "default", "7", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").
+		WithArgs("default", "9").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(4, "active"))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, lockversionstmt, inserthistorystmt, inserteventstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+	ops := []*catalog.BatchOperation{
+		{Op: catalog.BatchUpsert, Product: &catalog.Product{ProductCode: "1234"}},
+		{Op: catalog.BatchDelete, ID: "9", Version: 3},
+	}
+	// execute the method
+	results, err := client.productService.BatchProducts(context.Background(), ops, true)
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if results[0].Err != catalog.ErrBatchAborted || results[0].Product != nil {
+		t.Errorf("expected the create to be aborted, got %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, catalog.ErrVersionConflict) {
+		t.Errorf("expected a version conflict, got %v", results[1].Err)
+	}
+	// makes sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_BatchProductsAtomicInvalid(t *testing.T) {
+	client := NewClient()
+	ops := []*catalog.BatchOperation{
+		{Op: catalog.BatchDelete, ID: "9"},
+		{Op: "merge"},
+	}
+	// Invalid operations fail the batch before the database is touched.
+	results, err := client.productService.BatchProducts(context.Background(), ops, true)
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if results[0].Err != catalog.ErrBatchAborted {
+		t.Errorf("expected the delete to be aborted, got %v", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, catalog.ErrInvalid) {
+		t.Errorf("expected a validation error, got %v", results[1].Err)
+	}
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+)
+
+// historyColumns is the column list selected for every revision read.
+const historyColumns = "product_id, version, action, actor, changed_at, before_doc, after_doc"
+
+var inserthistorystmt InsertHistoryStatement = "INSERT product_history SET tenant_id=?, product_id=?, version=?, action=?, actor=?, " +
+	"changed_at=UTC_TIMESTAMP(6), before_doc=?, after_doc=?"
+
+var historystmt HistoryStatement = "SELECT " + historyColumns + " FROM product_history " +
+	"WHERE tenant_id = ? AND product_id = ? AND version < ? ORDER BY version DESC LIMIT ?"
+
+var revisionstmt RevisionStatement = "SELECT " + historyColumns + " FROM product_history WHERE tenant_id = ? AND product_id = ? AND version = ?"
+
+var revisionasofstmt RevisionAsOfStatement = "SELECT " + historyColumns + " FROM product_history " +
+	"WHERE tenant_id = ? AND product_id = ? AND changed_at <= ? ORDER BY version DESC LIMIT 1"
+
+var firstrevisionstmt FirstRevisionStatement = "SELECT " + historyColumns + " FROM product_history " +
+	"WHERE tenant_id = ? AND product_id = ? ORDER BY version LIMIT 1"
+
+// recordChange adds a revision of the product to its history and queues
+// the event of the change within the transaction making the change. The
+// actor is taken from the context.
+func (s *ProductService) recordChange(ctx context.Context, tx *sql.Tx, action, id string, version int64, before, after *catalog.Product) error {
+	beforeDoc, err := productDoc(before)
+	if err != nil {
+		return err
+	}
+	afterDoc, err := productDoc(after)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, s.insertHistory).ExecContext(ctx, catalog.TenantFromContext(ctx), id, version, action,
+		catalog.ActorFromContext(ctx), beforeDoc, afterDoc); err != nil {
+		log.Errorf("Error recording the product history: %v, %v", id, err)
+		return err
+	}
+	return s.recordEvent(ctx, tx, eventTypes[action], id, version, after)
+}
+
+// productDoc returns the JSON document of a product, or nil.
+func productDoc(p *catalog.Product) (interface{}, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// snapshot returns a copy of p that later changes to p do not affect.
+func snapshot(p *catalog.Product) *catalog.Product {
+	c := *p
+	if p.Attributes != nil {
+		c.Attributes = make(map[string]interface{}, len(p.Attributes))
+		for k, v := range p.Attributes {
+			c.Attributes[k] = v
+		}
+	}
+	return &c
+}
+
+// scanRevision scans a row selected with historyColumns.
+func scanRevision(row rowScanner) (*catalog.ProductRevision, error) {
+	var r catalog.ProductRevision
+	var before, after []byte
+	if err := row.Scan(&r.ProductID, &r.Version, &r.Action, &r.Actor, &r.ChangedAt, &before, &after); err != nil {
+		return nil, err
+	}
+	for _, d := range []struct {
+		doc []byte
+		dst **catalog.Product
+	}{{before, &r.Before}, {after, &r.After}} {
+		if d.doc == nil {
+			continue
+		}
+		var p catalog.Product
+		if err := json.Unmarshal(d.doc, &p); err != nil {
+			return nil, err
+		}
+		*d.dst = &p
+	}
+	return &r, nil
+}
+
+// ProductHistory returns a page of the revisions of a product of the
+// context's tenant, newest first. Revisions are kept after the product is
+// purged.
+func (s *ProductService) ProductHistory(ctx context.Context, id string, page catalog.Page) (*catalog.RevisionPage, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	// The cursor holds the last version returned.
+	before := int64(math.MaxUint32)
+	if page.Cursor != "" {
+		if c.Sort != "history" || c.ID < 1 {
+			return nil, catalog.ErrInvalidCursor
+		}
+		before = c.ID
+	}
+	limit := page.PageSize()
+	// Fetch one extra row to find out whether there is another page
+	rows, err := s.history.QueryContext(ctx, catalog.TenantFromContext(ctx), id, before, limit+1)
+	if err != nil {
+		log.Errorf("Error retrieving product history: %v, %v", id, err)
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []*catalog.ProductRevision{}
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	result := &catalog.RevisionPage{Revisions: revisions}
+	if len(revisions) > limit {
+		result.Revisions = revisions[:limit]
+		result.Next = encodeCursor(cursor{Sort: "history", ID: revisions[limit-1].Version})
+	}
+	return result, nil
+}
+
+// ProductRevision returns the revision that produced the product version.
+func (s *ProductService) ProductRevision(ctx context.Context, id string, version int64) (*catalog.ProductRevision, error) {
+	r, err := scanRevision(s.revision.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id, version))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "revision %d of product %v not found", version, id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving product revision: %v, %v", id, err)
+		return nil, err
+	}
+	return r, nil
+}
+
+// ProductAsOf reconstructs a product at the given time from the last
+// revision made by then. Before its first revision a product that was not
+// created within the recorded history is as the revision found it, and a
+// product without any revisions is as it is now.
+func (s *ProductService) ProductAsOf(ctx context.Context, id string, at time.Time) (*catalog.Product, error) {
+	r, err := scanRevision(s.revisionAsOf.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id, at.UTC()))
+	if err == nil {
+		if r.After == nil {
+			return nil, catalog.Errorf(catalog.ErrNotFound, "product %v was deleted at %v", id, at.UTC().Format(time.RFC3339))
+		}
+		return r.After, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Errorf("Error retrieving product revision: %v, %v", id, err)
+		return nil, err
+	}
+	r, err = scanRevision(s.firstRevision.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id))
+	if err == sql.ErrNoRows {
+		return s.Product(ctx, id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving product revision: %v, %v", id, err)
+		return nil, err
+	}
+	if r.Before == nil {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v did not exist at %v", id, at.UTC().Format(time.RFC3339))
+	}
+	return r.Before, nil
+}
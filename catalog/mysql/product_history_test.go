@@ -0,0 +1,168 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var historyColumnNames = []string{"product_id", "version", "action", "actor", "changed_at", "before_doc", "after_doc"}
+
+func TestProductService_ProductHistory(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	changedAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history " +
+		"WHERE tenant_id = \\? AND product_id = \\? AND version < \\? ORDER BY version DESC LIMIT \\?")
+	mock.ExpectQuery("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history").
+		WithArgs("default", "5", int64(4294967295), 3).
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("5", 3, "delete", "alice", changedAt, `{"productId":"5","productCode":"tee","version":2}`, nil).
+			AddRow("5", 2, "update", "bob", changedAt, `{"productId":"5","productCode":"tee","version":1}`,
+				`{"productId":"5","productCode":"tee","shortDesc":"Tee","version":2}`).
+			AddRow("5", 1, "create", "bob", changedAt, nil, `{"productId":"5","productCode":"tee","version":1}`))
+	mock.ExpectQuery("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history").
+		WithArgs("default", "5", int64(2), 3).
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("5", 1, "create", "bob", changedAt, nil, `{"productId":"5","productCode":"tee","version":1}`))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(historystmt)
+
+	page, err := client.productService.ProductHistory(context.Background(), "5", catalog.Page{Limit: 2})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(page.Revisions) != 2 || page.Next == "" {
+		t.Fatalf("expected 2 revisions and a next page, got %+v", page)
+	}
+	r := page.Revisions[0]
+	if r.Version != 3 || r.Action != catalog.HistoryDelete || r.Actor != "alice" || r.After != nil || r.Before.Version != 2 {
+		t.Errorf("unexpected revision: %+v", r)
+	}
+	if page.Revisions[1].After.ShortDesc != "Tee" {
+		t.Errorf("unexpected revision: %+v", page.Revisions[1].After)
+	}
+	page, err = client.productService.ProductHistory(context.Background(), "5", catalog.Page{Cursor: page.Next, Limit: 2})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(page.Revisions) != 1 || page.Next != "" || page.Revisions[0].Before != nil {
+		t.Errorf("expected the create revision on the last page, got %+v", page)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductHistoryInvalidCursor(t *testing.T) {
+	client := NewClient()
+	page := catalog.Page{Cursor: encodeCursor(cursor{Sort: "productcode", ID: 3})}
+	if _, err := client.productService.ProductHistory(context.Background(), "5", page); err != catalog.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor but got: %v", err)
+	}
+}
+
+func TestProductService_ProductRevisionNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history " +
+		"WHERE tenant_id = \\? AND product_id = \\? AND version = \\?")
+	mock.ExpectQuery("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history").
+		WithArgs("default", "5", 9).
+		WillReturnError(sql.ErrNoRows)
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(revisionstmt)
+
+	if _, err := client.productService.ProductRevision(context.Background(), "5", 9); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductAsOf(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history " +
+		"WHERE tenant_id = \\? AND product_id = \\? AND changed_at <= \\? ORDER BY version DESC LIMIT 1")
+	mock.ExpectPrepare("SELECT product_id, version, action, actor, changed_at, before_doc, after_doc FROM product_history " +
+		"WHERE tenant_id = \\? AND product_id = \\? ORDER BY version LIMIT 1")
+	// The last revision by then.
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? AND changed_at <= \\?").
+		WithArgs("default", "5", at).
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("5", 2, "update", "", at, `{"productId":"5","productCode":"tee","version":1}`,
+				`{"productId":"5","productCode":"tee","shortDesc":"Tee","version":2}`))
+	// Deleted by then.
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? AND changed_at <= \\?").
+		WithArgs("default", "6", at).
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("6", 3, "delete", "", at, `{"productId":"6","productCode":"cap","version":2}`, nil))
+	// Not created yet.
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? AND changed_at <= \\?").
+		WithArgs("default", "7", at).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? ORDER BY version LIMIT 1").
+		WithArgs("default", "7").
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("7", 1, "create", "", at.Add(time.Hour), nil, `{"productId":"7","productCode":"hat","version":1}`))
+	// Last changed before the history was recorded.
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? AND changed_at <= \\?").
+		WithArgs("default", "8", at).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? ORDER BY version LIMIT 1").
+		WithArgs("default", "8").
+		WillReturnRows(sqlmock.NewRows(historyColumnNames).
+			AddRow("8", 5, "update", "", at.Add(time.Hour), `{"productId":"8","productCode":"scarf","version":4}`,
+				`{"productId":"8","productCode":"scarf","version":5}`))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(revisionasofstmt, firstrevisionstmt)
+
+	product, err := client.productService.ProductAsOf(context.Background(), "5", at)
+	if err != nil || product.ShortDesc != "Tee" || product.Version != 2 {
+		t.Errorf("expected version 2 of product 5, got %+v, %v", product, err)
+	}
+	for _, id := range []string{"6", "7"} {
+		if _, err := client.productService.ProductAsOf(context.Background(), id, at); !errors.Is(err, catalog.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for product %v but got: %v", id, err)
+		}
+	}
+	product, err = client.productService.ProductAsOf(context.Background(), "8", at)
+	if err != nil || product.Version != 4 {
+		t.Errorf("expected version 4 of product 8, got %+v, %v", product, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
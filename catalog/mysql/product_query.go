@@ -0,0 +1,212 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+)
+
+// productColumns is the column list selected for every product read.
+const productColumns = "id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct scans a row selected with productColumns.
+func scanProduct(row rowScanner) (*catalog.Product, error) {
+	var product catalog.Product
+	var deletedAt, publishAt, unpublishAt sql.NullTime
+	if err := row.Scan(&product.ID, &product.ProductCode, &product.ShortDesc, &product.LongDesc, &product.Version, &deletedAt,
+		&product.Status, &publishAt, &unpublishAt); err != nil {
+		return nil, err
+	}
+	product.DeletedAt = nullTime(deletedAt)
+	product.PublishAt = nullTime(publishAt)
+	product.UnpublishAt = nullTime(unpublishAt)
+	return &product, nil
+}
+
+// nullTime returns the time of a nullable column, or nil.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// sortColumns maps the sortable catalog fields to product columns.
+var sortColumns = map[string]string{
+	catalog.SortByID:          "id",
+	catalog.SortByProductCode: "productcode",
+	catalog.SortByShortDesc:   "shortdesc",
+}
+
+// listQuery builds the parameterized SELECT for a ProductQuery.
+// Only column names from sortColumns are interpolated into the SQL;
+// every value is passed as an argument.
+type listQuery struct {
+	where []string
+	args  []interface{}
+}
+
+func (q *listQuery) add(cond string, args ...interface{}) {
+	q.where = append(q.where, cond)
+	q.args = append(q.args, args...)
+}
+
+// sortKeys returns the effective ordering for a query, which always ends
+// with the product id so the keyset is unique.
+func sortKeys(sort []catalog.SortField) []catalog.SortField {
+	keys := make([]catalog.SortField, 0, len(sort)+1)
+	for _, f := range sort {
+		keys = append(keys, f)
+		if f.Field == catalog.SortByID {
+			// Anything after the unique id can never change the order.
+			return keys
+		}
+	}
+	return append(keys, catalog.SortField{Field: catalog.SortByID})
+}
+
+// sortSpec returns the canonical text form of the sort keys, used to tie
+// a cursor to the ordering it was issued for.
+func sortSpec(keys []catalog.SortField) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// buildListQuery returns the SQL and arguments selecting one page of the
+// tenant's products for q, positioned after c. The limit is applied by the
+// caller.
+func buildListQuery(tenant string, q catalog.ProductQuery, keys []catalog.SortField, c cursor) (string, []interface{}, error) {
+	lq := &listQuery{}
+	lq.add("tenant_id = ?", tenant)
+	switch q.Deleted {
+	case catalog.ExcludeDeleted:
+		lq.add("deleted_at IS NULL")
+	case catalog.OnlyDeleted:
+		lq.add("deleted_at IS NOT NULL")
+	}
+	if !q.PublishedAt.IsZero() {
+		at := q.PublishedAt.UTC()
+		lq.add("status = ?", string(catalog.StatusActive))
+		lq.add("(publish_at IS NULL OR publish_at <= ?)", at)
+		lq.add("(unpublish_at IS NULL OR unpublish_at > ?)", at)
+	}
+	if q.Status != "" {
+		lq.add("status = ?", string(q.Status))
+	}
+	if q.ProductCode != "" {
+		lq.add("productcode = ?", q.ProductCode)
+	}
+	if q.ProductCodePrefix != "" {
+		lq.add("productcode LIKE ?", escapeLike(q.ProductCodePrefix)+"%")
+	}
+	if q.ShortDescContains != "" {
+		lq.add("shortdesc LIKE ?", "%"+escapeLike(q.ShortDescContains)+"%")
+	}
+	if q.MinID > 0 {
+		lq.add("id >= ?", q.MinID)
+	}
+	if q.MaxID > 0 {
+		lq.add("id <= ?", q.MaxID)
+	}
+	if q.CategoryID != "" {
+		cond := "id IN (SELECT pc.product_id FROM product_category pc " +
+			"JOIN category_closure cc ON cc.descendant_id = pc.category_id WHERE cc.ancestor_id = ?"
+		if !q.IncludeDescendants {
+			cond += " AND cc.depth = 0"
+		}
+		lq.add(cond+")", q.CategoryID)
+	}
+	for _, f := range q.Attributes {
+		lq.add("id IN (SELECT pa.product_id FROM product_attribute pa "+
+			"WHERE pa.name = ? AND (pa.value = ? OR pa.value_number = ?))", f.Name, f.Value, numberValue(f.Value))
+	}
+	if q.Cursor != "" {
+		if c.Sort != sortSpec(keys) || len(c.Values) != len(keys)-1 {
+			return "", nil, catalog.ErrInvalidCursor
+		}
+		cond, args := keysetCondition(keys, c)
+		lq.add(cond, args...)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT " + productColumns + " FROM product")
+	if len(lq.where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(lq.where, " AND "))
+	}
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		order[i] = sortColumns[k.Field]
+		if k.Desc {
+			order[i] += " DESC"
+		}
+	}
+	b.WriteString(" ORDER BY " + strings.Join(order, ", ") + " LIMIT ?")
+	return b.String(), lq.args, nil
+}
+
+// keysetCondition builds the condition selecting rows strictly after the
+// cursor position in the given ordering, i.e. for keys (a, b, id):
+// a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?).
+func keysetCondition(keys []catalog.SortField, c cursor) (string, []interface{}) {
+	values := make([]interface{}, len(keys))
+	for i := range c.Values {
+		values[i] = c.Values[i]
+	}
+	values[len(keys)-1] = c.ID
+
+	var ors []string
+	var args []interface{}
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, sortColumns[keys[j].Field]+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if k.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, sortColumns[k.Field]+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// nextCursor returns the cursor positioned at product p for the given keys.
+func nextCursor(keys []catalog.SortField, p *catalog.Product) (string, error) {
+	id, err := strconv.ParseInt(p.ID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("mysql: unexpected product id: %v", err)
+	}
+	c := cursor{Sort: sortSpec(keys), ID: id}
+	for _, k := range keys[:len(keys)-1] {
+		switch k.Field {
+		case catalog.SortByProductCode:
+			c.Values = append(c.Values, p.ProductCode)
+		case catalog.SortByShortDesc:
+			c.Values = append(c.Values, p.ShortDesc)
+		}
+	}
+	return encodeCursor(c), nil
+}
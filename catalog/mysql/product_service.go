@@ -6,58 +6,103 @@ import (
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"github.com/mvonbodun/go-package-test/catalog"
-	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Ensure ProductService implements catalog.ProductService
 var _ catalog.ProductService = &ProductService{}
 
-// Table creation query
-var createTableStatements = []string{
-	`CREATE DATABASE IF NOT EXISTS catalog DEFAULT CHARACTER SET = 'utf8' DEFAULT COLLATE 'utf8_general_ci';`,
-	`USE catalog;`,
-	`CREATE TABLE IF NOT EXISTS product (
-		id INT UNSIGNED NOT NULL AUTO_INCREMENT,
-		productcode VARCHAR(255) NULL,
-		shortdesc VARCHAR(255) NULL,
-		longdesc text NULL,
-		PRIMARY KEY (id)
-	)`,
-}
-
 // MySQLConfig holds the connection info for the database.
 type MySQLConfig struct {
 	Username, Password string
 	Host string //host:port, i.e. localhost:3306
+
+	// SkipMigrations stops Open from applying pending schema migrations.
+	SkipMigrations bool
+}
+
+// dsn returns the data source name for the database, or for the server when dbName is empty.
+func (config MySQLConfig) dsn(dbName string) string {
+	mc := mysql.NewConfig()
+	mc.User = config.Username
+	mc.Passwd = config.Password
+	mc.Net = "tcp"
+	mc.Addr = config.Host
+	mc.Params = map[string]string{"charset": "utf8"}
+	mc.DBName = dbName
+	mc.ParseTime = true
+	// Report matched rather than changed rows so an UPDATE that leaves a
+	// row unchanged is not mistaken for a missing row.
+	mc.ClientFoundRows = true
+	return mc.FormatDSN()
 }
 
 // ProductService represents a service for managing Products
 type ProductService struct {
-	client *Client
-	get    *sql.Stmt
-	list   *sql.Stmt
-	insert *sql.Stmt
-	update *sql.Stmt
-	delete *sql.Stmt
+	client           *Client
+	get              *sql.Stmt
+	getByCode        *sql.Stmt
+	insert           *sql.Stmt
+	update           *sql.Stmt
+	delete           *sql.Stmt
+	lockVersion      *sql.Stmt
+	lockDeleted      *sql.Stmt
+	restore          *sql.Stmt
+	purge            *sql.Stmt
+	purgeDeleted     *sql.Stmt
+	attributes       *sql.Stmt
+	insertAttribute  *sql.Stmt
+	deleteAttributes *sql.Stmt
+	deleteAttribute  *sql.Stmt
+	insertHistory    *sql.Stmt
+	history          *sql.Stmt
+	revision         *sql.Stmt
+	revisionAsOf     *sql.Stmt
+	firstRevision    *sql.Stmt
+	insertEvent      *sql.Stmt
+	lockTenant       *sql.Stmt
 }
 
 // Define custom types for statements to help with sqlmock tests
 type (
 	SqlStatement    string
 	GetStatement    SqlStatement
-	ListStatement   SqlStatement
 	InsertStatement SqlStatement
 	UpdateStatement SqlStatement
 	DeleteStatement SqlStatement
+
+	GetByCodeStatement   SqlStatement
+	LockVersionStatement SqlStatement
+
+	LockDeletedStatement  SqlStatement
+	RestoreStatement      SqlStatement
+	PurgeStatement        SqlStatement
+	PurgeDeletedStatement SqlStatement
+
+	ProductAttributesStatement       SqlStatement
+	InsertProductAttributeStatement  SqlStatement
+	DeleteProductAttributesStatement SqlStatement
+	DeleteProductAttributeStatement  SqlStatement
+
+	InsertHistoryStatement SqlStatement
+	HistoryStatement       SqlStatement
+	RevisionStatement      SqlStatement
+	RevisionAsOfStatement  SqlStatement
+	FirstRevisionStatement SqlStatement
 )
 
 // prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
 func (s *ProductService) prepareSqlStmts() error {
 	// Prepare all the SQL statements
-	if err := s.prepareSqlStmt(getstmt, liststmt, insertstmt, updatestmt, deletestmt); err != nil {
+	if err := s.prepareSqlStmt(getstmt, getbycodestmt, insertstmt, updatestmt, deletestmt, lockversionstmt,
+		lockdeletedstmt, restorestmt, purgestmt, purgedeletedstmt,
+		productattributesstmt, insertproductattributestmt, deleteproductattributesstmt,
+		deleteproductattributestmt, inserthistorystmt, historystmt, revisionstmt, revisionasofstmt,
+		firstrevisionstmt, inserteventstmt, locktenantstmt); err != nil {
 		return err
 	}
 	return nil
@@ -73,9 +118,9 @@ func (s *ProductService) prepareSqlStmt(stmts ...interface{}) error {
 			if s.get, err = s.client.db.Prepare(string(getstmt)); err != nil {
 				return fmt.Errorf("mysql: prepare get: %v", err)
 			}
-		case ListStatement:
-			if s.list, err = s.client.db.Prepare(string(liststmt)); err != nil {
-				return fmt.Errorf("mysql: prepare list: %v", err)
+		case GetByCodeStatement:
+			if s.getByCode, err = s.client.db.Prepare(string(getbycodestmt)); err != nil {
+				return fmt.Errorf("mysql: prepare get by code: %v", err)
 			}
 		case InsertStatement:
 			if s.insert, err = s.client.db.Prepare(string(insertstmt)); err != nil {
@@ -89,123 +134,532 @@ func (s *ProductService) prepareSqlStmt(stmts ...interface{}) error {
 			if s.delete, err = s.client.db.Prepare(string(deletestmt)); err != nil {
 				return fmt.Errorf("mysql: prepare delete: %v", err)
 			}
+		case LockVersionStatement:
+			if s.lockVersion, err = s.client.db.Prepare(string(lockversionstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare lock version: %v", err)
+			}
+		case LockDeletedStatement:
+			if s.lockDeleted, err = s.client.db.Prepare(string(lockdeletedstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare lock deleted: %v", err)
+			}
+		case RestoreStatement:
+			if s.restore, err = s.client.db.Prepare(string(restorestmt)); err != nil {
+				return fmt.Errorf("mysql: prepare restore: %v", err)
+			}
+		case PurgeStatement:
+			if s.purge, err = s.client.db.Prepare(string(purgestmt)); err != nil {
+				return fmt.Errorf("mysql: prepare purge: %v", err)
+			}
+		case PurgeDeletedStatement:
+			if s.purgeDeleted, err = s.client.db.Prepare(string(purgedeletedstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare purge deleted: %v", err)
+			}
+		case ProductAttributesStatement:
+			if s.attributes, err = s.client.db.Prepare(string(productattributesstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare product attributes: %v", err)
+			}
+		case InsertProductAttributeStatement:
+			if s.insertAttribute, err = s.client.db.Prepare(string(insertproductattributestmt)); err != nil {
+				return fmt.Errorf("mysql: prepare insert product attribute: %v", err)
+			}
+		case DeleteProductAttributeStatement:
+			if s.deleteAttribute, err = s.client.db.Prepare(string(deleteproductattributestmt)); err != nil {
+				return fmt.Errorf("mysql: prepare delete product attribute: %v", err)
+			}
+		case DeleteProductAttributesStatement:
+			if s.deleteAttributes, err = s.client.db.Prepare(string(deleteproductattributesstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare delete product attributes: %v", err)
+			}
+		case InsertHistoryStatement:
+			if s.insertHistory, err = s.client.db.Prepare(string(inserthistorystmt)); err != nil {
+				return fmt.Errorf("mysql: prepare insert history: %v", err)
+			}
+		case HistoryStatement:
+			if s.history, err = s.client.db.Prepare(string(historystmt)); err != nil {
+				return fmt.Errorf("mysql: prepare history: %v", err)
+			}
+		case RevisionStatement:
+			if s.revision, err = s.client.db.Prepare(string(revisionstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare revision: %v", err)
+			}
+		case RevisionAsOfStatement:
+			if s.revisionAsOf, err = s.client.db.Prepare(string(revisionasofstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare revision as of: %v", err)
+			}
+		case FirstRevisionStatement:
+			if s.firstRevision, err = s.client.db.Prepare(string(firstrevisionstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare first revision: %v", err)
+			}
+		case InsertEventStatement:
+			if s.insertEvent, err = s.client.db.Prepare(string(inserteventstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare insert event: %v", err)
+			}
+		case LockTenantStatement:
+			if s.lockTenant, err = s.client.db.Prepare(string(locktenantstmt)); err != nil {
+				return fmt.Errorf("mysql: prepare lock tenant: %v", err)
+			}
 		}
 	}
 	return nil
 }
 
-var getstmt GetStatement = "SELECT id, productcode, shortdesc, longdesc FROM product WHERE id = ?"
+var getstmt GetStatement = "SELECT " + productColumns + " FROM product WHERE tenant_id = ? AND id = ? AND deleted_at IS NULL"
 
-// Product returns a Product by ID.
+// Product returns a Product by ID. Deleted products are not found.
 func (s *ProductService) Product(ctx context.Context, id string) (*catalog.Product, error) {
-	var product catalog.Product
 	// Retrieve the Product record.
-	err := s.get.QueryRowContext(ctx, id).
-		Scan(&product.ID, &product.ProductCode, &product.ShortDesc, &product.LongDesc)
+	product, err := scanProduct(s.get.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
+	}
 	if err != nil {
 		log.WithField("ctx", ctx).Warningf("Error retrieving product: %v, %v", id, err)
+		return nil, err
 	}
-	return &product, err
+	if err := s.productAttributes(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
-var liststmt ListStatement = "SELECT id, productcode, shortdesc, longdesc FROM product"
+var getbycodestmt GetByCodeStatement = "SELECT " + productColumns + " FROM product WHERE tenant_id = ? AND productcode = ? AND deleted_at IS NULL"
 
-// Products returns all Products.
-func (s *ProductService) Products(ctx context.Context) ([]*catalog.Product, error) {
-	// Query all rows in the database
-	rows, err := s.list.QueryContext(ctx)
+// ProductByCode returns a Product by its unique product code. Deleted
+// products are not found.
+func (s *ProductService) ProductByCode(ctx context.Context, code string) (*catalog.Product, error) {
+	product, err := scanProduct(s.getByCode.QueryRowContext(ctx, catalog.TenantFromContext(ctx), code))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product with code %q not found", code)
+	}
+	if err != nil {
+		log.WithField("ctx", ctx).Warningf("Error retrieving product by code: %v, %v", code, err)
+		return nil, err
+	}
+	if err := s.productAttributes(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// productAttributes reads the attribute values of a single product.
+func (s *ProductService) productAttributes(ctx context.Context, product *catalog.Product) error {
+	rows, err := s.attributes.QueryContext(ctx, product.ID)
+	if err != nil {
+		log.Errorf("Error retrieving product attributes: %v, %v", product.ID, err)
+		return err
+	}
+	return readAttributes(rows, map[string]*catalog.Product{product.ID: product})
+}
+
+// Products returns a page of the Products matching the query.
+func (s *ProductService) Products(ctx context.Context, q catalog.ProductQuery) (*catalog.ProductPage, error) {
+	c, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	keys := sortKeys(q.Sort)
+	query, args, err := buildListQuery(catalog.TenantFromContext(ctx), q, keys, c)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.PageSize()
+	// Fetch one extra row to find out whether there is another page
+	rows, err := s.client.db.QueryContext(ctx, query, append(args, limit+1)...)
 	if err != nil {
 		log.Errorf("Error retrieving products: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 	// Iterate over the results
-	var products []*catalog.Product
+	products := []*catalog.Product{}
 	for rows.Next() {
-		var product catalog.Product
-		if err := rows.Scan(&product.ID, &product.ProductCode, &product.ShortDesc, &product.LongDesc); err != nil {
+		product, err := scanProduct(rows)
+		if err != nil {
 			log.Errorf("Error scanning over rows: %v", err)
 			return nil, err
 		}
 		// Add the product record to the slice
-		products = append(products, &product)
+		products = append(products, product)
 	}
 	if err := rows.Err(); err != nil {
 		log.Errorf("Error iterating over rows: %v", err)
 		return nil, err
 	}
-	return products, err
+	result := &catalog.ProductPage{Products: products}
+	if len(products) > limit {
+		result.Products = products[:limit]
+		if result.Next, err = nextCursor(keys, result.Products[limit-1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.pageAttributes(ctx, result.Products); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-var insertstmt InsertStatement = "INSERT product SET productcode=?, shortdesc=?, longdesc=?"
+var insertstmt InsertStatement = "INSERT product SET tenant_id=?, productcode=?, shortdesc=?, longdesc=?, status=?, publish_at=?, unpublish_at=?"
 
-// CreateProduct stores a new product in the database.
+// CreateProduct stores a new product and its attribute values in the database.
 func (s *ProductService) CreateProduct(ctx context.Context, product *catalog.Product) error {
-	res, err := s.insert.ExecContext(ctx, product.ProductCode, product.ShortDesc, product.LongDesc)
+	if err := product.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		return s.createInTx(ctx, tx, product)
+	})
+}
+
+// createInTx inserts a validated product and its attribute values within a transaction.
+func (s *ProductService) createInTx(ctx context.Context, tx *sql.Tx, product *catalog.Product) error {
+	attrs, err := s.canonicalAttributes(ctx, tx, product)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
+	if product.Status == "" {
+		product.Status = catalog.StatusDraft
+	}
+	tenant := catalog.TenantFromContext(ctx)
+	if err := s.checkQuota(ctx, tx, tenant); err != nil {
+		return err
+	}
+	res, err := tx.StmtContext(ctx, s.insert).ExecContext(ctx, tenant, product.ProductCode, product.ShortDesc, product.LongDesc,
+		string(product.Status), timeArg(product.PublishAt), timeArg(product.UnpublishAt))
+	if err != nil {
+		log.Error(err)
+		return translateProductError(err, product.ProductCode)
+	}
 	id, err := res.LastInsertId()
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 	product.ID = strconv.Itoa(int(id))
+	product.Version = 1
 	log.WithField("productId", product.ID).
 		Debugf("New product.ProductId: %d", id)
-	return err
+	if err := s.writeAttributes(ctx, tx, product.ID, attrs); err != nil {
+		return err
+	}
+	return s.recordChange(ctx, tx, catalog.HistoryCreate, product.ID, product.Version, nil, product)
 }
 
-var updatestmt UpdateStatement = "UPDATE product SET productcode=?, shortdesc=?, longdesc=? WHERE id=?"
+var updatestmt UpdateStatement = "UPDATE product SET productcode=?, shortdesc=?, longdesc=?, status=?, publish_at=?, unpublish_at=?, " +
+	"version=version+1 WHERE tenant_id=? AND id=?"
+
+var lockversionstmt LockVersionStatement = "SELECT version, status FROM product WHERE tenant_id = ? AND id = ? AND deleted_at IS NULL FOR UPDATE"
 
-// UpdateProduct updates an existing product in the database.
+// UpdateProduct updates an existing product in the database, replacing its
+// attribute values. A non-zero product.Version must match the stored
+// version; on success product.Version holds the new version. A product
+// without a status keeps its current one, otherwise the status change must
+// be an allowed transition.
 func (s *ProductService) UpdateProduct(ctx context.Context, product *catalog.Product) error {
 	log.Infof("product: %v", product)
 	if len(product.ID) == 0 {
-		return errors.New("mysql: product with unassigned ID passed in to UpdateProduct")
+		return catalog.Errorf(catalog.ErrInvalid, "product with unassigned ID passed in to UpdateProduct")
 	}
-	res, err := s.update.ExecContext(ctx, product.ProductCode, product.ShortDesc, product.LongDesc, product.ID)
+	if err := product.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		return s.updateInTx(ctx, tx, product)
+	})
+}
+
+// updateInTx updates a validated product and replaces its attribute values
+// within a transaction.
+func (s *ProductService) updateInTx(ctx context.Context, tx *sql.Tx, product *catalog.Product) error {
+	current, status, err := s.lockProductVersion(ctx, tx, product.ID, product.Version)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
-	affect, err := res.RowsAffected()
+	if product.Status == "" {
+		product.Status = status
+	}
+	if err := catalog.CheckTransition(status, product.Status); err != nil {
+		return err
+	}
+	before, err := s.productInTx(ctx, tx, product.ID)
 	if err != nil {
+		return err
+	}
+	attrs, err := s.canonicalAttributes(ctx, tx, product)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, s.update).ExecContext(ctx, product.ProductCode, product.ShortDesc, product.LongDesc,
+		string(product.Status), timeArg(product.PublishAt), timeArg(product.UnpublishAt), catalog.TenantFromContext(ctx), product.ID); err != nil {
 		log.Error(err)
+		return translateProductError(err, product.ProductCode)
 	}
-	log.Debugf("Number of product rows updated: %d", affect)
-	return nil
+	product.Version = current + 1
+	if _, err := tx.StmtContext(ctx, s.deleteAttributes).ExecContext(ctx, product.ID); err != nil {
+		log.Error(err)
+		return err
+	}
+	if err := s.writeAttributes(ctx, tx, product.ID, attrs); err != nil {
+		return err
+	}
+	return s.recordChange(ctx, tx, catalog.HistoryUpdate, product.ID, product.Version, before, product)
+}
+
+// PatchProduct applies a partial update to a product. Only the columns and
+// attribute values named in the patch are written; the version is
+// incremented whenever something changes. A status change must be an
+// allowed transition.
+func (s *ProductService) PatchProduct(ctx context.Context, id string, patch *catalog.ProductPatch) (*catalog.Product, error) {
+	var product *catalog.Product
+	err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+		current, status, err := s.lockProductVersion(ctx, tx, id, patch.Version)
+		if err != nil {
+			return err
+		}
+		if product, err = s.productInTx(ctx, tx, id); err != nil {
+			return err
+		}
+		if patch.Empty() {
+			return nil
+		}
+		before := snapshot(product)
+		patch.Apply(product)
+		if err := product.Validate(); err != nil {
+			return err
+		}
+		if err := catalog.CheckTransition(status, product.Status); err != nil {
+			return err
+		}
+		// Validate the resulting attribute set, so that removing a required
+		// attribute fails, but write only the patched values.
+		values, err := s.canonicalAttributes(ctx, tx, product)
+		if err != nil {
+			return err
+		}
+		var set []string
+		var args []interface{}
+		for _, c := range []struct {
+			column string
+			value  *string
+		}{{"productcode", patch.ProductCode}, {"shortdesc", patch.ShortDesc}, {"longdesc", patch.LongDesc}} {
+			if c.value != nil {
+				set = append(set, c.column+"=?")
+				args = append(args, *c.value)
+			}
+		}
+		if patch.Status != nil {
+			set = append(set, "status=?")
+			args = append(args, string(product.Status))
+		}
+		if patch.PublishAt != nil {
+			set = append(set, "publish_at=?")
+			args = append(args, timeArg(product.PublishAt))
+		}
+		if patch.UnpublishAt != nil {
+			set = append(set, "unpublish_at=?")
+			args = append(args, timeArg(product.UnpublishAt))
+		}
+		set = append(set, "version=version+1")
+		if _, err := tx.ExecContext(ctx, "UPDATE product SET "+strings.Join(set, ", ")+" WHERE tenant_id=? AND id=?",
+			append(args, catalog.TenantFromContext(ctx), id)...); err != nil {
+			log.Error(err)
+			return translateProductError(err, product.ProductCode)
+		}
+		product.Version = current + 1
+		var changed []attributeValue
+		for _, v := range values {
+			if _, ok := patch.Attributes[v.name]; ok {
+				changed = append(changed, v)
+			}
+		}
+		for name, v := range patch.Attributes {
+			if v != nil {
+				continue
+			}
+			if _, err := tx.StmtContext(ctx, s.deleteAttribute).ExecContext(ctx, id, name); err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+		if err := s.writeAttributes(ctx, tx, id, changed); err != nil {
+			return err
+		}
+		return s.recordChange(ctx, tx, catalog.HistoryUpdate, id, product.Version, before, product)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
-var deletestmt DeleteStatement = "DELETE from product where id=?"
+// productInTx reads a product with its attribute values within a transaction.
+func (s *ProductService) productInTx(ctx context.Context, tx *sql.Tx, id string) (*catalog.Product, error) {
+	product, err := scanProduct(tx.StmtContext(ctx, s.get).QueryRowContext(ctx, catalog.TenantFromContext(ctx), id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	rows, err := tx.StmtContext(ctx, s.attributes).QueryContext(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if err := readAttributes(rows, map[string]*catalog.Product{product.ID: product}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
 
-// DeleteProduct deletes a product in the database.
-func (s *ProductService) DeleteProduct(ctx context.Context, id string) error {
-	res, err := s.delete.ExecContext(ctx, id)
+// lockProductVersion locks the product row for the rest of the transaction
+// and returns its version and status, checking the version against the
+// expected version unless that is zero.
+func (s *ProductService) lockProductVersion(ctx context.Context, tx *sql.Tx, id string, expected int64) (int64, catalog.ProductStatus, error) {
+	var current int64
+	var status catalog.ProductStatus
+	err := tx.StmtContext(ctx, s.lockVersion).QueryRowContext(ctx, catalog.TenantFromContext(ctx), id).Scan(&current, &status)
+	if err == sql.ErrNoRows {
+		return 0, "", catalog.Errorf(catalog.ErrNotFound, "product %v not found", id)
+	}
 	if err != nil {
 		log.Error(err)
+		return 0, "", err
+	}
+	if expected != 0 && expected != current {
+		return 0, "", catalog.Errorf(catalog.ErrVersionConflict,
+			"product %v is at version %d, not %d", id, current, expected)
+	}
+	return current, status, nil
+}
+
+// timeArg returns a nullable DATETIME argument, stored in UTC.
+func timeArg(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+var deletestmt DeleteStatement = "UPDATE product SET deleted_at=UTC_TIMESTAMP(), version=version+1 WHERE tenant_id=? AND id=? AND deleted_at IS NULL"
+
+// DeleteProduct soft deletes a product by setting its deleted_at time.
+// The product keeps its attributes, prices, variants and categories until
+// it is purged. A non-zero version must match the stored version.
+func (s *ProductService) DeleteProduct(ctx context.Context, id string, version int64) error {
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		return s.deleteInTx(ctx, tx, id, version)
+	})
+}
+
+// deleteInTx deletes a product within a transaction, checking a non-zero version.
+func (s *ProductService) deleteInTx(ctx context.Context, tx *sql.Tx, id string, version int64) error {
+	current, _, err := s.lockProductVersion(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+	before, err := s.productInTx(ctx, tx, id)
+	if err != nil {
 		return err
 	}
+	if _, err := tx.StmtContext(ctx, s.delete).ExecContext(ctx, catalog.TenantFromContext(ctx), id); err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	return s.recordChange(ctx, tx, catalog.HistoryDelete, id, current+1, before, nil)
+}
+
+var lockdeletedstmt LockDeletedStatement = "SELECT productcode FROM product WHERE tenant_id = ? AND id = ? AND deleted_at IS NOT NULL FOR UPDATE"
+
+var restorestmt RestoreStatement = "UPDATE product SET deleted_at=NULL, version=version+1 WHERE tenant_id=? AND id=?"
+
+// RestoreProduct undoes the deletion of a product. It fails with a
+// conflict when another product took the product code in the meantime.
+func (s *ProductService) RestoreProduct(ctx context.Context, id string) (*catalog.Product, error) {
+	var product *catalog.Product
+	err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+		tenant := catalog.TenantFromContext(ctx)
+		var code string
+		err := tx.StmtContext(ctx, s.lockDeleted).QueryRowContext(ctx, tenant, id).Scan(&code)
+		if err == sql.ErrNoRows {
+			return catalog.Errorf(catalog.ErrNotFound, "deleted product %v not found", id)
+		}
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		// A restored product counts against the quota again.
+		if err := s.checkQuota(ctx, tx, tenant); err != nil {
+			return err
+		}
+		if _, err := tx.StmtContext(ctx, s.restore).ExecContext(ctx, tenant, id); err != nil {
+			log.Error(err)
+			return translateProductError(err, code)
+		}
+		if product, err = s.productInTx(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.recordChange(ctx, tx, catalog.HistoryRestore, id, product.Version, nil, product)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+var purgestmt PurgeStatement = "DELETE FROM product WHERE tenant_id=? AND id=? AND deleted_at IS NOT NULL"
+
+// PurgeProduct permanently removes a deleted product together with its
+// attributes, prices, variants and category assignments.
+func (s *ProductService) PurgeProduct(ctx context.Context, id string) error {
+	res, err := s.purge.ExecContext(ctx, catalog.TenantFromContext(ctx), id)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
 	affect, err := res.RowsAffected()
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	log.Debugf("Number of product rows deleted: %d", affect)
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "deleted product %v not found", id)
+	}
 	return nil
 }
 
-// ensureTableExists checks the table exists. If not, it creates it.
-func (config MySQLConfig) ensureTableExists() error {
-	mc := mysql.NewConfig()
-	mc.User = config.Username
-	mc.Passwd = config.Password
-	mc.Net = "tcp"
-	mc.Addr = config.Host
-	mc.Params = map[string]string{"charset": "utf8"}
-	log.Infof("ensureTableExists formatDSN: %v", mc.FormatDSN())
-	conn, err := sql.Open("mysql", mc.FormatDSN())
+// purgeBatchSize limits the rows removed per statement by
+// PurgeDeletedProducts, keeping each transaction short.
+const purgeBatchSize = 500
+
+var purgedeletedstmt PurgeDeletedStatement = "DELETE FROM product WHERE deleted_at < ? ORDER BY deleted_at LIMIT ?"
+
+// PurgeDeletedProducts permanently removes the products of every tenant
+// deleted before the given time, purgeBatchSize rows at a time.
+func (s *ProductService) PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		res, err := s.purgeDeleted.ExecContext(ctx, before.UTC(), purgeBatchSize)
+		if err != nil {
+			log.Error(err)
+			return total, translateError(err)
+		}
+		affect, err := res.RowsAffected()
+		if err != nil {
+			log.Error(err)
+			return total, err
+		}
+		total += affect
+		if affect < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// ensureDatabaseExists creates the catalog database if it does not exist.
+// The tables are created by the schema migrations.
+func (config MySQLConfig) ensureDatabaseExists() error {
+	conn, err := sql.Open("mysql", config.dsn(""))
 	if err != nil {
 		return fmt.Errorf("mysql: could not get a connection: %v", err)
 	}
@@ -217,31 +671,8 @@ func (config MySQLConfig) ensureTableExists() error {
 			"could be bad address, or this address is not whitelisted for access.")
 	}
 
-	if _, err := conn.Exec("USE catalog"); err != nil {
-		// MySQL error 1049 is "database does not exist"
-		if mErr, ok := err.(*mysql.MySQLError); ok && mErr.Number == 1049 {
-			return createTable(conn)
-		}
-	}
-
-	if _, err := conn.Exec("DESCRIBE product"); err != nil {
-		// MySQL error 1146 is "table does not exist"
-		if mErr, ok := err.(*mysql.MySQLError); ok && mErr.Number == 1146 {
-			return createTable(conn)
-		}
-		// Unknown error.
-		return fmt.Errorf("mysql: could not connect to the database: %v", err)
-	}
-	return nil
-}
-
-// createTable creates the table, and if necessary, the database.
-func createTable(conn *sql.DB) error {
-	for _, stmt := range createTableStatements {
-		_, err := conn.Exec(stmt)
-		if err != nil {
-			return err
-		}
+	if _, err := conn.Exec("CREATE DATABASE IF NOT EXISTS catalog DEFAULT CHARACTER SET = 'utf8' DEFAULT COLLATE 'utf8_general_ci'"); err != nil {
+		return fmt.Errorf("mysql: could not create the catalog database: %v", err)
 	}
 	return nil
 }
@@ -2,15 +2,21 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/go-sql-driver/mysql"
 	"github.com/mvonbodun/go-package-test/catalog"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
-	"log"
 	"testing"
+	"time"
 )
 
 
 
+// productColumnNames are the columns of a product row.
+var productColumnNames = []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+
 func TestProductService_Product(t *testing.T) {
 	// Create DB Mock
 	db, mock, err := sqlmock.New()
@@ -19,18 +25,28 @@ func TestProductService_Product(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := []string{"id", "productcode", "shortdesc", "longdesc"}
-	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc FROM product WHERE id = \\?").
-		ExpectQuery().WithArgs("5").
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		WithArgs("default", "5").
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234"))
+			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234", 1, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}).
+			AddRow("5", "material", "string", "cotton").
+			AddRow("5", "weight", "number", "1.5"))
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(getstmt)
-	_, err = client.productService.Product(context.Background(), "5")
+	client.productService.prepareSqlStmt(getstmt, productattributesstmt)
+	product, err := client.productService.Product(context.Background(), "5")
 	if err != nil {
-		t.Errorf("expected no error, but got %s instead", err)
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if product.Attributes["material"] != "cotton" || product.Attributes["weight"] != 1.5 {
+		t.Errorf("unexpected attributes: %v", product.Attributes)
 	}
 	// make sure expectations were met
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -46,22 +62,49 @@ func TestProductService_ProductNotFound(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc FROM product WHERE id = \\?").
-		ExpectQuery().WithArgs("5").
-		WillReturnError(fmt.Errorf("no results"))
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		ExpectQuery().WithArgs("default", "5").
+		WillReturnError(sql.ErrNoRows)
 
 	client := NewClient()
 	client.db = db
 	client.productService.prepareSqlStmt(getstmt)
 	product, err := client.productService.Product(context.Background(), "5")
-	if err == nil {
-		t.Errorf("expected error, but got none")
+	if !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, but got %v", err)
+	}
+	if product != nil {
+		t.Errorf("expected no product, but got %v", product)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductDatabaseError(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		ExpectQuery().WithArgs("default", "5").
+		WillReturnError(fmt.Errorf("connection refused"))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(getstmt)
+	_, err = client.productService.Product(context.Background(), "5")
+	if err == nil || errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected a database error, but got %v", err)
 	}
 	// make sure expectations were met
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
-	log.Printf("productcode: %v", product.ProductCode)
 }
 
 func TestProductService_Products(t *testing.T) {
@@ -73,17 +116,19 @@ func TestProductService_Products(t *testing.T) {
 	defer db.Close()
 
 
-	columns := []string{"id", "productcode", "shortdesc", "longdesc"}
-	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc FROM product").
-		ExpectQuery().
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND deleted_at IS NULL ORDER BY id LIMIT \\?").
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234").
-			AddRow("6", "5678", "shortdesc for 5678", "longdesc for 5678"))
+			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234", 1, nil, "active", nil, nil).
+			AddRow("6", "5678", "shortdesc for 5678", "longdesc for 5678", 1, nil, "active", nil, nil))
 
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa " +
+		"JOIN attribute_definition d ON d.name = pa.name WHERE pa.product_id IN \\(\\?, \\?\\)").
+		WithArgs("5", "6").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(liststmt)
-	_, err = client.productService.Products(context.Background())
+	_, err = client.productService.Products(context.Background(), catalog.ProductQuery{})
 	if err != nil {
 		t.Errorf("expected no error, but got %s instead", err)
 	}
@@ -93,6 +138,148 @@ func TestProductService_Products(t *testing.T) {
 	}
 }
 
+func TestProductService_ProductsNextPage(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND deleted_at IS NULL AND \\(\\(id > \\?\\)\\) ORDER BY id LIMIT \\?").
+		WithArgs("default", 4, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234", 1, nil, "active", nil, nil).
+			AddRow("6", "5678", "shortdesc for 5678", "longdesc for 5678", 1, nil, "active", nil, nil).
+			AddRow("7", "9012", "shortdesc for 9012", "longdesc for 9012", 1, nil, "active", nil, nil))
+
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa " +
+		"JOIN attribute_definition d ON d.name = pa.name WHERE pa.product_id IN \\(\\?, \\?\\)").
+		WithArgs("5", "6").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	client := NewClient()
+	client.db = db
+	page, err := client.productService.Products(context.Background(), catalog.ProductQuery{
+		Page: catalog.Page{Cursor: encodeCursor(cursor{Sort: "productId", ID: 4}), Limit: 2},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(page.Products) != 2 {
+		t.Errorf("expected 2 products, got %d", len(page.Products))
+	}
+	if c, err := decodeCursor(page.Next); err != nil || c.ID != 6 {
+		t.Errorf("expected next cursor at id 6, got %v (%v)", c.ID, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductsFilteredAndSorted(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product " +
+		"WHERE tenant_id = \\? AND deleted_at IS NULL AND productcode LIKE \\? AND shortdesc LIKE \\? AND id >= \\? AND id <= \\? " +
+		"AND \\(\\(productcode < \\?\\) OR \\(productcode = \\? AND id > \\?\\)\\) " +
+		"ORDER BY productcode DESC, id LIMIT \\?").
+		WithArgs("default", "ab\\%%", "%shirt%", 10, 99, "abz", "abz", 12, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("15", "aby", "shirt", "longdesc", 1, nil, "active", nil, nil))
+
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa " +
+		"JOIN attribute_definition d ON d.name = pa.name WHERE pa.product_id IN \\(\\?\\)").
+		WithArgs("15").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	client := NewClient()
+	client.db = db
+	sort, err := catalog.ParseSort("-productCode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := client.productService.Products(context.Background(), catalog.ProductQuery{
+		Page:              catalog.Page{Cursor: encodeCursor(cursor{Sort: "-productCode,productId", Values: []string{"abz"}, ID: 12}), Limit: 1},
+		ProductCodePrefix: "ab%",
+		ShortDescContains: "shirt",
+		MinID:             10,
+		MaxID:             99,
+		Sort:              sort,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if page.Next != "" {
+		t.Errorf("expected last page, got next cursor %q", page.Next)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductsInCategory(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product " +
+		"WHERE tenant_id = \\? AND deleted_at IS NULL AND id IN \\(SELECT pc.product_id FROM product_category pc " +
+		"JOIN category_closure cc ON cc.descendant_id = pc.category_id WHERE cc.ancestor_id = \\?\\) " +
+		"ORDER BY id LIMIT \\?").
+		WithArgs("default", "7", 51).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("15", "aby", "shirt", "longdesc", 1, nil, "active", nil, nil))
+
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa " +
+		"JOIN attribute_definition d ON d.name = pa.name WHERE pa.product_id IN \\(\\?\\)").
+		WithArgs("15").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	client := NewClient()
+	client.db = db
+	page, err := client.productService.Products(context.Background(), catalog.ProductQuery{
+		CategoryID:         "7",
+		IncludeDescendants: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(page.Products) != 1 {
+		t.Errorf("expected 1 product, got %d", len(page.Products))
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductsInvalidCursor(t *testing.T) {
+	client := NewClient()
+	q := catalog.ProductQuery{Page: catalog.Page{Cursor: "!!"}}
+	if _, err := client.productService.Products(context.Background(), q); err != catalog.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	// A cursor issued for a different sort order is rejected too.
+	q = catalog.ProductQuery{
+		Page: catalog.Page{Cursor: encodeCursor(cursor{Sort: "productId", ID: 4})},
+		Sort: []catalog.SortField{{Field: catalog.SortByShortDesc}},
+	}
+	if _, err := client.productService.Products(context.Background(), q); err != catalog.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
 func TestProductService_ProductsNoneReturned(t *testing.T) {
 	// Create DB Mock
 	db, mock, err := sqlmock.New()
@@ -102,15 +289,13 @@ func TestProductService_ProductsNoneReturned(t *testing.T) {
 	defer db.Close()
 
 
-	//columns := []string{"id", "productcode", "shortdesc", "longdesc"}
-	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc FROM product").
-		ExpectQuery().
+	//columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND deleted_at IS NULL ORDER BY id LIMIT \\?").
 		WillReturnError(fmt.Errorf("no results"))
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(liststmt)
-	_, err = client.productService.Products(context.Background())
+	_, err = client.productService.Products(context.Background(), catalog.ProductQuery{})
 	if err == nil {
 		t.Errorf("expected error, but got none")
 	}
@@ -128,17 +313,15 @@ func TestProductService_ProductErrorScanningRows(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := []string{"id", "productcode", "shortdesc", "longdesc"}
-	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc FROM product").
-		ExpectQuery().
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND deleted_at IS NULL ORDER BY id LIMIT \\?").
 		WillReturnRows(sqlmock.NewRows(columns).RowError(1, fmt.Errorf("error reading row")).
-			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234").
-			AddRow("6", "5678", "shortdesc for 5678", "longdesc for 5678"))
+			AddRow("5", "1234", "shortdesc for 1234", "longdesc for 1234", 1, nil, "active", nil, nil).
+			AddRow("6", "5678", "shortdesc for 5678", "longdesc for 5678", 1, nil, "active", nil, nil))
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(liststmt)
-	_, err = client.productService.Products(context.Background())
+	_, err = client.productService.Products(context.Background(), catalog.ProductQuery{})
 	if err == nil {
 		t.Errorf("expected error, but got none")
 	}
@@ -156,14 +339,31 @@ func TestProductService_CreateProduct(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectPrepare("INSERT product SET productcode=\\?, shortdesc=\\?, longdesc=\\?").
-		ExpectExec().
-		WithArgs("1234", "shortdesc for 1234", "longdesc for 1234").
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
+		WithArgs("default", "1234", "shortdesc for 1234", "longdesc for 1234", "draft", nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "1", 1, "create", "", nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.created", "default", "1", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(insertstmt)
+	client.productService.prepareSqlStmt(insertstmt, inserthistorystmt, inserteventstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
 	product := &catalog.Product{
 		ProductCode: "1234",
 		ShortDesc: "shortdesc for 1234",
@@ -187,13 +387,22 @@ func TestProductService_CreateProductFailedInsert(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectPrepare("INSERT product SET productcode=\\?, shortdesc=\\?, longdesc=\\?").
-		ExpectExec().
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
 		WillReturnError(fmt.Errorf("error inserting row"))
+	mock.ExpectRollback()
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(insertstmt)
+	client.productService.prepareSqlStmt(insertstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
 	product := &catalog.Product{
 		ProductCode: "1234",
 		ShortDesc: "shortdesc for 1234",
@@ -217,15 +426,37 @@ func TestProductService_DeleteProduct(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectPrepare("DELETE from product where id=\\?").
-		ExpectExec().
+	mock.ExpectPrepare("UPDATE product SET deleted_at=UTC_TIMESTAMP\\(\\), version=version\\+1 WHERE tenant_id=\\? AND id=\\? AND deleted_at IS NULL")
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "active"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows(productColumnNames).
+			AddRow("1", "1234", "", "", 3, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectExec("UPDATE product SET deleted_at").WithArgs("default", "1").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "1", 4, "delete", "", sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.deleted", "default", "1", 4, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(deletestmt)
+	client.productService.prepareSqlStmt(deletestmt, lockversionstmt, getstmt, productattributesstmt, inserthistorystmt, inserteventstmt)
 
-	if err := client.productService.DeleteProduct(context.Background(), "1"); err != nil {
+	if err := client.productService.DeleteProduct(context.Background(), "1", 0); err != nil {
 		t.Errorf("expected no error but got: %v instead", err)
 	}
 	// make sure expectations were met
@@ -242,15 +473,29 @@ func TestProductService_DeleteProductFailedDelete (t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectPrepare("DELETE from product where id=\\?").
-		ExpectExec().
+	mock.ExpectPrepare("UPDATE product SET deleted_at=UTC_TIMESTAMP\\(\\), version=version\\+1 WHERE tenant_id=\\? AND id=\\? AND deleted_at IS NULL")
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "active"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows(productColumnNames).
+			AddRow("1", "1234", "", "", 3, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectExec("UPDATE product SET deleted_at").WithArgs("default", "1").
 		WillReturnError(fmt.Errorf("failed deleting record"))
+	mock.ExpectRollback()
 
 	client := NewClient()
 	client.db = db
-	client.productService.prepareSqlStmt(deletestmt)
+	client.productService.prepareSqlStmt(deletestmt, lockversionstmt, getstmt, productattributesstmt)
 
-	if err := client.productService.DeleteProduct(context.Background(), "1"); err == nil {
+	if err := client.productService.DeleteProduct(context.Background(), "1", 0); err == nil {
 		t.Errorf("expected error but got none")
 	}
 	// make sure expectations were met
@@ -258,4 +503,807 @@ func TestProductService_DeleteProductFailedDelete (t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 
-}
\ No newline at end of file
+}
+
+func TestProductService_CreateProductDuplicate(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	if err := client.productService.CreateProduct(context.Background(), &catalog.Product{ProductCode: "1234"}); !errors.Is(err, catalog.ErrConflict) {
+		t.Errorf("expected ErrConflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_CreateProductDuplicateCode(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1234' for key 'product_productcode'"})
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	err = client.productService.CreateProduct(context.Background(), &catalog.Product{ProductCode: "1234"})
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || cErr.Kind != catalog.ErrConflict || len(cErr.Fields) != 1 || cErr.Fields[0].Field != "productCode" {
+		t.Errorf("expected a productCode conflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductByCode(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND productcode = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND productcode = \\?").
+		WithArgs("default", "tee").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("5", "tee", "Tee", "", 2, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND productcode = \\?").
+		WithArgs("default", "cap").
+		WillReturnError(sql.ErrNoRows)
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(getbycodestmt, productattributesstmt)
+	product, err := client.productService.ProductByCode(context.Background(), "tee")
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if product.ID != "5" || product.Version != 2 {
+		t.Errorf("unexpected product: %+v", product)
+	}
+	if _, err := client.productService.ProductByCode(context.Background(), "cap"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_UpdateProductNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt)
+
+	if err := client.productService.UpdateProduct(context.Background(), &catalog.Product{ID: "1", ProductCode: "1234"}); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_UpdateProduct(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("UPDATE product SET productcode=\\?, shortdesc=\\?, longdesc=\\?, status=\\?, publish_at=\\?, unpublish_at=\\?, version=version\\+1 WHERE tenant_id=\\? AND id=\\?")
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("DELETE FROM product_attribute WHERE product_id=\\?")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "active"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").
+		WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows(productColumnNames).
+			AddRow("1", "1234", "", "", 3, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectExec("UPDATE product SET").WithArgs("1234", "", "", "active", nil, nil, "default", "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM product_attribute").WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "1", 4, "update", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.updated", "default", "1", 4, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(updatestmt, lockversionstmt, deleteproductattributesstmt, getstmt,
+		productattributesstmt, inserthistorystmt, inserteventstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	product := &catalog.Product{ID: "1", ProductCode: "1234", Version: 3}
+	if err := client.productService.UpdateProduct(context.Background(), product); err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if product.Version != 4 {
+		t.Errorf("expected version 4, got %d", product.Version)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_UpdateProductStaleVersion(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(4, "active"))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt)
+
+	product := &catalog.Product{ID: "1", ProductCode: "1234", Version: 3}
+	if err := client.productService.UpdateProduct(context.Background(), product); !errors.Is(err, catalog.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_DeleteProductStaleVersion(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(4, "active"))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt)
+
+	if err := client.productService.DeleteProduct(context.Background(), "1", 3); !errors.Is(err, catalog.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_UpdateProductNoID(t *testing.T) {
+	client := NewClient()
+	if err := client.productService.UpdateProduct(context.Background(), &catalog.Product{}); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+}
+
+func TestProductService_DeleteProductNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt)
+
+	if err := client.productService.DeleteProduct(context.Background(), "1", 0); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_CreateProductInvalid(t *testing.T) {
+	client := NewClient()
+	err := client.productService.CreateProduct(context.Background(), &catalog.Product{})
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || !errors.Is(err, catalog.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid but got: %v", err)
+	}
+	if len(cErr.Fields) != 1 || cErr.Fields[0].Field != "productCode" {
+		t.Errorf("expected a productCode field error, got %v", cErr.Fields)
+	}
+}
+
+func TestProductService_CreateProductWithAttributes(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?, shortdesc=\\?, longdesc=\\?")
+	mock.ExpectPrepare("INSERT product_attribute SET product_id=\\?, name=\\?, value=\\?, value_number=\\?")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).
+			AddRow("voltage", "integer", `["110","230"]`, "V", true))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("INSERT product SET").WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec("INSERT product_attribute SET").WithArgs("8", "voltage", "230", float64(230)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "8", 1, "create", "", nil, `{"productId":"8","productCode":"kettle","shortDesc":"","longDesc":"","version":1,"attributes":{"voltage":230},"status":"draft"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.created", "default", "8", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, insertproductattributestmt, inserthistorystmt, inserteventstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+	product := &catalog.Product{ProductCode: "kettle", Attributes: map[string]interface{}{"voltage": "230"}}
+	if err := client.productService.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if product.Attributes["voltage"] != int64(230) {
+		t.Errorf("expected typed voltage 230, got %#v", product.Attributes["voltage"])
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_CreateProductInvalidAttributes(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).
+			AddRow("material", "string", nil, "", true).
+			AddRow("voltage", "integer", `["110","230"]`, "V", false))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+	product := &catalog.Product{ProductCode: "kettle", Attributes: map[string]interface{}{"voltage": 120.0, "colour": "red"}}
+	err = client.productService.CreateProduct(context.Background(), product)
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || !errors.Is(err, catalog.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid but got: %v", err)
+	}
+	if len(cErr.Fields) != 3 {
+		t.Errorf("expected material, voltage and colour field errors, got %v", cErr.Fields)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductsFilteredByAttribute(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product " +
+		"WHERE tenant_id = \\? AND deleted_at IS NULL AND id IN \\(SELECT pa.product_id FROM product_attribute pa " +
+		"WHERE pa.name = \\? AND \\(pa.value = \\? OR pa.value_number = \\?\\)\\) " +
+		"AND id IN \\(SELECT pa.product_id FROM product_attribute pa " +
+		"WHERE pa.name = \\? AND \\(pa.value = \\? OR pa.value_number = \\?\\)\\) " +
+		"ORDER BY id LIMIT \\?").
+		WithArgs("default", "material", "cotton", nil, "weight", "1.50", 1.5, 51).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	client := NewClient()
+	client.db = db
+	_, err = client.productService.Products(context.Background(), catalog.ProductQuery{
+		Attributes: []catalog.AttributeFilter{{Name: "material", Value: "cotton"}, {Name: "weight", Value: "1.50"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_PatchProduct(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("INSERT product_attribute SET")
+	mock.ExpectPrepare("DELETE FROM product_attribute WHERE product_id=\\? AND name=\\?")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "active"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("5", "tee", "old", "long", 3, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}).
+			AddRow("5", "material", "string", "wool").
+			AddRow("5", "weight", "number", "1.5"))
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).
+			AddRow("material", "string", nil, "", true).
+			AddRow("weight", "number", nil, "kg", false))
+	mock.ExpectExec("UPDATE product SET shortdesc=\\?, version=version\\+1 WHERE tenant_id=\\? AND id=\\?").WithArgs("new", "default", "5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM product_attribute WHERE product_id=\\? AND name=\\?").WithArgs("5", "weight").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT product_attribute SET").WithArgs("5", "material", "cotton", nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "5", 4, "update", "",
+			`{"productId":"5","productCode":"tee","shortDesc":"old","longDesc":"long","version":3,"attributes":{"material":"wool","weight":1.5},"status":"active"}`,
+			`{"productId":"5","productCode":"tee","shortDesc":"new","longDesc":"long","version":4,"attributes":{"material":"cotton"},"status":"active"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.updated", "default", "5", 4, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt, getstmt, productattributesstmt,
+		insertproductattributestmt, deleteproductattributestmt, inserthistorystmt, inserteventstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	shortDesc := "new"
+	patch := &catalog.ProductPatch{
+		ShortDesc:  &shortDesc,
+		Attributes: map[string]interface{}{"material": "cotton", "weight": nil},
+	}
+	product, err := client.productService.PatchProduct(context.Background(), "5", patch)
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if product.ShortDesc != "new" || product.LongDesc != "long" || product.Version != 4 {
+		t.Errorf("unexpected product: %+v", product)
+	}
+	if _, ok := product.Attributes["weight"]; ok || product.Attributes["material"] != "cotton" {
+		t.Errorf("unexpected attributes: %v", product.Attributes)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_PatchProductRemovesRequiredAttribute(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "active"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("5", "tee", "old", "long", 3, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}).
+			AddRow("5", "material", "string", "wool"))
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns).AddRow("material", "string", nil, "", true))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt, getstmt, productattributesstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	patch := &catalog.ProductPatch{Attributes: map[string]interface{}{"material": nil}}
+	if _, err := client.productService.PatchProduct(context.Background(), "5", patch); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_RestoreProduct(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NOT NULL FOR UPDATE")
+	mock.ExpectPrepare("UPDATE product SET deleted_at=NULL, version=version\\+1 WHERE tenant_id=\\? AND id=\\?")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"productcode"}).AddRow("tee"))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("UPDATE product SET deleted_at=NULL").WithArgs("default", "5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("5", "tee", "Tee", "", 4, nil, "active", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "5", 4, "restore", "", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.restored", "default", "5", 4, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockdeletedstmt, restorestmt, getstmt, productattributesstmt, inserthistorystmt, inserteventstmt, locktenantstmt)
+
+	product, err := client.productService.RestoreProduct(context.Background(), "5")
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if product.ID != "5" || product.Version != 4 || product.DeletedAt != nil {
+		t.Errorf("unexpected product: %+v", product)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_RestoreProductCodeInUse(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NOT NULL FOR UPDATE")
+	mock.ExpectPrepare("UPDATE product SET deleted_at=NULL, version=version\\+1 WHERE tenant_id=\\? AND id=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"productcode"}).AddRow("tee"))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(0, 0))
+	mock.ExpectExec("UPDATE product SET deleted_at=NULL").WithArgs("default", "5").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'tee' for key 'product_productcode'"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "6").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockdeletedstmt, restorestmt, locktenantstmt)
+
+	_, err = client.productService.RestoreProduct(context.Background(), "5")
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || cErr.Kind != catalog.ErrConflict || len(cErr.Fields) != 1 || cErr.Fields[0].Field != "productCode" {
+		t.Errorf("expected a productCode conflict but got: %v", err)
+	}
+	if _, err := client.productService.RestoreProduct(context.Background(), "6"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_PurgeProduct(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("DELETE FROM product WHERE tenant_id=\\? AND id=\\? AND deleted_at IS NOT NULL")
+	mock.ExpectExec("DELETE FROM product WHERE tenant_id=\\? AND id=\\?").WithArgs("default", "5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM product WHERE tenant_id=\\? AND id=\\?").WithArgs("default", "6").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(purgestmt)
+
+	if err := client.productService.PurgeProduct(context.Background(), "5"); err != nil {
+		t.Errorf("expected no error but got: %v", err)
+	}
+	if err := client.productService.PurgeProduct(context.Background(), "6"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a product that is not deleted, got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_PurgeDeletedProducts(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	before := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectPrepare("DELETE FROM product WHERE deleted_at < \\? ORDER BY deleted_at LIMIT \\?")
+	mock.ExpectExec("DELETE FROM product WHERE deleted_at").WithArgs(before, purgeBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, purgeBatchSize))
+	mock.ExpectExec("DELETE FROM product WHERE deleted_at").WithArgs(before, purgeBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(purgedeletedstmt)
+
+	n, err := client.productService.PurgeDeletedProducts(context.Background(), before)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if n != purgeBatchSize+7 {
+		t.Errorf("expected %d products purged, got %d", purgeBatchSize+7, n)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_ProductsPublished(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product " +
+		"WHERE tenant_id = \\? AND deleted_at IS NULL AND status = \\? AND \\(publish_at IS NULL OR publish_at <= \\?\\) " +
+		"AND \\(unpublish_at IS NULL OR unpublish_at > \\?\\) ORDER BY id LIMIT \\?").
+		WithArgs("default", "active", at, at, catalog.DefaultPageSize+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("5", "1234", "shortdesc for 1234", "", 1, nil, "active", at.Add(-time.Hour), nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").
+		WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+
+	client := NewClient()
+	client.db = db
+	page, err := client.productService.Products(context.Background(), catalog.ProductQuery{PublishedAt: at})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if p := page.Products[0]; p.Status != catalog.StatusActive || p.PublishAt == nil || !p.PublishAt.Equal(at.Add(-time.Hour)) {
+		t.Errorf("unexpected product: %+v", p)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_UpdateProductStatusTransition(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE").WithArgs("default", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(4, "draft"))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt)
+
+	// A draft has to be reviewed before it becomes active.
+	product := &catalog.Product{ID: "1", ProductCode: "1234", Status: catalog.StatusActive}
+	err = client.productService.UpdateProduct(context.Background(), product)
+	var cErr *catalog.Error
+	if !errors.As(err, &cErr) || cErr.Kind != catalog.ErrConflict || len(cErr.Fields) != 1 || cErr.Fields[0].Field != "status" {
+		t.Errorf("expected a status conflict but got: %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_PatchProductSchedule(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	publishAt := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "productcode", "shortdesc", "longdesc", "version", "deleted_at", "status", "publish_at", "unpublish_at"}
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa")
+	mock.ExpectPrepare("INSERT product_history SET")
+	mock.ExpectPrepare("INSERT product_event SET")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}).AddRow(3, "review"))
+	mock.ExpectQuery("SELECT id, productcode, shortdesc, longdesc, version, deleted_at, status, publish_at, unpublish_at FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("default", "5").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("5", "tee", "Tee", "", 3, nil, "review", nil, nil))
+	mock.ExpectQuery("SELECT pa.product_id, pa.name, d.type, pa.value FROM product_attribute pa").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "type", "value"}))
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectExec("UPDATE product SET status=\\?, publish_at=\\?, version=version\\+1 WHERE tenant_id=\\? AND id=\\?").
+		WithArgs("active", publishAt, "default", "5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT product_history SET").
+		WithArgs("default", "5", 4, "update", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT product_event SET").
+		WithArgs("product.updated", "default", "5", 4, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt, getstmt, productattributesstmt, inserthistorystmt, inserteventstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	status := catalog.StatusActive
+	at := &publishAt
+	product, err := client.productService.PatchProduct(context.Background(), "5",
+		&catalog.ProductPatch{Status: &status, PublishAt: &at})
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if product.Status != catalog.StatusActive || product.Version != 4 || product.Published(publishAt.Add(-time.Second)) {
+		t.Errorf("unexpected product: %+v", product)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
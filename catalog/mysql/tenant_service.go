@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure TenantService implements catalog.TenantService
+var _ catalog.TenantService = &TenantService{}
+
+// TenantService represents a service for provisioning tenants.
+type TenantService struct {
+	client *Client
+	get    *sql.Stmt
+	list   *sql.Stmt
+	insert *sql.Stmt
+	update *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetTenantStatement    SqlStatement
+	ListTenantsStatement  SqlStatement
+	InsertTenantStatement SqlStatement
+	UpdateTenantStatement SqlStatement
+	LockTenantStatement   SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *TenantService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(gettenantstmt, listtenantsstmt, inserttenantstmt, updatetenantstmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *TenantService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetTenantStatement:
+			err = prepare(&s.get, "get tenant", string(stmt))
+		case ListTenantsStatement:
+			err = prepare(&s.list, "list tenants", string(stmt))
+		case InsertTenantStatement:
+			err = prepare(&s.insert, "insert tenant", string(stmt))
+		case UpdateTenantStatement:
+			err = prepare(&s.update, "update tenant", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantColumns is the column list selected for every tenant read, with
+// the number of products that are not deleted.
+const tenantColumns = "t.id, t.name, t.max_products, " +
+	"(SELECT COUNT(*) FROM product p WHERE p.tenant_id = t.id AND p.deleted_at IS NULL), t.created_at"
+
+// scanTenant scans a row selected with tenantColumns.
+func scanTenant(row rowScanner) (*catalog.Tenant, error) {
+	var t catalog.Tenant
+	if err := row.Scan(&t.ID, &t.Name, &t.MaxProducts, &t.Products, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+var gettenantstmt GetTenantStatement = "SELECT " + tenantColumns + " FROM tenant t WHERE t.id = ?"
+
+// Tenant returns a tenant by ID.
+func (s *TenantService) Tenant(ctx context.Context, id string) (*catalog.Tenant, error) {
+	t, err := scanTenant(s.get.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "tenant %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving tenant: %v, %v", id, err)
+		return nil, err
+	}
+	return t, nil
+}
+
+var listtenantsstmt ListTenantsStatement = "SELECT " + tenantColumns + " FROM tenant t ORDER BY t.id"
+
+// Tenants returns all tenants ordered by ID.
+func (s *TenantService) Tenants(ctx context.Context) ([]*catalog.Tenant, error) {
+	rows, err := s.list.QueryContext(ctx)
+	if err != nil {
+		log.Errorf("Error retrieving tenants: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	tenants := []*catalog.Tenant{}
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return tenants, nil
+}
+
+var inserttenantstmt InsertTenantStatement = "INSERT tenant SET id=?, name=?, max_products=?, created_at=?"
+
+// CreateTenant provisions a new tenant. Its ID must not be taken.
+func (s *TenantService) CreateTenant(ctx context.Context, t *catalog.Tenant) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC()
+	if _, err := s.insert.ExecContext(ctx, t.ID, t.Name, t.MaxProducts, createdAt); err != nil {
+		log.Error(err)
+		if mErr := translateError(err); mErr != err {
+			return catalog.Errorf(catalog.ErrConflict, "tenant %v already exists", t.ID)
+		}
+		return err
+	}
+	t.Products = 0
+	t.CreatedAt = createdAt
+	return nil
+}
+
+var updatetenantstmt UpdateTenantStatement = "UPDATE tenant SET name=?, max_products=? WHERE id=?"
+
+// UpdateTenant changes the name and quota of a tenant.
+func (s *TenantService) UpdateTenant(ctx context.Context, t *catalog.Tenant) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	res, err := s.update.ExecContext(ctx, t.Name, t.MaxProducts, t.ID)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "tenant %v not found", t.ID)
+	}
+	return nil
+}
+
+// locktenantstmt locks the tenant, so that concurrent creates of its
+// products cannot both take the last product of the quota.
+var locktenantstmt LockTenantStatement = "SELECT t.max_products, " +
+	"(SELECT COUNT(*) FROM product p WHERE p.tenant_id = t.id AND p.deleted_at IS NULL) FROM tenant t WHERE t.id = ? FOR UPDATE"
+
+// checkQuota fails with ErrQuotaExceeded when the tenant cannot have
+// another product, and with ErrInvalid when it does not exist.
+func (s *ProductService) checkQuota(ctx context.Context, tx *sql.Tx, tenant string) error {
+	var max, count int
+	err := tx.StmtContext(ctx, s.lockTenant).QueryRowContext(ctx, tenant).Scan(&max, &count)
+	if err == sql.ErrNoRows {
+		return catalog.Errorf(catalog.ErrInvalid, "tenant %v does not exist", tenant)
+	}
+	if err != nil {
+		log.Errorf("Error locking tenant: %v, %v", tenant, err)
+		return err
+	}
+	if max > 0 && count >= max {
+		return catalog.Errorf(catalog.ErrQuotaExceeded, "tenant %v has reached its quota of %d products", tenant, max)
+	}
+	return nil
+}
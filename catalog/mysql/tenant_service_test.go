@@ -0,0 +1,285 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var tenantColumnNames = []string{"id", "name", "max_products", "products", "created_at"}
+
+var quotaColumnNames = []string{"max_products", "products"}
+
+func TestTenantService_Tenants(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT t.id, t.name, t.max_products, \\(SELECT COUNT\\(\\*\\) FROM product p " +
+		"WHERE p.tenant_id = t.id AND p.deleted_at IS NULL\\), t.created_at FROM tenant t ORDER BY t.id")
+	mock.ExpectQuery("SELECT t.id, t.name, .* FROM tenant t").
+		WillReturnRows(sqlmock.NewRows(tenantColumnNames).
+			AddRow("acme", "Acme", 100, 42, createdAt).
+			AddRow("default", "Default", 0, 7, createdAt))
+
+	client := NewClient()
+	client.db = db
+	client.tenantService.prepareSqlStmt(listtenantsstmt)
+
+	tenants, err := client.tenantService.Tenants(context.Background())
+	if err != nil || len(tenants) != 2 {
+		t.Fatalf("expected 2 tenants, got %v, %v", tenants, err)
+	}
+	if tt := tenants[0]; tt.ID != "acme" || tt.MaxProducts != 100 || tt.Products != 42 {
+		t.Errorf("unexpected tenant: %+v", tt)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantService_CreateTenantDuplicate(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT tenant SET id=\\?, name=\\?, max_products=\\?, created_at=\\?")
+	mock.ExpectExec("INSERT tenant SET").WithArgs("acme", "Acme", 100, sqlmock.AnyArg()).
+		WillReturnError(&mysql.MySQLError{Number: errDupEntry, Message: "Duplicate entry 'acme' for key 'PRIMARY'"})
+
+	client := NewClient()
+	client.db = db
+	client.tenantService.prepareSqlStmt(inserttenantstmt)
+
+	err = client.tenantService.CreateTenant(context.Background(), &catalog.Tenant{ID: "acme", Name: "Acme", MaxProducts: 100})
+	if !errors.Is(err, catalog.ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	// The message of the server names the key and value; clients see none of it.
+	if strings.Contains(err.Error(), "PRIMARY") {
+		t.Errorf("expected the server message to be left out, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantService_CreateTenantInvalid(t *testing.T) {
+	client := NewClient()
+	err := client.tenantService.CreateTenant(context.Background(), &catalog.Tenant{ID: "Acme Inc", MaxProducts: -1})
+	e, ok := err.(*catalog.Error)
+	if !ok || e.Kind != catalog.ErrInvalid || len(e.Fields) != 3 {
+		t.Errorf("expected 3 invalid fields, got %v", err)
+	}
+}
+
+func TestTenantService_UpdateTenantNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("UPDATE tenant SET name=\\?, max_products=\\? WHERE id=\\?")
+	mock.ExpectExec("UPDATE tenant SET").WithArgs("Globex", 10, "globex").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.tenantService.prepareSqlStmt(updatetenantstmt)
+
+	err = client.tenantService.UpdateTenant(context.Background(), &catalog.Tenant{ID: "globex", Name: "Globex", MaxProducts: 10})
+	if !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_CreateProductQuotaExceeded(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames).AddRow(100, 100))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	ctx := catalog.WithTenant(context.Background(), "acme")
+	err = client.productService.CreateProduct(ctx, &catalog.Product{ProductCode: "1234"})
+	if !errors.Is(err, catalog.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_CreateProductUnknownTenant(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT product SET tenant_id=\\?, productcode=\\?")
+	mock.ExpectPrepare("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE")
+	mock.ExpectPrepare("SELECT name, type, allowed_values, unit, required FROM attribute_definition")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, type, allowed_values, unit, required FROM attribute_definition").
+		WillReturnRows(sqlmock.NewRows(attributeColumns))
+	mock.ExpectQuery("SELECT t.max_products, .* FROM tenant t WHERE t.id = \\? FOR UPDATE").WithArgs("initech").
+		WillReturnRows(sqlmock.NewRows(quotaColumnNames))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(insertstmt, locktenantstmt)
+	client.attributeService.prepareSqlStmt(listattributesstmt)
+
+	ctx := catalog.WithTenant(context.Background(), "initech")
+	err = client.productService.CreateProduct(ctx, &catalog.Product{ProductCode: "1234"})
+	if !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// The row level isolation tests check that every statement reading or
+// changing a product passes the tenant of the context, so that the product
+// of another tenant is not found.
+
+func TestProductService_TenantIsolationRead(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT id, .* FROM product WHERE tenant_id = \\? AND id = \\?")
+	mock.ExpectPrepare("SELECT id, .* FROM product WHERE tenant_id = \\? AND productcode = \\?")
+	mock.ExpectPrepare("SELECT product_id, .* FROM product_history WHERE tenant_id = \\? AND product_id = \\? AND version = \\?")
+	// Product 5 belongs to acme.
+	mock.ExpectQuery("SELECT id, .* FROM product WHERE tenant_id = \\? AND id = \\?").WithArgs("globex", "5").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT id, .* FROM product WHERE tenant_id = \\? AND productcode = \\?").WithArgs("globex", "tee").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT product_id, .* FROM product_history").WithArgs("globex", "5", 1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT id, .* FROM product WHERE tenant_id = \\? AND deleted_at IS NULL ORDER BY id LIMIT \\?").
+		WithArgs("globex", catalog.DefaultPageSize+1).
+		WillReturnRows(sqlmock.NewRows(productColumnNames))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(getstmt, getbycodestmt, revisionstmt)
+
+	ctx := catalog.WithTenant(context.Background(), "globex")
+	if _, err := client.productService.Product(ctx, "5"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the product, got %v", err)
+	}
+	if _, err := client.productService.ProductByCode(ctx, "tee"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the product code, got %v", err)
+	}
+	if _, err := client.productService.ProductRevision(ctx, "5", 1); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the revision, got %v", err)
+	}
+	page, err := client.productService.Products(ctx, catalog.ProductQuery{})
+	if err != nil || len(page.Products) != 0 {
+		t.Errorf("expected no products, got %+v, %v", page, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductService_TenantIsolationWrite(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT version, status FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NULL FOR UPDATE")
+	mock.ExpectPrepare("SELECT productcode FROM product WHERE tenant_id = \\? AND id = \\? AND deleted_at IS NOT NULL FOR UPDATE")
+	mock.ExpectPrepare("DELETE FROM product WHERE tenant_id=\\? AND id=\\? AND deleted_at IS NOT NULL")
+	// Product 5 belongs to acme.
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product").WithArgs("globex", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version, status FROM product").WithArgs("globex", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "status"}))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT productcode FROM product").WithArgs("globex", "5").
+		WillReturnRows(sqlmock.NewRows([]string{"productcode"}))
+	mock.ExpectRollback()
+	mock.ExpectExec("DELETE FROM product WHERE tenant_id=\\? AND id=\\?").WithArgs("globex", "5").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.productService.prepareSqlStmt(lockversionstmt, lockdeletedstmt, purgestmt)
+
+	ctx := catalog.WithTenant(context.Background(), "globex")
+	p := &catalog.Product{ID: "5", ProductCode: "tee", Version: 1}
+	if err := client.productService.UpdateProduct(ctx, p); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the update, got %v", err)
+	}
+	if err := client.productService.DeleteProduct(ctx, "5", 0); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the delete, got %v", err)
+	}
+	if _, err := client.productService.RestoreProduct(ctx, "5"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the restore, got %v", err)
+	}
+	if err := client.productService.PurgeProduct(ctx, "5"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the purge, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,264 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure VariantService implements catalog.VariantService
+var _ catalog.VariantService = &VariantService{}
+
+// VariantService represents a service for managing product variants.
+type VariantService struct {
+	client         *Client
+	get            *sql.Stmt
+	list           *sql.Stmt
+	insert         *sql.Stmt
+	update         *sql.Stmt
+	delete         *sql.Stmt
+	variantOptions *sql.Stmt
+	productOptions *sql.Stmt
+	insertOption   *sql.Stmt
+	deleteOptions  *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetVariantStatement     SqlStatement
+	ListVariantsStatement   SqlStatement
+	InsertVariantStatement  SqlStatement
+	UpdateVariantStatement  SqlStatement
+	DeleteVariantStatement  SqlStatement
+	VariantOptionsStatement SqlStatement
+	ProductOptionsStatement SqlStatement
+	InsertOptionStatement   SqlStatement
+	DeleteOptionsStatement  SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *VariantService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getvariantstmt, listvariantsstmt, insertvariantstmt, updatevariantstmt,
+		deletevariantstmt, variantoptionsstmt, productoptionsstmt, insertoptionstmt, deleteoptionsstmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *VariantService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetVariantStatement:
+			err = prepare(&s.get, "get variant", string(stmt))
+		case ListVariantsStatement:
+			err = prepare(&s.list, "list variants", string(stmt))
+		case InsertVariantStatement:
+			err = prepare(&s.insert, "insert variant", string(stmt))
+		case UpdateVariantStatement:
+			err = prepare(&s.update, "update variant", string(stmt))
+		case DeleteVariantStatement:
+			err = prepare(&s.delete, "delete variant", string(stmt))
+		case VariantOptionsStatement:
+			err = prepare(&s.variantOptions, "variant options", string(stmt))
+		case ProductOptionsStatement:
+			err = prepare(&s.productOptions, "product options", string(stmt))
+		case InsertOptionStatement:
+			err = prepare(&s.insertOption, "insert option", string(stmt))
+		case DeleteOptionsStatement:
+			err = prepare(&s.deleteOptions, "delete options", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanVariant scans a row of id, product_id, sku, gtin.
+func scanVariant(row interface{ Scan(...interface{}) error }) (*catalog.Variant, error) {
+	var v catalog.Variant
+	var gtin sql.NullString
+	if err := row.Scan(&v.ID, &v.ProductID, &v.SKU, &gtin); err != nil {
+		return nil, err
+	}
+	v.GTIN = gtin.String
+	v.Options = map[string]string{}
+	return &v, nil
+}
+
+var getvariantstmt GetVariantStatement = "SELECT id, product_id, sku, gtin FROM variant WHERE id = ?"
+
+var variantoptionsstmt VariantOptionsStatement = "SELECT variant_id, axis, value FROM variant_option WHERE variant_id = ?"
+
+// Variant returns a Variant, with its options, by ID.
+func (s *VariantService) Variant(ctx context.Context, id string) (*catalog.Variant, error) {
+	v, err := scanVariant(s.get.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "variant %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving variant: %v, %v", id, err)
+		return nil, err
+	}
+	if err := s.readOptions(ctx, s.variantOptions, id, map[string]*catalog.Variant{v.ID: v}); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+var listvariantsstmt ListVariantsStatement = "SELECT id, product_id, sku, gtin FROM variant WHERE product_id = ? ORDER BY id"
+
+var productoptionsstmt ProductOptionsStatement = "SELECT o.variant_id, o.axis, o.value FROM variant_option o " +
+	"JOIN variant v ON v.id = o.variant_id WHERE v.product_id = ?"
+
+// Variants returns the variants of a product, with their options.
+func (s *VariantService) Variants(ctx context.Context, productID string) ([]*catalog.Variant, error) {
+	rows, err := s.list.QueryContext(ctx, productID)
+	if err != nil {
+		log.Errorf("Error retrieving variants: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	variants := []*catalog.Variant{}
+	byID := map[string]*catalog.Variant{}
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		variants = append(variants, v)
+		byID[v.ID] = v
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return variants, nil
+	}
+	if err := s.readOptions(ctx, s.productOptions, productID, byID); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// readOptions runs an options statement and adds the options to the variants they belong to.
+func (s *VariantService) readOptions(ctx context.Context, stmt *sql.Stmt, arg string, byID map[string]*catalog.Variant) error {
+	rows, err := stmt.QueryContext(ctx, arg)
+	if err != nil {
+		log.Errorf("Error retrieving variant options: %v", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var variantID, axis, value string
+		if err := rows.Scan(&variantID, &axis, &value); err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return err
+		}
+		if v, ok := byID[variantID]; ok {
+			v.Options[axis] = value
+		}
+	}
+	return rows.Err()
+}
+
+var insertvariantstmt InsertVariantStatement = "INSERT variant SET product_id=?, tenant_id=?, sku=?, gtin=?"
+
+var insertoptionstmt InsertOptionStatement = "INSERT variant_option SET variant_id=?, axis=?, value=?"
+
+// CreateVariant stores a new variant and its options. The variant belongs
+// to the tenant of the context, whose SKUs and GTINs must be unique.
+func (s *VariantService) CreateVariant(ctx context.Context, v *catalog.Variant) error {
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.StmtContext(ctx, s.insert).ExecContext(ctx, v.ProductID, catalog.TenantFromContext(ctx), v.SKU, nullString(v.GTIN))
+		if err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		v.ID = strconv.FormatInt(id, 10)
+		return s.writeOptions(ctx, tx, v)
+	})
+}
+
+// writeOptions inserts the options of a variant.
+func (s *VariantService) writeOptions(ctx context.Context, tx *sql.Tx, v *catalog.Variant) error {
+	stmt := tx.StmtContext(ctx, s.insertOption)
+	for axis, value := range v.Options {
+		if _, err := stmt.ExecContext(ctx, v.ID, axis, value); err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+var updatevariantstmt UpdateVariantStatement = "UPDATE variant SET sku=?, gtin=? WHERE id=? AND product_id=?"
+
+var deleteoptionsstmt DeleteOptionsStatement = "DELETE FROM variant_option WHERE variant_id=?"
+
+// UpdateVariant replaces a variant and its options.
+func (s *VariantService) UpdateVariant(ctx context.Context, v *catalog.Variant) error {
+	if len(v.ID) == 0 {
+		return catalog.Errorf(catalog.ErrInvalid, "variant with unassigned ID passed in to UpdateVariant")
+	}
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	return s.client.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.StmtContext(ctx, s.update).ExecContext(ctx, v.SKU, nullString(v.GTIN), v.ID, v.ProductID)
+		if err != nil {
+			log.Error(err)
+			return translateError(err)
+		}
+		affect, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affect == 0 {
+			return catalog.Errorf(catalog.ErrNotFound, "variant %v not found", v.ID)
+		}
+		if _, err := tx.StmtContext(ctx, s.deleteOptions).ExecContext(ctx, v.ID); err != nil {
+			log.Error(err)
+			return err
+		}
+		return s.writeOptions(ctx, tx, v)
+	})
+}
+
+var deletevariantstmt DeleteVariantStatement = "DELETE FROM variant WHERE id=?"
+
+// DeleteVariant deletes a variant. Its options and prices are removed with it.
+func (s *VariantService) DeleteVariant(ctx context.Context, id string) error {
+	res, err := s.delete.ExecContext(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "variant %v not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestVariantService_Variants(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("SELECT id, product_id, sku, gtin FROM variant WHERE product_id = \\?")
+	mock.ExpectPrepare("SELECT o.variant_id, o.axis, o.value FROM variant_option o")
+	mock.ExpectQuery("SELECT id, product_id, sku, gtin FROM variant WHERE product_id = \\?").
+		WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "gtin"}).
+			AddRow("1", "5", "TEE-M-RED", "4006381333931").
+			AddRow("2", "5", "TEE-L-RED", nil))
+	mock.ExpectQuery("SELECT o.variant_id, o.axis, o.value FROM variant_option o").
+		WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"variant_id", "axis", "value"}).
+			AddRow("1", "size", "M").
+			AddRow("1", "color", "red").
+			AddRow("2", "size", "L"))
+
+	client := NewClient()
+	client.db = db
+	client.variantService.prepareSqlStmt(listvariantsstmt, productoptionsstmt)
+	variants, err := client.variantService.Variants(context.Background(), "5")
+	if err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if len(variants) != 2 || variants[0].Options["color"] != "red" || variants[1].Options["size"] != "L" || variants[1].GTIN != "" {
+		t.Fatalf("unexpected variants: %+v %+v", variants[0], variants[1])
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVariantService_CreateVariant(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT variant SET product_id=\\?, tenant_id=\\?, sku=\\?, gtin=\\?")
+	mock.ExpectPrepare("INSERT variant_option SET variant_id=\\?, axis=\\?, value=\\?")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT variant SET").WithArgs("5", "acme", "TEE-M", nil).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT variant_option SET").WithArgs("3", "size", "M").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.variantService.prepareSqlStmt(insertvariantstmt, insertoptionstmt)
+	v := &catalog.Variant{ProductID: "5", SKU: "TEE-M", Options: map[string]string{"size": "M"}}
+	if err := client.variantService.CreateVariant(catalog.WithTenant(context.Background(), "acme"), v); err != nil {
+		t.Fatalf("expected no error, but got %s instead", err)
+	}
+	if v.ID != "3" {
+		t.Errorf("expected variant id 3, got %v", v.ID)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVariantService_CreateVariantRollsBack(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT variant SET product_id=\\?, tenant_id=\\?, sku=\\?, gtin=\\?")
+	mock.ExpectPrepare("INSERT variant_option SET variant_id=\\?, axis=\\?, value=\\?")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT variant SET").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT variant_option SET").WillReturnError(errors.New("lost connection"))
+	mock.ExpectRollback()
+
+	client := NewClient()
+	client.db = db
+	client.variantService.prepareSqlStmt(insertvariantstmt, insertoptionstmt)
+	v := &catalog.Variant{ProductID: "5", SKU: "TEE-M", Options: map[string]string{"size": "M"}}
+	if err := client.variantService.CreateVariant(context.Background(), v); err == nil {
+		t.Fatal("expected error, but got none")
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVariantService_CreateVariantInvalidGTIN(t *testing.T) {
+	client := NewClient()
+	v := &catalog.Variant{ProductID: "5", SKU: "TEE-M", GTIN: "4006381333932"}
+	if err := client.variantService.CreateVariant(context.Background(), v); !errors.Is(err, catalog.ErrInvalid) {
+		t.Errorf("expected ErrInvalid but got: %v", err)
+	}
+}
@@ -0,0 +1,417 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure WebhookService implements catalog.WebhookService
+var _ catalog.WebhookService = &WebhookService{}
+
+// WebhookService represents a service for managing webhooks and their
+// deliveries.
+type WebhookService struct {
+	client        *Client
+	get           *sql.Stmt
+	list          *sql.Stmt
+	insert        *sql.Stmt
+	delete        *sql.Stmt
+	enqueue       *sql.Stmt
+	claim         *sql.Stmt
+	lease         *sql.Stmt
+	recordAttempt *sql.Stmt
+	deliveries    *sql.Stmt
+	delivery      *sql.Stmt
+	replay        *sql.Stmt
+}
+
+// Define custom types for statements to help with sqlmock tests
+type (
+	GetWebhookStatement      SqlStatement
+	ListWebhooksStatement    SqlStatement
+	InsertWebhookStatement   SqlStatement
+	DeleteWebhookStatement   SqlStatement
+	EnqueueDeliveryStatement SqlStatement
+	ClaimDeliveriesStatement SqlStatement
+	LeaseDeliveryStatement   SqlStatement
+	RecordAttemptStatement   SqlStatement
+	DeliveriesStatement      SqlStatement
+	DeliveryStatement        SqlStatement
+	ReplayDeliveryStatement  SqlStatement
+)
+
+// prepareSqlStmts prepares the SQL statements ahead of time resulting in faster performance.
+func (s *WebhookService) prepareSqlStmts() error {
+	return s.prepareSqlStmt(getwebhookstmt, listwebhooksstmt, insertwebhookstmt, deletewebhookstmt,
+		enqueuedeliverystmt, claimdeliveriesstmt, leasedeliverystmt, recordattemptstmt,
+		deliveriesstmt, deliverystmt, replaydeliverystmt)
+}
+
+// prepareSqlStmt prepares the given statements. See ProductService.prepareSqlStmt.
+func (s *WebhookService) prepareSqlStmt(stmts ...interface{}) error {
+	var err error
+	prepare := func(dst **sql.Stmt, name string, query string) error {
+		if *dst, err = s.client.db.Prepare(query); err != nil {
+			return fmt.Errorf("mysql: prepare %s: %v", name, err)
+		}
+		return nil
+	}
+	for _, v := range stmts {
+		switch stmt := v.(type) {
+		case GetWebhookStatement:
+			err = prepare(&s.get, "get webhook", string(stmt))
+		case ListWebhooksStatement:
+			err = prepare(&s.list, "list webhooks", string(stmt))
+		case InsertWebhookStatement:
+			err = prepare(&s.insert, "insert webhook", string(stmt))
+		case DeleteWebhookStatement:
+			err = prepare(&s.delete, "delete webhook", string(stmt))
+		case EnqueueDeliveryStatement:
+			err = prepare(&s.enqueue, "enqueue delivery", string(stmt))
+		case ClaimDeliveriesStatement:
+			err = prepare(&s.claim, "claim deliveries", string(stmt))
+		case LeaseDeliveryStatement:
+			err = prepare(&s.lease, "lease delivery", string(stmt))
+		case RecordAttemptStatement:
+			err = prepare(&s.recordAttempt, "record attempt", string(stmt))
+		case DeliveriesStatement:
+			err = prepare(&s.deliveries, "deliveries", string(stmt))
+		case DeliveryStatement:
+			err = prepare(&s.delivery, "delivery", string(stmt))
+		case ReplayDeliveryStatement:
+			err = prepare(&s.replay, "replay delivery", string(stmt))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookColumns is the column list selected for every webhook read. The
+// secret is only read to sign deliveries.
+const webhookColumns = "id, tenant_id, url, event_types, created_at"
+
+// scanWebhook scans a row selected with webhookColumns.
+func scanWebhook(row rowScanner) (*catalog.Webhook, error) {
+	var w catalog.Webhook
+	var types string
+	if err := row.Scan(&w.ID, &w.TenantID, &w.URL, &types, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	if types != "" {
+		w.EventTypes = strings.Split(types, ",")
+	}
+	return &w, nil
+}
+
+var getwebhookstmt GetWebhookStatement = "SELECT " + webhookColumns + " FROM webhook WHERE tenant_id = ? AND id = ?"
+
+// Webhook returns a webhook of the context's tenant by ID.
+func (s *WebhookService) Webhook(ctx context.Context, id string) (*catalog.Webhook, error) {
+	w, err := scanWebhook(s.get.QueryRowContext(ctx, catalog.TenantFromContext(ctx), id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "webhook %v not found", id)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving webhook: %v, %v", id, err)
+		return nil, err
+	}
+	return w, nil
+}
+
+var listwebhooksstmt ListWebhooksStatement = "SELECT " + webhookColumns + " FROM webhook WHERE tenant_id = ? ORDER BY id"
+
+// Webhooks returns the webhooks of the context's tenant ordered by ID.
+func (s *WebhookService) Webhooks(ctx context.Context) ([]*catalog.Webhook, error) {
+	return s.webhooks(ctx, catalog.TenantFromContext(ctx))
+}
+
+// webhooks returns the webhooks of the tenant ordered by ID.
+func (s *WebhookService) webhooks(ctx context.Context, tenant string) ([]*catalog.Webhook, error) {
+	rows, err := s.list.QueryContext(ctx, tenant)
+	if err != nil {
+		log.Errorf("Error retrieving webhooks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []*catalog.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+var insertwebhookstmt InsertWebhookStatement = "INSERT webhook SET tenant_id=?, url=?, event_types=?, secret=?, created_at=?"
+
+// CreateWebhook stores a new webhook of the context's tenant and assigns
+// its ID. The secret is cleared once stored.
+func (s *WebhookService) CreateWebhook(ctx context.Context, w *catalog.Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	w.TenantID = catalog.TenantFromContext(ctx)
+	createdAt := time.Now().UTC()
+	res, err := s.insert.ExecContext(ctx, w.TenantID, w.URL, strings.Join(w.EventTypes, ","), w.Secret, createdAt)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	w.ID = strconv.FormatInt(id, 10)
+	w.Secret = ""
+	w.CreatedAt = createdAt
+	return nil
+}
+
+var deletewebhookstmt DeleteWebhookStatement = "DELETE FROM webhook WHERE tenant_id=? AND id=?"
+
+// DeleteWebhook deletes a webhook of the context's tenant; its deliveries
+// are deleted with it.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := s.delete.ExecContext(ctx, catalog.TenantFromContext(ctx), id)
+	if err != nil {
+		log.Error(err)
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if affect == 0 {
+		return catalog.Errorf(catalog.ErrNotFound, "webhook %v not found", id)
+	}
+	return nil
+}
+
+// enqueuedeliverystmt ignores the deliveries already queued for the event,
+// so that an event published again is not delivered twice.
+var enqueuedeliverystmt EnqueueDeliveryStatement = "INSERT IGNORE webhook_delivery SET webhook_id=?, tenant_id=?, event_id=?, event_type=?, " +
+	"payload=?, status='pending', next_attempt_at=UTC_TIMESTAMP(6), created_at=UTC_TIMESTAMP(6)"
+
+// EnqueueDeliveries queues a delivery of the event to every subscribed
+// webhook of the tenant of the event.
+func (s *WebhookService) EnqueueDeliveries(ctx context.Context, e *catalog.ProductEvent) (int, error) {
+	webhooks, err := s.webhooks(ctx, e.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, w := range webhooks {
+		if !w.Subscribes(e.Type) {
+			continue
+		}
+		res, err := s.enqueue.ExecContext(ctx, w.ID, e.TenantID, e.ID, e.Type, string(payload))
+		if err != nil {
+			log.Errorf("Error queueing the delivery of event %d to webhook %v: %v", e.ID, w.ID, err)
+			return queued, err
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// deliveryColumns is the column list selected for every delivery read.
+const deliveryColumns = "d.id, d.webhook_id, d.event_id, d.event_type, d.status, d.attempts, d.next_attempt_at, " +
+	"d.last_attempt_at, d.last_status_code, d.last_error, d.created_at, d.payload"
+
+// scanDelivery scans a row selected with deliveryColumns followed by dest.
+func scanDelivery(row rowScanner, dest ...interface{}) (*catalog.WebhookDelivery, error) {
+	var d catalog.WebhookDelivery
+	var nextAttemptAt, lastAttemptAt sql.NullTime
+	var payload []byte
+	if err := row.Scan(append([]interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+		&nextAttemptAt, &lastAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &payload}, dest...)...); err != nil {
+		return nil, err
+	}
+	d.NextAttemptAt = nullTime(nextAttemptAt)
+	d.LastAttemptAt = nullTime(lastAttemptAt)
+	d.Payload = json.RawMessage(payload)
+	return &d, nil
+}
+
+var claimdeliveriesstmt ClaimDeliveriesStatement = "SELECT " + deliveryColumns + ", w.url, w.secret " +
+	"FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id " +
+	"WHERE d.next_attempt_at <= UTC_TIMESTAMP(6) ORDER BY d.next_attempt_at, d.id LIMIT ? FOR UPDATE"
+
+var leasedeliverystmt LeaseDeliveryStatement = "UPDATE webhook_delivery SET next_attempt_at=? WHERE id=?"
+
+// ClaimDeliveries locks the due deliveries and moves their next attempt
+// past the lease, so that other dispatchers skip them while they are sent.
+// A dispatcher that dies mid-delivery leaves them to be retried after the
+// lease.
+func (s *WebhookService) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error) {
+	deliveries := []*catalog.WebhookDelivery{}
+	err := s.client.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.StmtContext(ctx, s.claim).QueryContext(ctx, limit)
+		if err != nil {
+			log.Errorf("Error retrieving due deliveries: %v", err)
+			return err
+		}
+		for rows.Next() {
+			w := &catalog.Webhook{}
+			d, err := scanDelivery(rows, &w.URL, &w.Secret)
+			if err != nil {
+				rows.Close()
+				log.Errorf("Error scanning over rows: %v", err)
+				return err
+			}
+			w.ID = d.WebhookID
+			d.Webhook = w
+			deliveries = append(deliveries, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			log.Errorf("Error iterating over rows: %v", err)
+			return err
+		}
+		leasedUntil := time.Now().UTC().Add(lease)
+		for _, d := range deliveries {
+			if _, err := tx.StmtContext(ctx, s.lease).ExecContext(ctx, leasedUntil, d.ID); err != nil {
+				log.Error(err)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+var recordattemptstmt RecordAttemptStatement = "UPDATE webhook_delivery SET status=?, attempts=?, next_attempt_at=?, " +
+	"last_attempt_at=?, last_status_code=?, last_error=? WHERE id=?"
+
+// maxErrorLength is the length of webhook_delivery.last_error.
+const maxErrorLength = 1024
+
+// RecordAttempt stores the outcome of a delivery attempt.
+func (s *WebhookService) RecordAttempt(ctx context.Context, d *catalog.WebhookDelivery) error {
+	lastError := d.LastError
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+	}
+	if _, err := s.recordAttempt.ExecContext(ctx, string(d.Status), d.Attempts, timeArg(d.NextAttemptAt),
+		timeArg(d.LastAttemptAt), d.LastStatusCode, lastError, d.ID); err != nil {
+		log.Errorf("Error recording the attempt of delivery %v: %v", d.ID, err)
+		return err
+	}
+	return nil
+}
+
+var deliveriesstmt DeliveriesStatement = "SELECT " + deliveryColumns + " FROM webhook_delivery d " +
+	"WHERE d.tenant_id = ? AND d.webhook_id = ? AND (? = '' OR d.status = ?) AND d.id < ? ORDER BY d.id DESC LIMIT ?"
+
+// Deliveries returns a page of the deliveries of a webhook of the
+// context's tenant, newest first.
+// A webhook without deliveries yields an empty page whether it exists or not.
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, status catalog.DeliveryStatus, page catalog.Page) (*catalog.DeliveryPage, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	// The cursor holds the last delivery returned.
+	before := int64(math.MaxInt64)
+	if page.Cursor != "" {
+		if c.Sort != "deliveries" || c.ID < 1 {
+			return nil, catalog.ErrInvalidCursor
+		}
+		before = c.ID
+	}
+	limit := page.PageSize()
+	// Fetch one extra row to find out whether there is another page
+	rows, err := s.deliveries.QueryContext(ctx, catalog.TenantFromContext(ctx), webhookID, string(status), string(status), before, limit+1)
+	if err != nil {
+		log.Errorf("Error retrieving deliveries: %v, %v", webhookID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []*catalog.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			log.Errorf("Error scanning over rows: %v", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error iterating over rows: %v", err)
+		return nil, err
+	}
+	result := &catalog.DeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > limit {
+		result.Deliveries = deliveries[:limit]
+		last, _ := strconv.ParseInt(deliveries[limit-1].ID, 10, 64)
+		result.Next = encodeCursor(cursor{Sort: "deliveries", ID: last})
+	}
+	return result, nil
+}
+
+var deliverystmt DeliveryStatement = "SELECT " + deliveryColumns + " FROM webhook_delivery d " +
+	"WHERE d.tenant_id = ? AND d.webhook_id = ? AND d.id = ?"
+
+// Delivery returns a delivery of a webhook of the context's tenant by ID.
+func (s *WebhookService) Delivery(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error) {
+	d, err := scanDelivery(s.delivery.QueryRowContext(ctx, catalog.TenantFromContext(ctx), webhookID, id))
+	if err == sql.ErrNoRows {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "delivery %v of webhook %v not found", id, webhookID)
+	}
+	if err != nil {
+		log.Errorf("Error retrieving delivery: %v, %v", id, err)
+		return nil, err
+	}
+	return d, nil
+}
+
+var replaydeliverystmt ReplayDeliveryStatement = "UPDATE webhook_delivery SET status='pending', attempts=0, " +
+	"next_attempt_at=UTC_TIMESTAMP(6) WHERE tenant_id=? AND webhook_id=? AND id=?"
+
+// ReplayDelivery queues a delivery of the context's tenant to be sent
+// again. The outcome of its last attempt is kept until the next attempt.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, webhookID, id string) (*catalog.WebhookDelivery, error) {
+	res, err := s.replay.ExecContext(ctx, catalog.TenantFromContext(ctx), webhookID, id)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if affect == 0 {
+		return nil, catalog.Errorf(catalog.ErrNotFound, "delivery %v of webhook %v not found", id, webhookID)
+	}
+	return s.Delivery(ctx, webhookID, id)
+}
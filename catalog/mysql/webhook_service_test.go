@@ -0,0 +1,271 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var webhookColumnNames = []string{"id", "tenant_id", "url", "event_types", "created_at"}
+
+var deliveryColumnNames = []string{"id", "webhook_id", "event_id", "event_type", "status", "attempts", "next_attempt_at",
+	"last_attempt_at", "last_status_code", "last_error", "created_at", "payload"}
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("INSERT webhook SET tenant_id=\\?, url=\\?, event_types=\\?, secret=\\?, created_at=\\?")
+	mock.ExpectExec("INSERT webhook SET").
+		WithArgs("acme", "https://partner.example.com/hooks", "product.created,product.deleted", "0123456789abcdef", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(insertwebhookstmt)
+
+	w := &catalog.Webhook{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{catalog.EventProductCreated, catalog.EventProductDeleted},
+		Secret:     "0123456789abcdef",
+	}
+	// The webhook is created for the tenant of the request.
+	if err := client.webhookService.CreateWebhook(catalog.WithTenant(context.Background(), "acme"), w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.ID != "3" || w.TenantID != "acme" || w.Secret != "" || w.CreatedAt.IsZero() {
+		t.Errorf("unexpected webhook: %+v", w)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_CreateWebhookInvalid(t *testing.T) {
+	client := NewClient()
+	w := &catalog.Webhook{URL: "ftp://partner.example.com", EventTypes: []string{"product.renamed"}, Secret: "short"}
+	err := client.webhookService.CreateWebhook(context.Background(), w)
+	e, ok := err.(*catalog.Error)
+	if !ok || e.Kind != catalog.ErrInvalid || len(e.Fields) != 3 {
+		t.Errorf("expected 3 invalid fields, got %v", err)
+	}
+}
+
+func TestWebhookService_DeleteWebhookNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("DELETE FROM webhook WHERE tenant_id=\\? AND id=\\?")
+	mock.ExpectExec("DELETE FROM webhook").WithArgs("default", "9").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(deletewebhookstmt)
+
+	err = client.webhookService.DeleteWebhook(context.Background(), "9")
+	if e, ok := err.(*catalog.Error); !ok || e.Kind != catalog.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_EnqueueDeliveries(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT id, tenant_id, url, event_types, created_at FROM webhook WHERE tenant_id = \\? ORDER BY id")
+	mock.ExpectPrepare("INSERT IGNORE webhook_delivery SET webhook_id=\\?, tenant_id=\\?, event_id=\\?, event_type=\\?, payload=\\?")
+	// Only the webhooks of the tenant of the event receive it.
+	mock.ExpectQuery("SELECT id, tenant_id, url, event_types, created_at FROM webhook").WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows(webhookColumnNames).
+			AddRow("1", "acme", "https://a.example.com", "", createdAt).
+			AddRow("2", "acme", "https://b.example.com", "product.deleted", createdAt).
+			AddRow("3", "acme", "https://c.example.com", "product.created,product.updated", createdAt))
+	mock.ExpectExec("INSERT IGNORE webhook_delivery").WithArgs("1", "acme", 11, "product.updated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Already queued
+	mock.ExpectExec("INSERT IGNORE webhook_delivery").WithArgs("3", "acme", 11, "product.updated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(listwebhooksstmt, enqueuedeliverystmt)
+
+	e := &catalog.ProductEvent{ID: 11, Type: catalog.EventProductUpdated, TenantID: "acme", ProductID: "5", Version: 2}
+	n, err := client.webhookService.EnqueueDeliveries(context.Background(), e)
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 delivery queued, got %d, %v", n, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_ClaimDeliveries(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT d.id, d.webhook_id, .* FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id " +
+		"WHERE d.next_attempt_at <= UTC_TIMESTAMP\\(6\\) ORDER BY d.next_attempt_at, d.id LIMIT \\? FOR UPDATE")
+	mock.ExpectPrepare("UPDATE webhook_delivery SET next_attempt_at=\\? WHERE id=\\?")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT d.id, d.webhook_id, .* FROM webhook_delivery d JOIN webhook w").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(append(deliveryColumnNames, "url", "secret")).
+			AddRow("7", "1", 11, "product.updated", "failed", 2, createdAt, createdAt, 500, "500 Internal Server Error",
+				createdAt, `{"id":11}`, "https://a.example.com", "0123456789abcdef"))
+	mock.ExpectExec("UPDATE webhook_delivery SET next_attempt_at").WithArgs(sqlmock.AnyArg(), "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(claimdeliveriesstmt, leasedeliverystmt)
+
+	deliveries, err := client.webhookService.ClaimDeliveries(context.Background(), 10, time.Minute)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("expected 1 delivery claimed, got %v, %v", deliveries, err)
+	}
+	d := deliveries[0]
+	if d.Status != catalog.DeliveryFailed || d.Attempts != 2 || string(d.Payload) != `{"id":11}` {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+	if d.Webhook == nil || d.Webhook.ID != "1" || d.Webhook.URL != "https://a.example.com" || d.Webhook.Secret != "0123456789abcdef" {
+		t.Errorf("unexpected webhook: %+v", d.Webhook)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_Deliveries(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT d.id, .* FROM webhook_delivery d " +
+		"WHERE d.tenant_id = \\? AND d.webhook_id = \\? AND \\(\\? = '' OR d.status = \\?\\) AND d.id < \\? ORDER BY d.id DESC LIMIT \\?")
+	mock.ExpectQuery("SELECT d.id, .* FROM webhook_delivery d").
+		WithArgs("default", "1", "dead", "dead", 9, 3).
+		WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+			AddRow("8", "1", 12, "product.updated", "dead", 8, nil, createdAt, 0, "connection refused", createdAt, `{}`).
+			AddRow("6", "1", 10, "product.created", "dead", 8, nil, createdAt, 410, "410 Gone", createdAt, `{}`).
+			AddRow("5", "1", 9, "product.created", "dead", 8, nil, createdAt, 410, "410 Gone", createdAt, `{}`))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(deliveriesstmt)
+
+	page := catalog.Page{Cursor: encodeCursor(cursor{Sort: "deliveries", ID: 9}), Limit: 2}
+	p, err := client.webhookService.Deliveries(context.Background(), "1", catalog.DeliveryDead, page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Deliveries) != 2 || p.Deliveries[0].NextAttemptAt != nil || p.Deliveries[1].LastStatusCode != 410 {
+		t.Errorf("unexpected deliveries: %+v", p.Deliveries)
+	}
+	if c, err := decodeCursor(p.Next); err != nil || c.ID != 6 {
+		t.Errorf("expected the next page after delivery 6, got %v, %v", p.Next, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_DeliveriesInvalidCursor(t *testing.T) {
+	client := NewClient()
+	page := catalog.Page{Cursor: encodeCursor(cursor{Sort: "history", ID: 9})}
+	if _, err := client.webhookService.Deliveries(context.Background(), "1", "", page); err != catalog.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestWebhookService_ReplayDelivery(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare("SELECT d.id, .* FROM webhook_delivery d WHERE d.tenant_id = \\? AND d.webhook_id = \\? AND d.id = \\?")
+	mock.ExpectPrepare("UPDATE webhook_delivery SET status='pending', attempts=0, next_attempt_at=UTC_TIMESTAMP\\(6\\) " +
+		"WHERE tenant_id=\\? AND webhook_id=\\? AND id=\\?")
+	mock.ExpectExec("UPDATE webhook_delivery SET status='pending'").WithArgs("default", "1", "8").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT d.id, .* FROM webhook_delivery d").WithArgs("default", "1", "8").
+		WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+			AddRow("8", "1", 12, "product.updated", "pending", 0, createdAt, createdAt, 0, "connection refused", createdAt, `{}`))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(deliverystmt, replaydeliverystmt)
+
+	d, err := client.webhookService.ReplayDelivery(context.Background(), "1", "8")
+	if err != nil || d.Status != catalog.DeliveryPending || d.Attempts != 0 {
+		t.Errorf("unexpected delivery: %+v, %v", d, err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookService_ReplayDeliveryNotFound(t *testing.T) {
+	// Create DB Mock
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare("UPDATE webhook_delivery SET status='pending'")
+	mock.ExpectExec("UPDATE webhook_delivery SET status='pending'").WithArgs("default", "1", "99").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	client := NewClient()
+	client.db = db
+	client.webhookService.prepareSqlStmt(replaydeliverystmt)
+
+	_, err = client.webhookService.ReplayDelivery(context.Background(), "1", "99")
+	if e, ok := err.(*catalog.Error); !ok || e.Kind != catalog.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// make sure expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,60 @@
+package catalog
+
+import "time"
+
+// ProductPatch is a partial update of a product. Nil fields are left
+// unchanged.
+type ProductPatch struct {
+	ProductCode *string
+	ShortDesc   *string
+	LongDesc    *string
+	// Status moves the product to another lifecycle stage.
+	Status *ProductStatus
+	// PublishAt and UnpublishAt replace the publishing schedule; pointing
+	// to a nil time clears it.
+	PublishAt   **time.Time
+	UnpublishAt **time.Time
+	// Attributes sets the named attribute values. A nil value removes the
+	// attribute from the product.
+	Attributes map[string]interface{}
+	// Version, when non-zero, must match the current version of the product.
+	Version int64
+}
+
+// Empty reports whether the patch changes nothing.
+func (p *ProductPatch) Empty() bool {
+	return p.ProductCode == nil && p.ShortDesc == nil && p.LongDesc == nil && p.Status == nil &&
+		p.PublishAt == nil && p.UnpublishAt == nil && len(p.Attributes) == 0
+}
+
+// Apply applies the patch to a product.
+func (p *ProductPatch) Apply(product *Product) {
+	if p.ProductCode != nil {
+		product.ProductCode = *p.ProductCode
+	}
+	if p.ShortDesc != nil {
+		product.ShortDesc = *p.ShortDesc
+	}
+	if p.LongDesc != nil {
+		product.LongDesc = *p.LongDesc
+	}
+	if p.Status != nil {
+		product.Status = *p.Status
+	}
+	if p.PublishAt != nil {
+		product.PublishAt = *p.PublishAt
+	}
+	if p.UnpublishAt != nil {
+		product.UnpublishAt = *p.UnpublishAt
+	}
+	for name, v := range p.Attributes {
+		if v == nil {
+			delete(product.Attributes, name)
+			continue
+		}
+		if product.Attributes == nil {
+			product.Attributes = map[string]interface{}{}
+		}
+		product.Attributes[name] = v
+	}
+}
@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// PriceList groups prices for a market or channel, e.g. "retail-us".
+type PriceList struct {
+	ID   string `json:"priceListId"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Price is the price of a product, or of one of its variants when
+// VariantID is set, on a price list in one currency.
+// Amounts are integer minor units of the currency, e.g. cents for USD.
+// A nil SaleAmount means the product is not on sale. A nil ValidFrom or
+// ValidTo leaves that end of the validity window open; ValidTo is exclusive.
+type Price struct {
+	ID          string     `json:"priceId"`
+	ProductID   string     `json:"productId"`
+	VariantID   string     `json:"variantId,omitempty"`
+	PriceListID string     `json:"priceListId"`
+	Currency    string     `json:"currency"`
+	ListAmount  int64      `json:"listAmount"`
+	SaleAmount  *int64     `json:"saleAmount,omitempty"`
+	ValidFrom   *time.Time `json:"validFrom,omitempty"`
+	ValidTo     *time.Time `json:"validTo,omitempty"`
+}
+
+// ValidAt reports whether the price is in effect at t.
+func (p *Price) ValidAt(t time.Time) bool {
+	if p.ValidFrom != nil && t.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidTo != nil && !t.Before(*p.ValidTo) {
+		return false
+	}
+	return true
+}
+
+// Amount returns the amount to charge: the sale amount if set, otherwise the list amount.
+func (p *Price) Amount() int64 {
+	if p.SaleAmount != nil {
+		return *p.SaleAmount
+	}
+	return p.ListAmount
+}
+
+// Validate checks the price fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (p *Price) Validate() error {
+	var fields []FieldError
+	if p.PriceListID == "" {
+		fields = append(fields, FieldError{Field: "priceListId", Message: "is required"})
+	}
+	if !isCurrencyCode(p.Currency) {
+		fields = append(fields, FieldError{Field: "currency", Message: "must be a three letter ISO 4217 code"})
+	}
+	if p.ListAmount < 0 {
+		fields = append(fields, FieldError{Field: "listAmount", Message: "must not be negative"})
+	}
+	if p.SaleAmount != nil && (*p.SaleAmount < 0 || *p.SaleAmount > p.ListAmount) {
+		fields = append(fields, FieldError{Field: "saleAmount", Message: "must be between 0 and listAmount"})
+	}
+	if p.ValidFrom != nil && p.ValidTo != nil && !p.ValidFrom.Before(*p.ValidTo) {
+		fields = append(fields, FieldError{Field: "validTo", Message: "must be after validFrom"})
+	}
+	return ValidationError("price is invalid", fields)
+}
+
+// Validate checks the price list fields.
+func (pl *PriceList) Validate() error {
+	var fields []FieldError
+	if pl.Code == "" {
+		fields = append(fields, FieldError{Field: "code", Message: "is required"})
+	}
+	if pl.Name == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	return ValidationError("price list is invalid", fields)
+}
+
+// isCurrencyCode reports whether s looks like an ISO 4217 alphabetic code.
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// PriceQuery filters the prices returned by PriceService.Prices.
+// Zero values leave a filter unset.
+type PriceQuery struct {
+	VariantID   string
+	PriceListID string
+	Currency    string
+	// At only returns prices valid at the given time.
+	At *time.Time
+}
+
+// PriceService represents a service for managing price lists and product prices.
+type PriceService interface {
+	PriceList(ctx context.Context, id string) (*PriceList, error)
+	PriceLists(ctx context.Context) ([]*PriceList, error)
+	CreatePriceList(ctx context.Context, pl *PriceList) error
+
+	Price(ctx context.Context, id string) (*Price, error)
+	Prices(ctx context.Context, productID string, q PriceQuery) ([]*Price, error)
+	CreatePrice(ctx context.Context, p *Price) error
+	UpdatePrice(ctx context.Context, p *Price) error
+	DeletePrice(ctx context.Context, id string) error
+}
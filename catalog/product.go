@@ -1,28 +1,154 @@
 package catalog
 
-import "golang.org/x/net/context"
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
 
 // ProductID represents a product identifier.
 // type ProductID string
 
+// Page size limits used when listing products.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ErrInvalidCursor is returned when a page cursor cannot be decoded.
+var ErrInvalidCursor error = &Error{Kind: ErrInvalid, Message: "invalid page cursor"}
+
 // Product represents a product for sale.
 type Product struct {
 	ID          string `json:"productId"`
 	ProductCode string `json:"productCode"`
 	ShortDesc   string `json:"shortDesc"`
 	LongDesc    string `json:"longDesc"`
+	// Version is incremented by every update. Updating or deleting with a
+	// non-zero Version fails with ErrVersionConflict unless it is current.
+	Version int64 `json:"version"`
+
+	// Attributes holds the values of custom attributes by name, typed
+	// according to their AttributeDefinition.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// Status is the lifecycle stage of the product. New products without
+	// a status are created as drafts.
+	Status ProductStatus `json:"status"`
+	// PublishAt and UnpublishAt bound when an active product is shown to
+	// the public. Either may be unset.
+	PublishAt   *time.Time `json:"publishAt,omitempty"`
+	UnpublishAt *time.Time `json:"unpublishAt,omitempty"`
+
+	// DeletedAt is set on products that were deleted and can still be
+	// restored until they are purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+
+	// Variants is only filled in when requested, e.g. GET /product/{id}?embed=variants.
+	Variants []*Variant `json:"variants,omitempty"`
+}
+
+// maxFieldLength is the longest value the short text fields may hold.
+const maxFieldLength = 255
+
+// Validate checks the product fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (p *Product) Validate() error {
+	var fields []FieldError
+	switch {
+	case p.ProductCode == "":
+		fields = append(fields, FieldError{Field: "productCode", Message: "is required"})
+	case utf8.RuneCountInString(p.ProductCode) > maxFieldLength:
+		fields = append(fields, FieldError{Field: "productCode", Message: "must be at most 255 characters"})
+	}
+	if utf8.RuneCountInString(p.ShortDesc) > maxFieldLength {
+		fields = append(fields, FieldError{Field: "shortDesc", Message: "must be at most 255 characters"})
+	}
+	if p.Status != "" && !p.Status.Valid() {
+		var names []string
+		for _, s := range statuses {
+			names = append(names, string(s))
+		}
+		fields = append(fields, FieldError{Field: "status", Message: "must be one of " + strings.Join(names, ", ")})
+	}
+	if p.PublishAt != nil && p.UnpublishAt != nil && !p.UnpublishAt.After(*p.PublishAt) {
+		fields = append(fields, FieldError{Field: "unpublishAt", Message: "must be after publishAt"})
+	}
+	return ValidationError("product is invalid", fields)
+}
+
+// Page requests a single page of a listing. Cursor is the opaque value
+// returned as Next by the previous page, or empty for the first page.
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+// PageSize returns the number of items to return, applying the defaults
+// and upper bound.
+func (p Page) PageSize() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageSize
+	case p.Limit > MaxPageSize:
+		return MaxPageSize
+	}
+	return p.Limit
+}
+
+// ProductPage is a page of products. Next is empty on the last page.
+type ProductPage struct {
+	Products []*Product `json:"products"`
+	Next     string     `json:"next,omitempty"`
 }
 
 // Client creates a connection to the service.
 type Client interface {
 	ProductService() ProductService
+	PriceService() PriceService
+	VariantService() VariantService
+	CategoryService() CategoryService
+	AttributeService() AttributeService
 }
 
 // ProductService represents a service for managing products.
 type ProductService interface {
 	Product(ctx context.Context, id string) (*Product, error)
-	Products(ctx context.Context) ([]*Product, error)
+	// ProductByCode returns the product with the unique product code.
+	ProductByCode(ctx context.Context, code string) (*Product, error)
+	Products(ctx context.Context, q ProductQuery) (*ProductPage, error)
 	CreateProduct(ctx context.Context, p *Product) error
 	UpdateProduct(ctx context.Context, p *Product) error
-	DeleteProduct(ctx context.Context, id string) error
+	// PatchProduct applies a partial update to a product, writing only the
+	// changed fields, and returns the updated product.
+	PatchProduct(ctx context.Context, id string, patch *ProductPatch) (*Product, error)
+	// DeleteProduct soft deletes a product, hiding it until it is restored
+	// or purged. A non-zero version must match the current version of the
+	// product.
+	DeleteProduct(ctx context.Context, id string, version int64) error
+	// RestoreProduct undoes the deletion of a product that was not purged yet.
+	RestoreProduct(ctx context.Context, id string) (*Product, error)
+	// PurgeProduct permanently removes a deleted product.
+	PurgeProduct(ctx context.Context, id string) error
+	// PurgeDeletedProducts permanently removes the products deleted before
+	// the given time and returns how many were removed.
+	PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error)
+	// ProductHistory returns a page of the recorded changes of a product,
+	// newest first.
+	ProductHistory(ctx context.Context, id string, page Page) (*RevisionPage, error)
+	// ProductRevision returns the recorded change that produced the
+	// product version.
+	ProductRevision(ctx context.Context, id string, version int64) (*ProductRevision, error)
+	// ProductAsOf reconstructs a product as it was at the given time from
+	// its history. It fails with ErrNotFound if the product did not exist
+	// or was deleted at that time.
+	ProductAsOf(ctx context.Context, id string, at time.Time) (*Product, error)
+	// BatchProducts applies a list of upserts and deletes and returns one
+	// result per operation. When atomic is set either every operation is
+	// applied or none is; otherwise each operation that fails is skipped
+	// and the others are applied. The error is only set when the batch
+	// could not be run at all.
+	BatchProducts(ctx context.Context, ops []*BatchOperation, atomic bool) ([]*BatchResult, error)
 }
@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"strings"
+	"time"
+)
+
+// Sortable product fields, named as they appear in the product JSON.
+const (
+	SortByID          = "productId"
+	SortByProductCode = "productCode"
+	SortByShortDesc   = "shortDesc"
+)
+
+// SortField orders a listing by a single field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ProductQuery selects, orders and pages the products returned by
+// ProductService.Products. Zero values leave a filter unset.
+type ProductQuery struct {
+	Page
+
+	// ProductCode matches the product code exactly.
+	ProductCode string
+	// ProductCodePrefix matches product codes starting with the value.
+	ProductCodePrefix string
+	// ShortDescContains matches short descriptions containing the value.
+	ShortDescContains string
+	// MinID and MaxID bound the product id, inclusive.
+	MinID int64
+	MaxID int64
+	// CategoryID matches products assigned to the category, and to any of
+	// its descendants when IncludeDescendants is set.
+	CategoryID         string
+	IncludeDescendants bool
+	// Attributes matches products having all of the attribute values.
+	Attributes []AttributeFilter
+	// Deleted selects whether deleted products are listed.
+	Deleted DeletedFilter
+	// Status matches products in the lifecycle stage.
+	Status ProductStatus
+	// PublishedAt, when set, only matches products shown to the public at
+	// that time, see Product.Published.
+	PublishedAt time.Time
+
+	// Sort orders the results. Products are always finally ordered by id.
+	Sort []SortField
+}
+
+// DeletedFilter selects whether ProductService.Products lists deleted products.
+type DeletedFilter int
+
+// Deleted product filters.
+const (
+	ExcludeDeleted DeletedFilter = iota
+	IncludeDeleted
+	OnlyDeleted
+)
+
+// ParseDeletedFilter parses the "exclude", "include" or "only" deleted
+// product filter. An empty string excludes deleted products.
+func ParseDeletedFilter(s string) (DeletedFilter, error) {
+	switch s {
+	case "", "exclude":
+		return ExcludeDeleted, nil
+	case "include":
+		return IncludeDeleted, nil
+	case "only":
+		return OnlyDeleted, nil
+	}
+	return ExcludeDeleted, Errorf(ErrInvalid, "must be exclude, include or only")
+}
+
+// ParseSort parses a sort specification such as "productCode,-productId".
+// A leading "-" sorts the field in descending order.
+func ParseSort(spec string) ([]SortField, error) {
+	var fields []SortField
+	if spec == "" {
+		return fields, nil
+	}
+	for _, f := range strings.Split(spec, ",") {
+		sf := SortField{Field: strings.TrimSpace(f)}
+		if strings.HasPrefix(sf.Field, "-") {
+			sf.Desc = true
+			sf.Field = sf.Field[1:]
+		}
+		switch sf.Field {
+		case SortByID, SortByProductCode, SortByShortDesc:
+		default:
+			return nil, Errorf(ErrInvalid, "cannot sort by %q", sf.Field)
+		}
+		fields = append(fields, sf)
+	}
+	return fields, nil
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Ensure MemoryStore implements Store
+var _ Store = &MemoryStore{}
+
+// sweepInterval is how often MemoryStore drops the buckets of idle clients.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets in the memory of the process, so every
+// instance of the service limits its clients on its own.
+type MemoryStore struct {
+	// now returns the current time; tests replace it.
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*entry
+	swept   time.Time
+}
+
+// entry is a bucket with the period of the limit it was last taken for.
+type entry struct {
+	Bucket
+	period time.Duration
+}
+
+// NewMemoryStore returns a store without buckets.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, buckets: map[string]*entry{}}
+}
+
+// Take takes a token from the bucket of the key for the limit.
+func (s *MemoryStore) Take(ctx context.Context, key string, l Limit) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	e, ok := s.buckets[key]
+	if !ok {
+		e = &entry{}
+		s.buckets[key] = e
+	}
+	e.period = l.Period
+	return e.Take(l, now), nil
+}
+
+// sweep drops the buckets that have refilled since they were last used,
+// as they would start full again anyway.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, e := range s.buckets {
+		if now.Sub(e.Updated) >= e.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	l := Limit{Burst: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	if res, err := s.Take(ctx, "alice", l); err != nil || !res.Allowed {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+	if res, _ := s.Take(ctx, "alice", l); res.Allowed || res.RetryAfter != time.Minute {
+		t.Fatalf("expected alice to wait a minute, got %+v", res)
+	}
+	// Every key has its own bucket.
+	if res, _ := s.Take(ctx, "bob", l); !res.Allowed {
+		t.Fatalf("expected bob to be allowed, got %+v", res)
+	}
+
+	// The buckets of idle clients are dropped.
+	now = now.Add(2 * time.Minute)
+	s.Take(ctx, "bob", l)
+	if _, ok := s.buckets["alice"]; ok || len(s.buckets) != 1 {
+		t.Errorf("expected only the bucket of bob, got %v", s.buckets)
+	}
+}
@@ -0,0 +1,136 @@
+// Package ratelimit limits how often clients may call the catalog with
+// token buckets kept in a pluggable store.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Limit is a token bucket holding up to Burst tokens that refills from
+// empty in Period. Every request takes a token. The zero Limit does not
+// limit.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+// String formats the limit as ParseLimit reads it.
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	for _, u := range units {
+		if l.Period == u.period {
+			return fmt.Sprintf("%d/%s", l.Burst, u.name)
+		}
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// units are the periods ParseLimit accepts by name.
+var units = []struct {
+	name   string
+	period time.Duration
+}{
+	{"s", time.Second},
+	{"m", time.Minute},
+	{"h", time.Hour},
+}
+
+// ParseLimit reads a limit of the form "<requests>/<period>", such as
+// "600/m". The period is s, m, h or a duration such as "10s". "off" and
+// the empty string do not limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: the requests must be a positive number", s)
+	}
+	l := Limit{Burst: n}
+	for _, u := range units {
+		if s[i+1:] == u.name {
+			l.Period = u.period
+		}
+	}
+	if l.Period == 0 {
+		if l.Period, err = time.ParseDuration(s[i+1:]); err != nil || l.Period <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q: the period must be s, m, h or a positive duration", s)
+		}
+	}
+	return l, nil
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	// Allowed reports whether the bucket had a token for the request.
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets of the clients. Take must take the token
+// atomically, so that concurrent requests of a client never share one.
+// MemoryStore keeps the buckets of a single instance; SharedStore keeps
+// them in a key-value store shared by all instances.
+type Store interface {
+	// Take takes a token from the bucket of the key for the limit.
+	Take(ctx context.Context, key string, l Limit) (*Result, error)
+}
+
+// Bucket is the state of a token bucket, for stores to persist.
+type Bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Take refills the bucket for the time passed since it was last updated
+// and takes a token if it holds one. A new bucket starts full.
+func (b *Bucket) Take(l Limit, now time.Time) *Result {
+	burst := float64(l.Burst)
+	rate := burst / l.Period.Seconds()
+	if b.Updated.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*rate)
+	}
+	b.Updated = now
+
+	res := &Result{Limit: l.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((burst - b.Tokens) / rate)
+	return res
+}
+
+// seconds converts seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
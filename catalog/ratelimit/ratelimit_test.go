@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit Limit
+		ok    bool
+	}{
+		{"600/m", Limit{600, time.Minute}, true},
+		{"10/s", Limit{10, time.Second}, true},
+		{"5000/h", Limit{5000, time.Hour}, true},
+		{"20/10s", Limit{20, 10 * time.Second}, true},
+		{"off", Limit{}, true},
+		{"", Limit{}, true},
+		{"600", Limit{}, false},
+		{"0/m", Limit{}, false},
+		{"ten/m", Limit{}, false},
+		{"10/fortnight", Limit{}, false},
+		{"10/-1s", Limit{}, false},
+	}
+	for _, tt := range tests {
+		l, err := ParseLimit(tt.s)
+		if l != tt.limit || (err == nil) != tt.ok {
+			t.Errorf("%q: unexpected limit %+v, %v", tt.s, l, err)
+		}
+	}
+	if s := (Limit{600, time.Minute}).String(); s != "600/m" {
+		t.Errorf("expected 600/m, got %s", s)
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	l := Limit{Burst: 2, Period: 2 * time.Second}
+	now := time.Now()
+	var b Bucket
+
+	// A new bucket starts full.
+	if res := b.Take(l, now); !res.Allowed || res.Remaining != 1 || res.Reset != time.Second {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res := b.Take(l, now); !res.Allowed || res.Remaining != 0 || res.Reset != 2*time.Second {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	res := b.Take(l, now.Add(500*time.Millisecond))
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected to wait 500ms, got %+v", res)
+	}
+
+	// The bucket refills at one token per second, up to the burst.
+	if res := b.Take(l, now.Add(time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res := b.Take(l, now.Add(time.Hour)); !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Ensure SharedStore implements Store
+var _ Store = &SharedStore{}
+
+// ErrContention is returned by SharedStore when other instances kept
+// updating a bucket through every attempt to take a token from it.
+var ErrContention = errors.New("ratelimit: bucket updated concurrently")
+
+// KV is a key-value store shared by the instances of the service, such
+// as Redis or Memcached, holding the buckets of SharedStore.
+type KV interface {
+	// Get returns the value of the key, or nil when it has none.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// CompareAndSwap sets the value of the key to new if it still is old,
+	// nil meaning the key has no value, and expires it after ttl. It
+	// reports whether it set the value.
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+}
+
+// SharedStore keeps the buckets in a KV, so the limits hold across all
+// instances of the service. It takes tokens with optimistic concurrency,
+// retrying when another instance updated the bucket in between.
+type SharedStore struct {
+	KV KV
+	// Prefix is prepended to the keys of the buckets.
+	Prefix string
+	// Retries is the number of attempts after the first; 3 when zero.
+	Retries int
+
+	// now returns the current time; tests replace it.
+	now func() time.Time
+}
+
+// NewSharedStore returns a store keeping the buckets in kv under prefix.
+func NewSharedStore(kv KV, prefix string) *SharedStore {
+	return &SharedStore{KV: kv, Prefix: prefix, now: time.Now}
+}
+
+// Take takes a token from the bucket of the key for the limit.
+func (s *SharedStore) Take(ctx context.Context, key string, l Limit) (*Result, error) {
+	retries := s.Retries
+	if retries == 0 {
+		retries = 3
+	}
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+	key = s.Prefix + key
+	for i := 0; i <= retries; i++ {
+		old, err := s.KV.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		var b Bucket
+		if old != nil {
+			if err := json.Unmarshal(old, &b); err != nil {
+				return nil, err
+			}
+		}
+		res := b.Take(l, now())
+		new, err := json.Marshal(&b)
+		if err != nil {
+			return nil, err
+		}
+		// The bucket is full again after the reset, so it may expire then.
+		ok, err := s.KV.CompareAndSwap(ctx, key, old, new, res.Reset+time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return res, nil
+		}
+	}
+	return nil, ErrContention
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// mapKV is a KV in a map. conflicts makes as many swaps fail as if
+// another instance had updated the bucket in between.
+type mapKV struct {
+	values    map[string][]byte
+	ttls      map[string]time.Duration
+	conflicts int
+}
+
+func (kv *mapKV) Get(ctx context.Context, key string) ([]byte, error) {
+	return kv.values[key], nil
+}
+
+func (kv *mapKV) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	if kv.conflicts > 0 {
+		kv.conflicts--
+		return false, nil
+	}
+	if !bytes.Equal(kv.values[key], old) {
+		return false, nil
+	}
+	kv.values[key] = new
+	kv.ttls[key] = ttl
+	return true, nil
+}
+
+func TestSharedStore_Take(t *testing.T) {
+	kv := &mapKV{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+	s := NewSharedStore(kv, "catalog:")
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	l := Limit{Burst: 2, Period: time.Minute}
+	ctx := context.Background()
+
+	if res, err := s.Take(ctx, "alice", l); err != nil || !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+	if kv.values["catalog:alice"] == nil || kv.ttls["catalog:alice"] != 31*time.Second {
+		t.Fatalf("expected the bucket under the prefix until it refills, got %v", kv.ttls)
+	}
+
+	// A conflicting update is retried.
+	kv.conflicts = 1
+	if res, err := s.Take(ctx, "alice", l); err != nil || !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+	if res, err := s.Take(ctx, "alice", l); err != nil || res.Allowed {
+		t.Fatalf("expected alice to be limited, got %+v %v", res, err)
+	}
+
+	kv.conflicts = 4
+	if _, err := s.Take(ctx, "alice", l); err != ErrContention {
+		t.Errorf("expected ErrContention, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"strings"
+	"time"
+)
+
+// ProductStatus is the lifecycle stage of a product.
+type ProductStatus string
+
+// Product lifecycle stages. Products are created as drafts and only
+// active products are shown to the public.
+const (
+	StatusDraft        ProductStatus = "draft"
+	StatusReview       ProductStatus = "review"
+	StatusActive       ProductStatus = "active"
+	StatusDiscontinued ProductStatus = "discontinued"
+	StatusArchived     ProductStatus = "archived"
+)
+
+// statuses lists the stages in lifecycle order.
+var statuses = []ProductStatus{StatusDraft, StatusReview, StatusActive, StatusDiscontinued, StatusArchived}
+
+// statusTransitions lists the stages each stage may move to. A product in
+// review can be sent back to draft and a discontinued product can be
+// reactivated; archived products stay archived.
+var statusTransitions = map[ProductStatus][]ProductStatus{
+	StatusDraft:        {StatusReview},
+	StatusReview:       {StatusDraft, StatusActive},
+	StatusActive:       {StatusDiscontinued},
+	StatusDiscontinued: {StatusActive, StatusArchived},
+}
+
+// Valid reports whether s is a known lifecycle stage.
+func (s ProductStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok || s == StatusArchived
+}
+
+// CanTransitionTo reports whether a product may move from s to next.
+// Staying in the same stage is always allowed.
+func (s ProductStatus) CanTransitionTo(next ProductStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, t := range statusTransitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition returns an ErrConflict *Error when a product may not
+// move from one stage to the other.
+func CheckTransition(from, to ProductStatus) error {
+	if from.CanTransitionTo(to) {
+		return nil
+	}
+	var allowed []string
+	for _, t := range statusTransitions[from] {
+		allowed = append(allowed, string(t))
+	}
+	msg := "cannot change from " + string(from) + " to " + string(to)
+	if len(allowed) > 0 {
+		msg += ", only to " + strings.Join(allowed, " or ")
+	}
+	return &Error{Kind: ErrConflict, Message: "product status cannot change", Fields: []FieldError{{Field: "status", Message: msg}}}
+}
+
+// Published reports whether the product is shown to the public at the
+// given time: it is active and within its publishing schedule.
+func (p *Product) Published(at time.Time) bool {
+	if p.Status != StatusActive {
+		return false
+	}
+	if p.PublishAt != nil && p.PublishAt.After(at) {
+		return false
+	}
+	return p.UnpublishAt == nil || p.UnpublishAt.After(at)
+}
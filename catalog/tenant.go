@@ -0,0 +1,82 @@
+package catalog
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// Tenant is a merchant whose catalog is hosted on the deployment. Products,
+// their history, events, prices and variants, and the webhooks receiving
+// the events belong to a tenant and are only seen by requests acting for
+// it. Prices and variants are reached through their product. Categories,
+// attributes and price lists are shared by all tenants and changed by the
+// operators of the deployment.
+type Tenant struct {
+	// ID is a slug such as "acme", chosen when the tenant is provisioned.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// MaxProducts caps the products the tenant may have that are not
+	// deleted; 0 means no limit.
+	MaxProducts int `json:"maxProducts"`
+	// Products is the number of products of the tenant that are not
+	// deleted. It is only read.
+	Products  int       `json:"products"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DefaultTenant owns the products of requests that name no tenant,
+// including every product created before tenants existed.
+const DefaultTenant = "default"
+
+// maxTenantIDLength is the length of tenant.id.
+const maxTenantIDLength = 63
+
+// ValidTenantID reports whether id is a valid tenant ID.
+func ValidTenantID(id string) bool {
+	return len(id) <= maxTenantIDLength && slugPattern.MatchString(id)
+}
+
+// Validate checks the tenant fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (t *Tenant) Validate() error {
+	var fields []FieldError
+	if !ValidTenantID(t.ID) {
+		fields = append(fields, FieldError{Field: "id", Message: "must be a slug of at most 63 characters"})
+	}
+	if n := utf8.RuneCountInString(t.Name); n == 0 || n > maxFieldLength {
+		fields = append(fields, FieldError{Field: "name", Message: "must be between 1 and 255 characters"})
+	}
+	if t.MaxProducts < 0 {
+		fields = append(fields, FieldError{Field: "maxProducts", Message: "must not be negative"})
+	}
+	return ValidationError("tenant is invalid", fields)
+}
+
+// TenantService provisions the tenants.
+type TenantService interface {
+	Tenant(ctx context.Context, id string) (*Tenant, error)
+	Tenants(ctx context.Context) ([]*Tenant, error)
+	CreateTenant(ctx context.Context, t *Tenant) error
+	// UpdateTenant changes the name and quota of a tenant. Lowering the
+	// quota below the current products only stops new ones.
+	UpdateTenant(ctx context.Context, t *Tenant) error
+}
+
+// tenantKey is the context key of the tenant.
+type tenantKey struct{}
+
+// WithTenant returns a context acting for the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant recorded by WithTenant, or
+// DefaultTenant.
+func TenantFromContext(ctx context.Context) string {
+	if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
@@ -0,0 +1,69 @@
+package catalog
+
+import "golang.org/x/net/context"
+
+// Variant is a sellable SKU of a product, distinguished from its siblings
+// by its option values, e.g. {"size": "M", "color": "red"}.
+type Variant struct {
+	ID        string            `json:"variantId"`
+	ProductID string            `json:"productId"`
+	SKU       string            `json:"sku"`
+	GTIN      string            `json:"gtin,omitempty"`
+	Options   map[string]string `json:"options"`
+}
+
+// Validate checks the variant fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (v *Variant) Validate() error {
+	var fields []FieldError
+	if v.SKU == "" {
+		fields = append(fields, FieldError{Field: "sku", Message: "is required"})
+	} else if len(v.SKU) > maxFieldLength {
+		fields = append(fields, FieldError{Field: "sku", Message: "must be at most 255 characters"})
+	}
+	if v.GTIN != "" && !ValidGTIN(v.GTIN) {
+		fields = append(fields, FieldError{Field: "gtin", Message: "must be a GTIN-8, 12, 13 or 14 with a valid check digit"})
+	}
+	for axis, value := range v.Options {
+		if axis == "" || len(axis) > 64 {
+			fields = append(fields, FieldError{Field: "options", Message: "option names must be 1 to 64 characters"})
+		} else if value == "" || len(value) > maxFieldLength {
+			fields = append(fields, FieldError{Field: "options." + axis, Message: "must be 1 to 255 characters"})
+		}
+	}
+	return ValidationError("variant is invalid", fields)
+}
+
+// ValidGTIN reports whether s is a GTIN-8, GTIN-12 (UPC), GTIN-13 (EAN) or
+// GTIN-14 with a correct check digit.
+func ValidGTIN(s string) bool {
+	switch len(s) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	sum := 0
+	for i := len(s) - 2; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		// Weights alternate 3, 1, 3, ... moving left from the check digit.
+		if (len(s)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	check := s[len(s)-1]
+	return check >= '0' && check <= '9' && int(check-'0') == (10-sum%10)%10
+}
+
+// VariantService represents a service for managing the variants of products.
+type VariantService interface {
+	Variant(ctx context.Context, id string) (*Variant, error)
+	Variants(ctx context.Context, productID string) ([]*Variant, error)
+	CreateVariant(ctx context.Context, v *Variant) error
+	UpdateVariant(ctx context.Context, v *Variant) error
+	DeleteVariant(ctx context.Context, id string) error
+}
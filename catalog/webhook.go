@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// Webhook is a subscription of a partner to product events. Matching
+// events are POSTed to the URL, signed with the secret. A webhook without
+// event types receives every event. Webhooks only receive the events of
+// the products of their tenant.
+type Webhook struct {
+	ID string `json:"id"`
+	// TenantID is the tenant the webhook was created for.
+	TenantID   string   `json:"tenantId"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes,omitempty"`
+	// Secret signs the deliveries. It is only ever written; reads leave
+	// it empty.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// minSecretLength keeps webhook secrets hard to guess.
+const minSecretLength = 16
+
+// eventTypes lists the event types webhooks can subscribe to.
+var eventTypes = []string{EventProductCreated, EventProductUpdated, EventProductDeleted, EventProductRestored}
+
+// Validate checks the webhook fields, returning an ErrInvalid *Error
+// listing every field that failed.
+func (w *Webhook) Validate() error {
+	var fields []FieldError
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		fields = append(fields, FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	} else if len(w.URL) > 2048 {
+		fields = append(fields, FieldError{Field: "url", Message: "must be at most 2048 characters"})
+	}
+	for _, t := range w.EventTypes {
+		if !validEventType(t) {
+			fields = append(fields, FieldError{Field: "eventTypes", Message: t + " is not an event type"})
+		}
+	}
+	if n := utf8.RuneCountInString(w.Secret); n < minSecretLength || n > maxFieldLength {
+		fields = append(fields, FieldError{Field: "secret", Message: "must be between 16 and 255 characters"})
+	}
+	return ValidationError("webhook is invalid", fields)
+}
+
+// Subscribes reports whether the webhook receives events of the type.
+func (w *Webhook) Subscribes(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// validEventType reports whether t is a known event type.
+func validEventType(t string) bool {
+	for _, e := range eventTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the state of a webhook delivery.
+type DeliveryStatus string
+
+// Webhook delivery states. Failed deliveries are retried until they run
+// out of attempts and become dead; dead deliveries are only sent again
+// when they are replayed.
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryFailed    DeliveryStatus = "failed"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Valid reports whether s is a known delivery state.
+func (s DeliveryStatus) Valid() bool {
+	switch s {
+	case DeliveryPending, DeliveryFailed, DeliverySucceeded, DeliveryDead:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is the delivery of an event to a webhook, recording the
+// outcome of the last attempt. Payload is the body that is POSTed.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        int64           `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	Payload        json.RawMessage `json:"payload"`
+
+	// Webhook is the subscription to deliver to, set on claimed deliveries.
+	Webhook *Webhook `json:"-"`
+}
+
+// DeliveryPage is a page of deliveries, newest first. Next is empty on the
+// last page.
+type DeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Next       string             `json:"next,omitempty"`
+}
+
+// WebhookService manages the webhook subscriptions and their deliveries.
+type WebhookService interface {
+	Webhook(ctx context.Context, id string) (*Webhook, error)
+	Webhooks(ctx context.Context) ([]*Webhook, error)
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	// DeleteWebhook deletes a webhook together with its deliveries.
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// EnqueueDeliveries queues a delivery of the event to every webhook
+	// of its tenant subscribed to its type and returns how many were
+	// queued. Queueing an event again does not deliver it twice.
+	EnqueueDeliveries(ctx context.Context, e *ProductEvent) (int, error)
+	// ClaimDeliveries returns up to limit deliveries that are due, with
+	// their webhook, and postpones their next attempt by lease so that
+	// no one else sends them meanwhile.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	// RecordAttempt stores the status, attempts, next attempt and last
+	// outcome of a delivery.
+	RecordAttempt(ctx context.Context, d *WebhookDelivery) error
+
+	// Deliveries returns a page of the deliveries of a webhook, newest
+	// first, optionally only those in the given state.
+	Deliveries(ctx context.Context, webhookID string, status DeliveryStatus, page Page) (*DeliveryPage, error)
+	Delivery(ctx context.Context, webhookID, id string) (*WebhookDelivery, error)
+	// ReplayDelivery queues a delivery to be sent again right away with a
+	// fresh set of attempts.
+	ReplayDelivery(ctx context.Context, webhookID, id string) (*WebhookDelivery, error)
+}
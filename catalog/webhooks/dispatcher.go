@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Dispatcher defaults.
+const (
+	DefaultMaxAttempts = 8
+	DefaultMinBackoff  = 30 * time.Second
+	DefaultMaxBackoff  = 6 * time.Hour
+	DefaultTimeout     = 10 * time.Second
+	DefaultBatchSize   = 20
+)
+
+// Dispatcher sends the queued webhook deliveries. A failed delivery is
+// retried with exponential backoff until it has been attempted MaxAttempts
+// times, after which it is dead until replayed.
+type Dispatcher struct {
+	Service catalog.WebhookService
+	Client  *http.Client
+
+	MaxAttempts int
+	// MinBackoff is the wait after the first failure; every further
+	// failure doubles it, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// BatchSize is the number of deliveries claimed and sent at once.
+	BatchSize int
+
+	now func() time.Time
+}
+
+// NewDispatcher returns a dispatcher of the deliveries of s with the
+// default settings.
+func NewDispatcher(s catalog.WebhookService) *Dispatcher {
+	return &Dispatcher{
+		Service:     s,
+		Client:      &http.Client{Timeout: DefaultTimeout},
+		MaxAttempts: DefaultMaxAttempts,
+		MinBackoff:  DefaultMinBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		BatchSize:   DefaultBatchSize,
+		now:         time.Now,
+	}
+}
+
+// lease returns how long claimed deliveries are held back from other
+// dispatchers: long enough for every delivery of a batch to time out.
+func (d *Dispatcher) lease() time.Duration {
+	return 2*d.Client.Timeout + time.Minute
+}
+
+// backoff returns the wait before the next attempt after attempts failed.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.MinBackoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait
+}
+
+// DeliverDue claims a batch of due deliveries, sends them concurrently and
+// records their outcome. It returns how many deliveries were attempted.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.Service.ClaimDeliveries(ctx, d.BatchSize, d.lease())
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for i, dl := range deliveries {
+		wg.Add(1)
+		go func(i int, dl *catalog.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, dl)
+			errs[i] = d.Service.RecordAttempt(ctx, dl)
+		}(i, dl)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends the delivery once and updates its state with the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, dl *catalog.WebhookDelivery) {
+	now := d.now().UTC()
+	dl.Attempts++
+	dl.LastAttemptAt = &now
+	dl.LastStatusCode, dl.LastError = 0, ""
+	code, err := d.send(ctx, dl, now)
+	dl.LastStatusCode = code
+	switch {
+	case err == nil:
+		dl.Status = catalog.DeliverySucceeded
+		dl.NextAttemptAt = nil
+		return
+	case dl.Attempts >= d.MaxAttempts:
+		dl.Status = catalog.DeliveryDead
+		dl.NextAttemptAt = nil
+	default:
+		dl.Status = catalog.DeliveryFailed
+		next := now.Add(d.backoff(dl.Attempts))
+		dl.NextAttemptAt = &next
+	}
+	dl.LastError = err.Error()
+	log.Warnf("Webhook delivery %v of event %d to webhook %v failed (attempt %d): %v",
+		dl.ID, dl.EventID, dl.WebhookID, dl.Attempts, err)
+}
+
+// send POSTs the signed payload to the webhook. Any response other than a
+// 2xx fails the delivery.
+func (d *Dispatcher) send(ctx context.Context, dl *catalog.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, dl.Webhook.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "catalog-webhooks")
+	req.Header.Set(EventHeader, dl.EventType)
+	req.Header.Set(DeliveryHeader, dl.ID)
+	req.Header.Set(SignatureHeader, Sign(dl.Webhook.Secret, now, dl.Payload))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mvonbodun/go-package-test/catalog"
+	"github.com/mvonbodun/go-package-test/catalog/mock"
+	"golang.org/x/net/context"
+)
+
+func TestSign(t *testing.T) {
+	// Computed with: printf '1583064000.{}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	got := Sign("0123456789abcdef", time.Unix(1583064000, 0), []byte("{}"))
+	if got != "t=1583064000,v1=a2c2d598a53fe0c901c3ce259b73a654b5a5dd45d1f5e3d055d01b767a2f6989" {
+		t.Errorf("unexpected signature: %s", got)
+	}
+}
+
+func TestDispatcher_DeliverDue(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]*http.Request{}
+	bodies := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		received[r.Header.Get(DeliveryHeader)] = r
+		bodies[r.Header.Get(DeliveryHeader)] = string(body)
+		mu.Unlock()
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	hook := &catalog.Webhook{ID: "1", URL: srv.URL + "/hooks", Secret: "0123456789abcdef"}
+	gone := &catalog.Webhook{ID: "2", URL: srv.URL + "/gone", Secret: "fedcba9876543210"}
+	claimed := []*catalog.WebhookDelivery{
+		{ID: "7", WebhookID: "1", EventID: 11, EventType: catalog.EventProductUpdated, Status: catalog.DeliveryPending,
+			Payload: []byte(`{"id":11}`), Webhook: hook},
+		{ID: "8", WebhookID: "2", EventID: 11, EventType: catalog.EventProductUpdated, Status: catalog.DeliveryFailed,
+			Attempts: 2, Payload: []byte(`{"id":11}`), Webhook: gone},
+		{ID: "9", WebhookID: "2", EventID: 12, EventType: catalog.EventProductDeleted, Status: catalog.DeliveryFailed,
+			Attempts: 7, Payload: []byte(`{"id":12}`), Webhook: gone},
+	}
+
+	var s mock.WebhookService
+	s.ClaimDeliveriesFn = func(ctx context.Context, limit int, lease time.Duration) ([]*catalog.WebhookDelivery, error) {
+		if limit != DefaultBatchSize || lease <= DefaultTimeout {
+			t.Errorf("unexpected claim: %d, %v", limit, lease)
+		}
+		return claimed, nil
+	}
+	recorded := map[string]catalog.WebhookDelivery{}
+	s.RecordAttemptFn = func(ctx context.Context, d *catalog.WebhookDelivery) error {
+		mu.Lock()
+		recorded[d.ID] = *d
+		mu.Unlock()
+		return nil
+	}
+
+	d := NewDispatcher(&s)
+	d.now = func() time.Time { return now }
+	n, err := d.DeliverDue(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries attempted, got %d, %v", n, err)
+	}
+
+	r := received["7"]
+	if r == nil || r.Method != http.MethodPost || r.Header.Get(EventHeader) != catalog.EventProductUpdated {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+	if sig := r.Header.Get(SignatureHeader); sig != Sign(hook.Secret, now, []byte(bodies["7"])) || bodies["7"] != `{"id":11}` {
+		t.Errorf("unexpected signature %s of %s", sig, bodies["7"])
+	}
+	if sig := received["8"].Header.Get(SignatureHeader); sig != Sign(gone.Secret, now, []byte(`{"id":11}`)) {
+		t.Errorf("expected the delivery signed with the secret of its webhook, got %s", sig)
+	}
+
+	if got := recorded["7"]; got.Status != catalog.DeliverySucceeded || got.Attempts != 1 || got.LastStatusCode != 200 ||
+		got.NextAttemptAt != nil || !got.LastAttemptAt.Equal(now) {
+		t.Errorf("unexpected succeeded delivery: %+v", got)
+	}
+	// The third attempt waits four times the first backoff.
+	if got := recorded["8"]; got.Status != catalog.DeliveryFailed || got.Attempts != 3 || got.LastStatusCode != 410 ||
+		got.NextAttemptAt == nil || !got.NextAttemptAt.Equal(now.Add(4*DefaultMinBackoff)) || got.LastError == "" {
+		t.Errorf("unexpected failed delivery: %+v", got)
+	}
+	if got := recorded["9"]; got.Status != catalog.DeliveryDead || got.Attempts != DefaultMaxAttempts || got.NextAttemptAt != nil {
+		t.Errorf("unexpected dead delivery: %+v", got)
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := &Dispatcher{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second,
+		8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("expected a backoff of %v after %d attempts, got %v", want, attempts, got)
+		}
+	}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	var s mock.WebhookService
+	e := &catalog.ProductEvent{ID: 11, Type: catalog.EventProductCreated}
+	s.EnqueueDeliveriesFn = func(ctx context.Context, got *catalog.ProductEvent) (int, error) {
+		if got != e {
+			t.Errorf("unexpected event: %+v", got)
+		}
+		return 2, nil
+	}
+	p := &Publisher{Service: &s}
+	if err := p.Publish(context.Background(), e); err != nil || !s.EnqueueDeliveriesInvoked {
+		t.Errorf("expected the deliveries queued, got %v", err)
+	}
+}
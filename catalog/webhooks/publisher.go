@@ -0,0 +1,29 @@
+package webhooks
+
+import (
+	"github.com/mvonbodun/go-package-test/catalog"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Ensure Publisher implements catalog.EventPublisher
+var _ catalog.EventPublisher = &Publisher{}
+
+// Publisher queues the delivery of product events to the subscribed
+// webhooks. The Dispatcher sends them.
+type Publisher struct {
+	Service catalog.WebhookService
+}
+
+// Publish queues the deliveries of the event. Publishing an event again
+// does not queue it twice.
+func (p *Publisher) Publish(ctx context.Context, e *catalog.ProductEvent) error {
+	n, err := p.Service.EnqueueDeliveries(ctx, e)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Debugf("Queued %d webhook deliveries of event %d", n, e.ID)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package webhooks delivers product events to the webhooks partners
+// subscribe.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	// SignatureHeader carries the signature of the delivery in the form
+	// t=<unix seconds>,v1=<hex HMAC-SHA256>. See Sign.
+	SignatureHeader = "X-Catalog-Signature"
+	// EventHeader carries the event type.
+	EventHeader = "X-Catalog-Event"
+	// DeliveryHeader carries the delivery ID, which stays the same across
+	// the attempts of a delivery.
+	DeliveryHeader = "X-Catalog-Delivery"
+)
+
+// Sign returns the signature header value of a body sent at t. The HMAC is
+// computed with the webhook secret over "<t>.<body>", so receivers can
+// reject old deliveries being replayed by checking t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}